@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"time"
+
+	"go-backend/models"
+)
+
+// Roles
+const (
+	RoleAdmin = "admin" // allowed to use administrative endpoints
+	RoleUser  = "user"
+)
+
+// ValidRole reports whether role is a known role
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleUser
+}
+
+type sessionKey struct{}
+
+// NewContext returns a copy of ctx carrying the authenticated session
+func NewContext(ctx context.Context, session *models.Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// FromContext returns the authenticated session, or nil for anonymous requests
+func FromContext(ctx context.Context) *models.Session {
+	session, _ := ctx.Value(sessionKey{}).(*models.Session)
+	return session
+}
+
+// UserID returns the authenticated user's ID, or "" for anonymous requests
+func UserID(ctx context.Context) string {
+	if session := FromContext(ctx); session != nil {
+		return session.UserID
+	}
+	return ""
+}
+
+// CheckPassword reports whether password matches the user's stored password.
+// Plaintext passwords are still accepted until the hash_passwords migration
+// has run.
+func CheckPassword(user models.User, password string) bool {
+	if IsPasswordHash(user.Password) {
+		return verifyHash(user.Password, password)
+	}
+	return subtle.ConstantTimeCompare([]byte(user.Password), []byte(password)) == 1
+}
+
+// UnknownUser stands in for a user that does not exist. Checking a password
+// against it takes as long as against a real account, so response times do
+// not reveal which emails have one. The result of the check is meaningless.
+var UnknownUser = models.User{
+	Password: "pbkdf2-sha256$600000$tupYA7m611i31EENHTNVDg$k0yicJ48X6HDFVLxn83Qisxph57LTSg3s2HtPOnt0SI",
+}
+
+// detachedTimeout bounds writes that must not be cut short by the request
+const detachedTimeout = 5 * time.Second
+
+// detach keeps ctx's values but not its cancellation or deadline
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), detachedTimeout)
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go-backend/db"
+	"go-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Auth event types
+const (
+	EventLoginSuccess = "login_success"
+	EventLoginFailure = "login_failure"
+	EventLockout      = "lockout"
+	EventLogout       = "logout"
+	EventUnlock       = "unlock"
+)
+
+// EventFilter selects auth events
+type EventFilter struct {
+	UserID string
+	Email  string
+	Type   string
+	From   time.Time
+	To     time.Time
+	Limit  int64
+}
+
+// RecordEvent persists an auth event. Failures are logged rather than
+// returned so auditing never blocks a login. The write outlives ctx's
+// cancellation, so a client hanging up cannot skip the audit trail.
+func RecordEvent(ctx context.Context, event models.AuthEvent) {
+	ctx, cancel := detach(ctx)
+	defer cancel()
+
+	event.CreatedAt = time.Now().UTC()
+	event.Email = normalizeEmail(event.Email)
+
+	if _, err := db.GetAuthEventsCollection().InsertOne(ctx, event); err != nil {
+		log.Printf("Failed to record auth event %s: %v", event.Type, err)
+	}
+}
+
+// QueryEvents returns matching auth events, newest first
+func QueryEvents(ctx context.Context, filter EventFilter) ([]models.AuthEvent, error) {
+	query := bson.M{}
+	if filter.UserID != "" {
+		query["user_id"] = filter.UserID
+	}
+	if filter.Email != "" {
+		query["email"] = normalizeEmail(filter.Email)
+	}
+	if filter.Type != "" {
+		query["type"] = filter.Type
+	}
+
+	createdAt := bson.M{}
+	if !filter.From.IsZero() {
+		createdAt["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		createdAt["$lt"] = filter.To
+	}
+	if len(createdAt) > 0 {
+		query["created_at"] = createdAt
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(filter.Limit).
+		SetMaxTime(db.MaxTime(ctx))
+
+	cursor, err := db.GetAuthEventsCollection().Find(ctx, query, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []models.AuthEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
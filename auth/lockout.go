@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"go-backend/config"
+	"go-backend/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Lockout is the active brute-force protection configuration
+var Lockout = config.Default().Auth.Lockout
+
+// ErrLockedOut is returned while an account or address is locked
+var ErrLockedOut = errors.New("too many failed login attempts")
+
+// LockoutError tells the caller how long to wait before trying again
+type LockoutError struct {
+	RetryAfter time.Duration
+	Locked     bool // true for a lockout, false for a progressive delay
+}
+
+func (e *LockoutError) Error() string {
+	return ErrLockedOut.Error()
+}
+
+func (e *LockoutError) Unwrap() error {
+	return ErrLockedOut
+}
+
+// loginAttempts is the failure counter stored for an account or address
+type loginAttempts struct {
+	Key         string    `bson:"_id"`
+	Failures    int       `bson:"failures"`
+	LastFailure time.Time `bson:"last_failure"`
+	LockedUntil time.Time `bson:"locked_until"`
+	UpdatedAt   time.Time `bson:"updated_at"`
+}
+
+// reserveRetries bounds how often ReserveLogin re-reads a counter that
+// another attempt changed first
+const reserveRetries = 3
+
+// ReserveLogin counts a login attempt against the account and address
+// before the password is checked, and returns a *LockoutError instead if
+// either must wait. The attempt counts as a failure until
+// RecordLoginSuccess releases it, so parallel guesses cannot all pass the
+// check before the counters grow. The counters are kept even if the client
+// hangs up, so disconnecting early does not dodge the lockout.
+func ReserveLogin(ctx context.Context, email, ip string) error {
+	ctx, cancel := detach(ctx)
+	defer cancel()
+
+	now := time.Now().UTC()
+	keys := attemptKeys(email, ip)
+	attempts := make([]*loginAttempts, len(keys))
+	var wait time.Duration
+	locked := false
+
+	// Refuse the attempt if any counter asks to wait, without counting it
+	for i, key := range keys {
+		var err error
+		if attempts[i], err = findAttempts(ctx, key); err != nil {
+			return err
+		}
+		w, l := attempts[i].wait(now)
+		wait, locked = max(wait, w), locked || l
+	}
+	if wait > 0 {
+		return &LockoutError{RetryAfter: wait, Locked: locked}
+	}
+
+	for i, key := range keys {
+		if err := reserve(ctx, key, attempts[i], now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reserve adds an attempt to the counter read as current, unless another
+// attempt changed it since. The counter is then read again and the attempt
+// refused if it now has to wait.
+func reserve(ctx context.Context, key string, current *loginAttempts, now time.Time) error {
+	collection := db.GetLoginAttemptsCollection()
+	for try := 0; ; try++ {
+		var err error
+		if current == nil {
+			// Only one of several first attempts creates the counter
+			_, err = collection.InsertOne(ctx, loginAttempts{Key: key, Failures: 1, LastFailure: now, UpdatedAt: now})
+			if err == nil {
+				return nil
+			}
+			if !mongo.IsDuplicateKeyError(err) {
+				return err
+			}
+		} else {
+			// Start counting afresh once earlier failures fall out of the window
+			failures := 0
+			if now.Sub(current.LastFailure) < Lockout.FailureWindow {
+				failures = current.Failures
+			}
+			result, err := collection.UpdateOne(ctx,
+				bson.M{"_id": key, "failures": current.Failures, "last_failure": current.LastFailure},
+				bson.M{"$set": bson.M{"failures": failures + 1, "last_failure": now, "updated_at": now}},
+			)
+			if err != nil {
+				return err
+			}
+			if result.MatchedCount == 1 {
+				return nil
+			}
+		}
+
+		if current, err = findAttempts(ctx, key); err != nil {
+			return err
+		}
+		if wait, locked := current.wait(now); wait > 0 || try == reserveRetries {
+			return &LockoutError{RetryAfter: max(wait, Lockout.BaseDelay), Locked: locked}
+		}
+	}
+}
+
+// findAttempts reads the counter for key, or returns nil if there is none
+func findAttempts(ctx context.Context, key string) (*loginAttempts, error) {
+	var attempts loginAttempts
+	err := db.GetLoginAttemptsCollection().FindOne(ctx, bson.M{"_id": key}, options.FindOne().SetMaxTime(db.MaxTime(ctx))).Decode(&attempts)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attempts, nil
+}
+
+// wait is how long the counter's owner must wait before the next attempt,
+// and whether that is a lockout rather than a progressive delay
+func (a *loginAttempts) wait(now time.Time) (time.Duration, bool) {
+	if a == nil {
+		return 0, false
+	}
+	if a.LockedUntil.After(now) {
+		return a.LockedUntil.Sub(now), true
+	}
+	if a.Failures > 0 && now.Sub(a.LastFailure) < Lockout.FailureWindow {
+		if next := a.LastFailure.Add(delay(Lockout, a.Failures)); next.After(now) {
+			return next.Sub(now), false
+		}
+	}
+	return 0, false
+}
+
+// RecordLoginFailure keeps the attempt reserved by ReserveLogin as a
+// failure, and reports whether it caused the account or address to be
+// locked
+func RecordLoginFailure(ctx context.Context, email, ip string) (bool, error) {
+	ctx, cancel := detach(ctx)
+	defer cancel()
+
+	now := time.Now().UTC()
+	lockedOut := false
+	for _, key := range attemptKeys(email, ip) {
+		maxFailures := Lockout.MaxIPFailures
+		if strings.HasPrefix(key, "account:") {
+			maxFailures = Lockout.MaxAccountFailures
+		}
+		result, err := db.GetLoginAttemptsCollection().UpdateOne(ctx,
+			bson.M{"_id": key, "failures": bson.M{"$gte": maxFailures}, "locked_until": bson.M{"$not": bson.M{"$gt": now}}},
+			bson.M{"$set": bson.M{"locked_until": now.Add(Lockout.LockoutDuration), "failures": 0, "updated_at": now}},
+		)
+		if err != nil {
+			return false, err
+		}
+		lockedOut = lockedOut || result.ModifiedCount > 0
+	}
+	return lockedOut, nil
+}
+
+// RecordLoginSuccess clears the account's failure counter and releases the
+// attempt reserved against the address
+func RecordLoginSuccess(ctx context.Context, email, ip string) error {
+	ctx, cancel := detach(ctx)
+	defer cancel()
+
+	collection := db.GetLoginAttemptsCollection()
+	if email != "" {
+		if _, err := collection.DeleteOne(ctx, bson.M{"_id": "account:" + normalizeEmail(email)}); err != nil {
+			return err
+		}
+	}
+	if ip != "" {
+		_, err := collection.UpdateOne(ctx,
+			bson.M{"_id": "ip:" + ip, "failures": bson.M{"$gt": 0}},
+			bson.M{"$inc": bson.M{"failures": -1}},
+		)
+		return err
+	}
+	return nil
+}
+
+// Unlock clears failures and lockouts for an account and/or address
+func Unlock(ctx context.Context, email, ip string) (bool, error) {
+	keys := attemptKeys(email, ip)
+	if len(keys) == 0 {
+		return false, nil
+	}
+	result, err := db.GetLoginAttemptsCollection().DeleteMany(ctx, bson.M{"_id": bson.M{"$in": keys}})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// delay is the progressive wait after the given number of failures
+func delay(c config.Lockout, failures int) time.Duration {
+	delay := float64(c.BaseDelay) * math.Pow(2, float64(failures-1))
+	return time.Duration(min(delay, float64(c.MaxDelay)))
+}
+
+func attemptKeys(email, ip string) []string {
+	var keys []string
+	if email != "" {
+		keys = append(keys, "account:"+normalizeEmail(email))
+	}
+	if ip != "" {
+		keys = append(keys, "ip:"+ip)
+	}
+	return keys
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Password hashes are stored as pbkdf2-sha256$<iterations>$<salt>$<key>
+const (
+	hashPrefix     = "pbkdf2-sha256$"
+	hashIterations = 600000
+	saltLength     = 16
+	keyLength      = 32
+)
+
+// HashPassword returns a salted hash of password for storage
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, hashIterations, keyLength)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%d$%s$%s", hashPrefix, hashIterations,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// IsPasswordHash reports whether stored is a hash made by HashPassword
+func IsPasswordHash(stored string) bool {
+	return strings.HasPrefix(stored, hashPrefix)
+}
+
+// verifyHash checks password against a stored hash
+func verifyHash(stored, password string) bool {
+	parts := strings.Split(strings.TrimPrefix(stored, hashPrefix), "$")
+	if len(parts) != 3 {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[0])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(want))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(key, want) == 1
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"go-backend/config"
+	"go-backend/db"
+	"go-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SessionTTL is how long an access token stays valid
+var SessionTTL = config.Default().Auth.SessionTTL
+
+// ErrInvalidToken is returned for unknown, revoked or expired tokens
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// CreateSession issues a new access token for the user. Only a hash of the
+// token is stored.
+func CreateSession(ctx context.Context, user models.User) (string, *models.Session, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	now := time.Now().UTC()
+	session := &models.Session{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		CreatedAt: now,
+		ExpiresAt: now.Add(SessionTTL),
+	}
+
+	if _, err := db.GetSessionsCollection().InsertOne(ctx, session); err != nil {
+		return "", nil, err
+	}
+	return token, session, nil
+}
+
+// LookupSession returns the live session for a token
+func LookupSession(ctx context.Context, token string) (*models.Session, error) {
+	var session models.Session
+	err := db.GetSessionsCollection().FindOne(ctx, bson.M{
+		"_id":        hashToken(token),
+		"expires_at": bson.M{"$gt": time.Now().UTC()},
+	}, options.FindOne().SetMaxTime(db.MaxTime(ctx))).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// RevokeSession invalidates a single session
+func RevokeSession(ctx context.Context, session *models.Session) error {
+	_, err := db.GetSessionsCollection().DeleteOne(ctx, bson.M{"_id": session.TokenHash})
+	return err
+}
+
+// RevokeUserSessions invalidates every session belonging to the user
+func RevokeUserSessions(ctx context.Context, userID string) error {
+	_, err := db.GetSessionsCollection().DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
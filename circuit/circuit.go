@@ -0,0 +1,171 @@
+package circuit
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// State is the state of a breaker
+type State int
+
+const (
+	Closed   State = iota // requests pass
+	Open                  // requests fail fast
+	HalfOpen              // one probe request at a time passes
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half_open"
+	}
+	return "unknown"
+}
+
+// Breaker stops requests to a dependency that is known to be down. Once
+// tripped it stays open for the cooldown, then lets a single probe through
+// per cooldown until it is reset.
+type Breaker struct {
+	mu       sync.Mutex
+	state    State
+	cooldown time.Duration
+	openedAt time.Time
+	probedAt time.Time
+	changes  []change // state changes not yet passed to OnChange
+
+	// pending is set while changes is not empty, so requests only take
+	// notifying when there is something to report. notifying serializes
+	// OnChange calls, so they run in the order the changes happened.
+	pending   atomic.Bool
+	notifying sync.Mutex
+
+	// OnChange is called after every state change, if set. Calls do not
+	// overlap and arrive in order.
+	OnChange func(from, to State)
+}
+
+type change struct {
+	from, to State
+}
+
+// New creates a closed breaker
+func New(cooldown time.Duration) *Breaker {
+	return &Breaker{cooldown: cooldown}
+}
+
+// SetCooldown changes how long the breaker stays open before probing
+func (b *Breaker) SetCooldown(cooldown time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cooldown = cooldown
+}
+
+// Allow reports whether a request may proceed
+func (b *Breaker) Allow() bool {
+	defer b.notify()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	switch b.state {
+	case Open:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.probedAt = now
+		b.setState(HalfOpen)
+		return true
+	case HalfOpen:
+		// A probe that takes longer than the cooldown makes way for another
+		if now.Sub(b.probedAt) < b.cooldown {
+			return false
+		}
+		b.probedAt = now
+		return true
+	}
+	return true
+}
+
+// Trip opens the breaker
+func (b *Breaker) Trip() {
+	defer b.notify()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == Open {
+		return
+	}
+	b.openedAt = time.Now()
+	b.setState(Open)
+}
+
+// Reset closes the breaker
+func (b *Breaker) Reset() {
+	defer b.notify()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.setState(Closed)
+}
+
+// State returns the current state
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// RetryAfter estimates how long until requests may pass again
+func (b *Breaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var since time.Time
+	switch b.state {
+	case Open:
+		since = b.openedAt
+	case HalfOpen:
+		since = b.probedAt
+	default:
+		return 0
+	}
+	return max(b.cooldown-time.Since(since), 0)
+}
+
+// setState must be called with b.mu held. The change is passed to
+// OnChange by notify once the lock is released.
+func (b *Breaker) setState(to State) {
+	from := b.state
+	if from == to {
+		return
+	}
+	b.state = to
+	if b.OnChange != nil {
+		b.changes = append(b.changes, change{from, to})
+		b.pending.Store(true)
+	}
+}
+
+// notify passes the pending state changes to OnChange, outside b.mu so
+// the callback may use the breaker
+func (b *Breaker) notify() {
+	if !b.pending.Load() {
+		return
+	}
+	b.notifying.Lock()
+	defer b.notifying.Unlock()
+
+	b.mu.Lock()
+	changes := b.changes
+	b.changes = nil
+	b.pending.Store(false)
+	b.mu.Unlock()
+
+	for _, c := range changes {
+		b.OnChange(c.from, c.to)
+	}
+}
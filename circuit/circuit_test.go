@@ -0,0 +1,38 @@
+package circuit
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOnChangeSeesChangesInOrder(t *testing.T) {
+	b := New(time.Hour)
+	var seen []State
+	last := Closed
+	b.OnChange = func(from, to State) {
+		if from != last {
+			t.Errorf("change %v -> %v after a change to %v", from, to, last)
+		}
+		last = to
+		seen = append(seen, to)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i%2 == 0 {
+				b.Trip()
+			} else {
+				b.Reset()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) == 0 || last != b.State() {
+		t.Errorf("OnChange ended on %v after %d changes, want the breaker's state %v", last, len(seen), b.State())
+	}
+}
@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"go-backend/config"
+	"go-backend/db"
+	"go-backend/logging"
+	"go-backend/repository"
+)
+
+// Exit codes
+const (
+	ExitOK    = 0
+	ExitError = 1 // the command failed
+	ExitUsage = 2 // bad arguments or configuration
+)
+
+// Output formats
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// command is a subcommand of the binary
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, env *env, args []string) error
+}
+
+// commands lists the subcommands; serve runs when none is given
+var commands = []command{
+	{"serve", "Run the HTTP and gRPC servers (default)", runServe},
+	{"migrate", "Database migrations: up, down, status", runMigrate},
+	{"seed", "Load seed data into the database", runSeed},
+	{"user", "Manage users: create, set-role, reset-password, delete", runUser},
+	{"products", "Bulk product transfer: import, export", runProducts},
+	{"indexes", "Manage indexes: sync (applies pending migrations and restores changed indexes)", runIndexes},
+	{"openapi", "Print the OpenAPI document, or -check that it covers every route", runOpenAPI},
+	{"proto", "Print the .proto file of the gRPC API", runProto},
+	{"print-config", "Print the effective configuration with secrets redacted", runPrintConfig},
+}
+
+// env is shared by all commands
+type env struct {
+	cfg    *config.Config
+	args   []string // all command line arguments, for reloading
+	stdout io.Writer
+	stderr io.Writer
+	store  *repository.Store
+}
+
+// usageError marks errors caused by invalid arguments
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// Run executes the command line and returns the process exit code. Global
+// configuration flags come before the subcommand, command flags after it.
+func Run(args []string) int {
+	cfg, rest, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		printUsage(os.Stderr)
+		return ExitOK
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitUsage
+	}
+
+	name := "serve"
+	if len(rest) > 0 {
+		name, rest = rest[0], rest[1:]
+	}
+
+	if name == "help" {
+		printUsage(os.Stdout)
+		return ExitOK
+	}
+
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		printUsage(os.Stderr)
+		return ExitUsage
+	}
+
+	logging.Setup(cfg.Logging)
+
+	// Stop on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	e := &env{cfg: cfg, args: args, stdout: os.Stdout, stderr: os.Stderr}
+	err = cmd.run(ctx, e, rest)
+
+	var usageErr *usageError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return ExitUsage
+	default:
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return ExitError
+	}
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s [config flags] [command] [command flags]\n\nCommands:\n", os.Args[0])
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", c.name, c.summary)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nRun '%s -h' for config flags and '%s <command> -h' for command flags.\n", os.Args[0], os.Args[0])
+}
+
+// connect opens the database and sets up the repositories
+func (e *env) connect(ctx context.Context) error {
+	if e.store != nil {
+		return nil
+	}
+	if err := db.Connect(ctx, e.cfg.Database); err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	e.store = repository.NewMongoStore(db.Database())
+	return nil
+}
+
+// close disconnects from the database if connect was called
+func (e *env) close() {
+	if e.store != nil {
+		db.Disconnect()
+	}
+}
+
+// flagSet creates a flag set for a command with the shared -output flag
+func (e *env) flagSet(name string, output *string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.StringVar(output, "output", OutputText, "output format: text or json")
+	return fs
+}
+
+// parse parses command flags and checks the output format
+func parse(fs *flag.FlagSet, args []string, output *string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &usageError{msg: err.Error()}
+	}
+	if fs.NArg() > 0 {
+		return usagef("unexpected argument %q", fs.Arg(0))
+	}
+	if *output != OutputText && *output != OutputJSON {
+		return usagef("invalid -output %q, must be text or json", *output)
+	}
+	return nil
+}
+
+// print writes v as JSON, or the text lines for the text format
+func (e *env) print(output string, v any, text ...string) error {
+	if output == OutputJSON {
+		encoder := json.NewEncoder(e.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+	_, err := fmt.Fprintln(e.stdout, strings.Join(text, "\n"))
+	return err
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"go-backend/db"
+	"go-backend/migrations"
+)
+
+// runIndexes dispatches the indexes subcommands. Indexes are defined by
+// migrations, so syncing applies any pending ones and then restores
+// indexes that were dropped or changed by hand.
+func runIndexes(ctx context.Context, e *env, args []string) error {
+	if len(args) == 0 || args[0] != "sync" {
+		return usagef("usage: indexes sync [-dry-run] [-output text|json]")
+	}
+
+	var output string
+	var dryRun bool
+	fs := e.flagSet("indexes sync", &output)
+	fs.BoolVar(&dryRun, "dry-run", false, "list the migrations and index changes without applying them")
+	if err := parse(fs, args[1:], &output); err != nil {
+		return err
+	}
+
+	migrator, err := e.migrator(ctx)
+	if err != nil {
+		return err
+	}
+	defer e.close()
+
+	applied, err := migrator.Up(ctx, migrations.Options{DryRun: dryRun})
+	if err != nil {
+		return err
+	}
+	changes, err := migrations.SyncIndexes(ctx, db.Database(), dryRun)
+	if err != nil {
+		return err
+	}
+
+	result := struct {
+		Migrations int                      `json:"migrations_applied"`
+		Indexes    []migrations.IndexChange `json:"indexes"`
+		DryRun     bool                     `json:"dry_run"`
+	}{len(applied), changes, dryRun}
+
+	var lines []string
+	if len(applied) > 0 {
+		if dryRun {
+			lines = append(lines, fmt.Sprintf("%d pending migrations would run", len(applied)))
+		} else {
+			lines = append(lines, fmt.Sprintf("Applied %d migrations", len(applied)))
+		}
+	}
+	fixed := 0
+	for _, change := range changes {
+		name := change.Collection + "." + change.Index
+		switch {
+		case change.Action == migrations.IndexExtra:
+			lines = append(lines, fmt.Sprintf("%s is not declared and was kept", name))
+		case dryRun:
+			lines = append(lines, fmt.Sprintf("%s would be %sd", name, change.Action))
+		default:
+			lines = append(lines, fmt.Sprintf("%s was %sd", name, change.Action))
+			fixed++
+		}
+	}
+	if !dryRun {
+		if fixed == 0 && len(applied) == 0 {
+			lines = append(lines, "Indexes are in sync")
+		} else {
+			lines = append(lines, "Indexes are in sync again")
+		}
+	}
+	return e.print(output, result, lines...)
+}
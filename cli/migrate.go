@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"text/tabwriter"
+	"time"
+
+	"go-backend/db"
+	"go-backend/migrations"
+)
+
+// migrationResult is printed by migrate up and down
+type migrationResult struct {
+	Direction  string              `json:"direction"`
+	DryRun     bool                `json:"dry_run"`
+	Migrations []migrations.Status `json:"migrations"`
+}
+
+// runMigrate dispatches the migrate subcommands
+func runMigrate(ctx context.Context, e *env, args []string) error {
+	if len(args) == 0 {
+		return usagef("usage: migrate up|down|status [flags]")
+	}
+
+	switch args[0] {
+	case "up", "down":
+		return runMigrateStep(ctx, e, args[0], args[1:])
+	case "status":
+		return runMigrateStatus(ctx, e, args[1:])
+	}
+	return usagef("unknown migrate command %q", args[0])
+}
+
+func runMigrateStep(ctx context.Context, e *env, direction string, args []string) error {
+	var output string
+	var opts migrations.Options
+	fs := e.flagSet("migrate "+direction, &output)
+	fs.Int64Var(&opts.Target, "to", 0, "target version (up: default latest; down: revert everything above it)")
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "list the migrations that would run without running them")
+	if direction == "down" {
+		fs.IntVar(&opts.Steps, "steps", 0, "number of migrations to revert (default 1 unless -to is set)")
+	}
+	if err := parse(fs, args, &output); err != nil {
+		return err
+	}
+
+	migrator, err := e.migrator(ctx)
+	if err != nil {
+		return err
+	}
+	defer e.close()
+
+	run := migrator.Up
+	if direction == "down" {
+		run = migrator.Down
+	}
+	done, runErr := run(ctx, opts)
+
+	result := migrationResult{Direction: direction, DryRun: opts.DryRun, Migrations: []migrations.Status{}}
+	lines := []string{}
+	verb := map[string]string{"up": "Applied", "down": "Reverted"}[direction]
+	if opts.DryRun {
+		verb = "Would run"
+	}
+	for _, m := range done {
+		result.Migrations = append(result.Migrations, migrations.Status{Version: m.Version, Name: m.Name, Applied: direction == "up"})
+		lines = append(lines, fmt.Sprintf("%s %d %s", verb, m.Version, m.Name))
+	}
+	if len(done) == 0 {
+		lines = append(lines, "Nothing to do")
+	}
+
+	// Report what ran even when a later migration failed
+	if err := e.print(output, result, lines...); err != nil {
+		return err
+	}
+	return runErr
+}
+
+func runMigrateStatus(ctx context.Context, e *env, args []string) error {
+	var output string
+	fs := e.flagSet("migrate status", &output)
+	if err := parse(fs, args, &output); err != nil {
+		return err
+	}
+
+	migrator, err := e.migrator(ctx)
+	if err != nil {
+		return err
+	}
+	defer e.close()
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	if output == OutputJSON {
+		return e.print(output, statuses)
+	}
+	tw := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		state, appliedAt := "pending", ""
+		if s.Applied {
+			state, appliedAt = "applied", s.AppliedAt.Local().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	return tw.Flush()
+}
+
+// migrator connects to the database and returns a migrator for all migrations
+func (e *env) migrator(ctx context.Context) (*migrations.Migrator, error) {
+	if err := e.connect(ctx); err != nil {
+		return nil, err
+	}
+	migrator, err := migrations.New(db.Database(), migrations.All)
+	if err != nil {
+		e.close()
+		return nil, err
+	}
+	migrator.Log = func(format string, args ...any) {
+		slog.Info(fmt.Sprintf(format, args...))
+	}
+	return migrator, nil
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go-backend/routes"
+
+	"github.com/gorilla/mux"
+)
+
+// runOpenAPI prints the OpenAPI document, or with -check only verifies
+// that it covers every registered route, for CI
+func runOpenAPI(ctx context.Context, e *env, args []string) error {
+	var output string
+	var check bool
+	fs := e.flagSet("openapi", &output)
+	fs.BoolVar(&check, "check", false, "only check that every route is documented")
+	if err := parse(fs, args, &output); err != nil {
+		return err
+	}
+
+	router := mux.NewRouter()
+	if err := routes.RegisterRoutes(router, routeOptions(e.cfg, router)); err != nil {
+		return err
+	}
+
+	if check {
+		return e.print(output, map[string]bool{"ok": true}, "Every route is documented")
+	}
+	encoder := json.NewEncoder(e.stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(routes.Spec()); err != nil {
+		return fmt.Errorf("encoding OpenAPI document: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go-backend/models"
+)
+
+// Product file formats
+const (
+	formatJSON  = "json"  // a JSON array
+	formatJSONL = "jsonl" // one JSON object per line
+)
+
+// runProducts dispatches the products subcommands
+func runProducts(ctx context.Context, e *env, args []string) error {
+	if len(args) == 0 {
+		return usagef("usage: products import|export [flags]")
+	}
+
+	switch args[0] {
+	case "import":
+		return runProductsImport(ctx, e, args[1:])
+	case "export":
+		return runProductsExport(ctx, e, args[1:])
+	}
+	return usagef("unknown products command %q", args[0])
+}
+
+// importResult is printed by products import
+type importResult struct {
+	Imported int  `json:"imported"`
+	DryRun   bool `json:"dry_run"`
+}
+
+// runProductsImport reads products from a JSON array or JSON lines file.
+// Products are upserted by id, or by name within their category when they
+// have none, so importing a file twice does not duplicate products.
+func runProductsImport(ctx context.Context, e *env, args []string) error {
+	var output, file string
+	var dryRun bool
+	fs := e.flagSet("products import", &output)
+	fs.StringVar(&file, "file", "", `JSON or JSON lines file, "-" for stdin (required)`)
+	fs.BoolVar(&dryRun, "dry-run", false, "validate the file without writing")
+	if err := parse(fs, args, &output); err != nil {
+		return err
+	}
+	if file == "" {
+		return usagef("-file is required")
+	}
+
+	in := io.Reader(os.Stdin)
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	// Dry runs connect too, to check that the categories exist
+	if err := e.connect(ctx); err != nil {
+		return err
+	}
+	defer e.close()
+	categories, err := e.store.Categories.List(ctx)
+	if err != nil {
+		return err
+	}
+	exists := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		exists[category.ID] = true
+	}
+
+	models.MaxAttributes = e.cfg.Limits.Body.MaxAttributes
+
+	result := importResult{DryRun: dryRun}
+	err = decodeProducts(in, func(n int, product models.Product) error {
+		if product.Name == "" || product.CategoryID == "" {
+			return fmt.Errorf("product %d: name and category_id are required", n)
+		}
+		if err := product.CheckLimits(); err != nil {
+			return fmt.Errorf("product %d: %w", n, err)
+		}
+		if !exists[product.CategoryID] {
+			return fmt.Errorf("product %d: category_id %q does not exist", n, product.CategoryID)
+		}
+		if product.CategoryGroup != "" && !exists[product.CategoryGroup] {
+			return fmt.Errorf("product %d: category_group %q does not exist", n, product.CategoryGroup)
+		}
+		if !dryRun {
+			if err := e.store.Products.Upsert(ctx, &product); err != nil {
+				return fmt.Errorf("product %d: %w", n, err)
+			}
+		}
+		result.Imported++
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w (%d processed before the error)", err, result.Imported)
+	}
+
+	text := fmt.Sprintf("Imported %d products", result.Imported)
+	if dryRun {
+		text = fmt.Sprintf("Dry run: %d products are valid", result.Imported)
+	}
+	return e.print(output, result, text)
+}
+
+// decodeProducts calls fn for each product in a JSON array or JSON lines
+// stream, numbering them from 1
+func decodeProducts(r io.Reader, fn func(int, models.Product) error) error {
+	reader := bufio.NewReader(r)
+	decoder := json.NewDecoder(reader)
+
+	// Peek at the first non-space byte to tell the formats apart
+	isArray := false
+	for {
+		b, err := reader.Peek(1)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if b[0] == ' ' || b[0] == '\t' || b[0] == '\r' || b[0] == '\n' {
+			reader.ReadByte()
+			continue
+		}
+		isArray = b[0] == '['
+		break
+	}
+
+	if isArray {
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+	}
+
+	for n := 1; ; n++ {
+		if isArray && !decoder.More() {
+			_, err := decoder.Token()
+			return err
+		}
+
+		var product models.Product
+		err := decoder.Decode(&product)
+		if !isArray && errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("product %d: %w", n, err)
+		}
+		if err := fn(n, product); err != nil {
+			return err
+		}
+	}
+}
+
+// exportResult is printed by products export when writing to a file
+type exportResult struct {
+	Exported int    `json:"exported"`
+	File     string `json:"file"`
+	Format   string `json:"format"`
+}
+
+// runProductsExport streams every product to a file or stdout
+func runProductsExport(ctx context.Context, e *env, args []string) error {
+	var output, file, format string
+	fs := e.flagSet("products export", &output)
+	fs.StringVar(&file, "file", "-", `output file, "-" for stdout`)
+	fs.StringVar(&format, "format", formatJSONL, "file format: json or jsonl")
+	if err := parse(fs, args, &output); err != nil {
+		return err
+	}
+	if format != formatJSON && format != formatJSONL {
+		return usagef("invalid -format %q, must be json or jsonl", format)
+	}
+
+	if err := e.connect(ctx); err != nil {
+		return err
+	}
+	defer e.close()
+
+	out := e.stdout
+	if file != "-" {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriter(out)
+	encoder := json.NewEncoder(w)
+
+	result := exportResult{File: file, Format: format}
+	if format == formatJSON {
+		w.WriteString("[")
+	}
+	err := e.store.Products.ForEach(ctx, func(product models.Product) error {
+		if format == formatJSON && result.Exported > 0 {
+			w.WriteString(",")
+		}
+		result.Exported++
+		return encoder.Encode(product)
+	})
+	if err != nil {
+		return err
+	}
+	if format == formatJSON {
+		w.WriteString("]\n")
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	// The products are the output when writing to stdout
+	if file == "-" {
+		fmt.Fprintf(e.stderr, "Exported %d products\n", result.Exported)
+		return nil
+	}
+	return e.print(output, result, fmt.Sprintf("Exported %d products to %s", result.Exported, file))
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-backend/config"
+	"go-backend/models"
+	"go-backend/repository"
+)
+
+func TestProductsImportChecksProducts(t *testing.T) {
+	ctx := context.Background()
+	defer func(max int) { models.MaxAttributes = max }(models.MaxAttributes)
+
+	tests := []struct {
+		name    string
+		product string
+		err     string
+	}{
+		{"unknown category", `{"name":"Chair","category_id":"garden"}`, `category_id "garden" does not exist`},
+		{"unknown category group", `{"name":"Chair","category_id":"chairs","category_group":"garden"}`, `category_group "garden" does not exist`},
+		{"too many attributes", `{"name":"Chair","category_id":"chairs","attributes":[{"code":"a"},{"code":"b"},{"code":"c"}]}`, "at most 2 attributes"},
+		{"valid", `{"name":"Chair","category_id":"chairs","category_group":"furniture"}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := repository.NewMemoryStore()
+			parent := "furniture"
+			for _, category := range []models.Category{{ID: "furniture", Name: "Furniture"}, {ID: "chairs", Name: "Chairs", ParentID: &parent}} {
+				if err := store.Categories.Upsert(ctx, &category); err != nil {
+					t.Fatalf("Upsert: %v", err)
+				}
+			}
+			cfg := config.Default()
+			cfg.Limits.Body.MaxAttributes = 2
+			e := &env{cfg: cfg, stdout: io.Discard, stderr: io.Discard, store: store}
+
+			file := filepath.Join(t.TempDir(), "products.jsonl")
+			if err := os.WriteFile(file, []byte(tt.product+"\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			err := runProductsImport(ctx, e, []string{"-file", file})
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("import: %v", err)
+				}
+				if _, total, _ := store.Products.List(ctx, models.PaginationParams{Limit: 10}); total != 1 {
+					t.Errorf("stored %d products, want 1", total)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("import = %v, want an error containing %q", err, tt.err)
+			}
+			if _, total, _ := store.Products.List(ctx, models.PaginationParams{Limit: 10}); total != 0 {
+				t.Errorf("stored %d products, want none", total)
+			}
+		})
+	}
+}
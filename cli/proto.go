@@ -0,0 +1,20 @@
+package cli
+
+import (
+	"context"
+	"io"
+
+	"go-backend/grpcapi"
+)
+
+// runProto prints the .proto file of the gRPC API, checked in as
+// proto/catalog/v1/catalog.proto for clients to generate code from
+func runProto(ctx context.Context, e *env, args []string) error {
+	var output string
+	fs := e.flagSet("proto", &output)
+	if err := parse(fs, args, &output); err != nil {
+		return err
+	}
+	_, err := io.WriteString(e.stdout, grpcapi.Proto())
+	return err
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"go-backend/seed"
+)
+
+// runSeed loads fixtures for the configured environment, or from -dir or
+// -file, and optionally generates fake products
+func runSeed(ctx context.Context, e *env, args []string) error {
+	var output, dir, file string
+	var generate int
+	var randomSeed uint64
+	var dryRun bool
+	fs := e.flagSet("seed", &output)
+	fs.StringVar(&dir, "dir", "", "fixtures directory (default fixtures/<environment>)")
+	fs.StringVar(&file, "file", "", "single JSON or YAML fixture file, instead of -dir")
+	fs.IntVar(&generate, "generate-products", 0, "also generate this many fake products")
+	fs.Uint64Var(&randomSeed, "random-seed", 1, "seed for generated products")
+	fs.BoolVar(&dryRun, "dry-run", false, "validate fixtures without writing")
+	if err := parse(fs, args, &output); err != nil {
+		return err
+	}
+	if dir != "" && file != "" {
+		return usagef("use either -dir or -file, not both")
+	}
+	if generate < 0 {
+		return usagef("-generate-products must not be negative")
+	}
+
+	var data *seed.Data
+	var err error
+	if file != "" {
+		data, err = seed.ReadFile(file)
+	} else {
+		if dir == "" {
+			dir = filepath.Join("fixtures", e.cfg.Environment)
+		}
+		data, err = seed.LoadDir(dir)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := e.connect(ctx); err != nil {
+		return err
+	}
+	defer e.close()
+
+	if generate > 0 {
+		// Generate across the fixture categories and those already stored
+		categories, err := e.store.Categories.List(ctx)
+		if err != nil {
+			return err
+		}
+		categories = append(categories, data.Categories...)
+		products, err := seed.GenerateProducts(categories, generate, randomSeed)
+		if err != nil {
+			return err
+		}
+		data.Products = append(data.Products, products...)
+	}
+
+	// Users are never seeded in production
+	opts := seed.Options{SkipUsers: e.cfg.IsProduction(), DryRun: dryRun}
+	result, err := seed.Apply(ctx, e.store, data, opts)
+	if err != nil {
+		return err
+	}
+
+	lines := []string{
+		fmt.Sprintf("Categories upserted: %d", result.Categories),
+		fmt.Sprintf("Products upserted:   %d", result.Products),
+		fmt.Sprintf("Users created:       %d", result.UsersCreated),
+		fmt.Sprintf("Users updated:       %d", result.UsersUpdated),
+	}
+	if result.UsersSkipped > 0 {
+		lines = append(lines, fmt.Sprintf("Users skipped:       %d (not seeded in production)", result.UsersSkipped))
+	}
+	if dryRun {
+		lines = append([]string{"Dry run: fixtures are valid, nothing was written"}, lines...)
+	}
+	return e.print(output, result, lines...)
+}
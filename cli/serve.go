@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go-backend/auth"
+	"go-backend/codec"
+	"go-backend/config"
+	"go-backend/db"
+	"go-backend/grpcapi"
+	"go-backend/handlers"
+	"go-backend/health"
+	"go-backend/idempotency"
+	"go-backend/logging"
+	"go-backend/middleware"
+	"go-backend/migrations"
+	"go-backend/models"
+	"go-backend/ratelimit"
+	"go-backend/routes"
+	"go-backend/server"
+	"go-backend/tracing"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v3"
+)
+
+// runServe starts the server and returns once it has shut down, so that
+// deferred cleanup always runs
+func runServe(ctx context.Context, e *env, args []string) error {
+	var output string
+	fs := e.flagSet("serve", &output)
+	if err := parse(fs, args, &output); err != nil {
+		return err
+	}
+
+	cfg := e.cfg
+	slog.Debug("Loaded configuration", "config", cfg.String())
+
+	// Set up tracing before anything that creates spans
+	shutdownTracing, err := tracing.Init(ctx, cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Connect to MongoDB
+	if err := e.connect(ctx); err != nil {
+		return err
+	}
+	defer e.close()
+	handlers.SetStore(e.store)
+
+	// Readiness depends on MongoDB
+	health.Default.Configure(cfg.Health.CacheTTL, cfg.Health.CheckTimeout)
+	health.Register(health.Check{Name: "mongodb", Check: db.Ping, Critical: true})
+
+	// Bring the schema up to date, or warn when it is behind
+	if err := startupMigrations(ctx, e); err != nil {
+		return err
+	}
+
+	// Apply auth settings
+	auth.SessionTTL = cfg.Auth.SessionTTL
+	auth.Lockout = cfg.Auth.Lockout
+
+	// Apply request body limits
+	codec.MaxDepth = cfg.Limits.Body.MaxDepth
+	models.MaxAttributes = cfg.Limits.Body.MaxAttributes
+
+	// Register routes
+	router := mux.NewRouter()
+	opts := routeOptions(cfg, router)
+	opts.Store = e.store
+	if cfg.Database.CircuitBreaker.Enabled {
+		opts.DatabaseBreaker = db.Breaker
+	}
+	if err := routes.RegisterRoutes(router, opts); err != nil {
+		slog.Warn("OpenAPI document is out of date", "error", err)
+	}
+
+	// Reload on SIGHUP or config file changes
+	reloader := config.NewReloader(cfg, e.args, func(next *config.Config) {
+		opts.CORS.Update(next.CORS)
+		opts.RateLimiter.Update(next.Limits.RateLimit)
+		opts.Deadlines.Update(next.Limits.Timeouts)
+		opts.BodyLimits.Update(next.Limits.Body)
+		logging.SetLevel(next.Logging.Level)
+	})
+	go reloader.Run(ctx)
+
+	// The gRPC server shuts down with the HTTP server; its port is opened
+	// first so a port in use fails startup
+	var grpcDone chan error
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if cfg.GRPC.Enabled {
+		srv := grpcapi.New(grpcapi.Options{
+			Store:      e.store,
+			Spec:       routes.Spec(),
+			Reflection: cfg.GRPC.Reflection,
+			Timeout:    cfg.Limits.Timeouts.Default,
+		})
+		if err := srv.Listen(cfg.GRPC.Port); err != nil {
+			return err
+		}
+		grpcDone = make(chan error, 1)
+		go func() {
+			grpcDone <- srv.Serve(ctx, cfg.Server)
+		}()
+	}
+
+	// Serve until a shutdown signal, then drain before the deferred
+	// database disconnect runs
+	err = server.Run(ctx, server.New(router, cfg.Server), cfg.Server)
+	cancel()
+	if grpcDone != nil {
+		err = errors.Join(err, <-grpcDone)
+	}
+	return err
+}
+
+// routeOptions creates the middleware for router from cfg. CORS, rate
+// limits, deadlines and body size limits can be reloaded while serving.
+func routeOptions(cfg *config.Config, router *mux.Router) routes.Options {
+	// Only these proxies may set X-Forwarded-For (validated by config.Load)
+	trustedProxies, _ := cfg.Server.TrustedProxyNetworks()
+
+	return routes.Options{
+		CORS:             middleware.NewCORS(router, cfg.CORS),
+		RateLimiter:      middleware.NewRateLimiter(ratelimit.NewMemoryStore(), cfg.Limits.RateLimit),
+		Deadlines:        middleware.NewDeadlines(cfg.Limits.Timeouts),
+		BodyLimits:       middleware.NewBodyLimits(cfg.Limits.Body),
+		TrustedProxies:   trustedProxies,
+		Validation:       cfg.Validation,
+		APIv1:            cfg.API.V1,
+		GraphQL:          cfg.Limits.GraphQL,
+		Compression:      cfg.Compression,
+		Idempotency:      cfg.Idempotency,
+		IdempotencyStore: idempotency.NewMemoryStore(),
+		Debug:            cfg.Debug,
+	}
+}
+
+// runPrintConfig prints the configuration with secrets redacted
+func runPrintConfig(ctx context.Context, e *env, args []string) error {
+	var output string
+	fs := e.flagSet("print-config", &output)
+	if err := parse(fs, args, &output); err != nil {
+		return err
+	}
+	if output == OutputJSON {
+		// Go through YAML so keys match the config file
+		var doc map[string]any
+		if err := yaml.Unmarshal([]byte(e.cfg.String()), &doc); err != nil {
+			return err
+		}
+		return e.print(output, doc)
+	}
+	_, err := fmt.Fprint(e.stdout, e.cfg)
+	return err
+}
+
+// startupMigrations applies pending migrations when database.migrate_on_start
+// is set, waiting for any other instance that is already migrating
+func startupMigrations(ctx context.Context, e *env) error {
+	migrator, err := e.migrator(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !e.cfg.Database.MigrateOnStart {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to check migrations: %w", err)
+		}
+		if len(pending) > 0 {
+			slog.Warn("Database has pending migrations, run the migrate up command", "pending", len(pending))
+		}
+		return nil
+	}
+
+	for {
+		_, err := migrator.Up(ctx, migrations.Options{})
+		if !errors.Is(err, migrations.ErrLocked) {
+			if err != nil {
+				return fmt.Errorf("failed to apply migrations: %w", err)
+			}
+			return nil
+		}
+
+		slog.Info("Waiting for another instance to finish migrations")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-backend/auth"
+	"go-backend/models"
+	"go-backend/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// userResult is printed by the user subcommands
+type userResult struct {
+	User models.UserResponse `json:"user"`
+	// Password is only set when it was generated
+	Password string `json:"password,omitempty"`
+}
+
+// runUser dispatches the user subcommands
+func runUser(ctx context.Context, e *env, args []string) error {
+	if len(args) == 0 {
+		return usagef("usage: user create|set-role|reset-password|delete [flags]")
+	}
+
+	switch args[0] {
+	case "create":
+		return runUserCreate(ctx, e, args[1:])
+	case "set-role":
+		return runUserSetRole(ctx, e, args[1:])
+	case "reset-password":
+		return runUserResetPassword(ctx, e, args[1:])
+	case "delete":
+		return runUserDelete(ctx, e, args[1:])
+	}
+	return usagef("unknown user command %q", args[0])
+}
+
+func runUserCreate(ctx context.Context, e *env, args []string) error {
+	var output, email, name, role, password string
+	fs := e.flagSet("user create", &output)
+	fs.StringVar(&email, "email", "", "email address (required)")
+	fs.StringVar(&name, "name", "", "display name")
+	fs.StringVar(&role, "role", auth.RoleUser, "role: admin or user")
+	fs.StringVar(&password, "password", "", "password (default: generate one)")
+	if err := parse(fs, args, &output); err != nil {
+		return err
+	}
+
+	if email == "" {
+		return usagef("-email is required")
+	}
+	if !auth.ValidRole(role) {
+		return usagef("invalid -role %q, must be %s or %s", role, auth.RoleAdmin, auth.RoleUser)
+	}
+
+	var result userResult
+	if password == "" {
+		password = generatePassword()
+		result.Password = password
+	}
+
+	if err := e.connect(ctx); err != nil {
+		return err
+	}
+	defer e.close()
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	user := models.User{
+		ID:       primitive.NewObjectID().Hex(),
+		Email:    strings.TrimSpace(email),
+		Password: hash,
+		Name:     name,
+		Role:     role,
+	}
+	if err := e.store.Users.Create(ctx, &user); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return fmt.Errorf("a user with email %s already exists", user.Email)
+		}
+		return err
+	}
+
+	result.User = models.UserResponse{ID: user.ID, Email: user.Email, Name: user.Name, Role: user.Role}
+	return e.print(output, result, userLines("Created user", result)...)
+}
+
+func runUserSetRole(ctx context.Context, e *env, args []string) error {
+	var output, id, email, role string
+	fs := e.flagSet("user set-role", &output)
+	fs.StringVar(&id, "id", "", "user ID")
+	fs.StringVar(&email, "email", "", "email address, instead of -id")
+	fs.StringVar(&role, "role", "", "role: admin or user (required)")
+	if err := parse(fs, args, &output); err != nil {
+		return err
+	}
+
+	if !auth.ValidRole(role) {
+		return usagef("invalid -role %q, must be %s or %s", role, auth.RoleAdmin, auth.RoleUser)
+	}
+
+	if err := e.connect(ctx); err != nil {
+		return err
+	}
+	defer e.close()
+
+	user, err := findUser(ctx, e.store, id, email)
+	if err != nil {
+		return err
+	}
+	if err := e.store.Users.SetRole(ctx, user.ID, role); err != nil {
+		return err
+	}
+	user.Role = role
+
+	// Sessions carry the role, so make the user log in again
+	if err := auth.RevokeUserSessions(ctx, user.ID); err != nil {
+		return fmt.Errorf("role changed but revoking sessions failed: %w", err)
+	}
+
+	result := userResult{User: models.UserResponse{ID: user.ID, Email: user.Email, Name: user.Name, Role: user.Role}}
+	return e.print(output, result, userLines("Updated user", result)...)
+}
+
+func runUserResetPassword(ctx context.Context, e *env, args []string) error {
+	var output, id, email, password string
+	fs := e.flagSet("user reset-password", &output)
+	fs.StringVar(&id, "id", "", "user ID")
+	fs.StringVar(&email, "email", "", "email address, instead of -id")
+	fs.StringVar(&password, "password", "", "new password (default: generate one)")
+	if err := parse(fs, args, &output); err != nil {
+		return err
+	}
+
+	var result userResult
+	if password == "" {
+		password = generatePassword()
+		result.Password = password
+	}
+
+	if err := e.connect(ctx); err != nil {
+		return err
+	}
+	defer e.close()
+
+	user, err := findUser(ctx, e.store, id, email)
+	if err != nil {
+		return err
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := e.store.Users.SetPassword(ctx, user.ID, hash); err != nil {
+		return err
+	}
+
+	// Existing sessions may belong to whoever knew the old password
+	if err := auth.RevokeUserSessions(ctx, user.ID); err != nil {
+		return fmt.Errorf("password changed but revoking sessions failed: %w", err)
+	}
+
+	result.User = models.UserResponse{ID: user.ID, Email: user.Email, Name: user.Name, Role: user.Role}
+	return e.print(output, result, userLines("Reset password for user", result)...)
+}
+
+func runUserDelete(ctx context.Context, e *env, args []string) error {
+	var output, id, email string
+	fs := e.flagSet("user delete", &output)
+	fs.StringVar(&id, "id", "", "user ID")
+	fs.StringVar(&email, "email", "", "email address, instead of -id")
+	if err := parse(fs, args, &output); err != nil {
+		return err
+	}
+
+	if err := e.connect(ctx); err != nil {
+		return err
+	}
+	defer e.close()
+
+	user, err := findUser(ctx, e.store, id, email)
+	if err != nil {
+		return err
+	}
+	if err := e.store.DeleteUser(ctx, user.ID); err != nil {
+		return err
+	}
+
+	result := userResult{User: models.UserResponse{ID: user.ID, Email: user.Email, Name: user.Name, Role: user.Role}}
+	return e.print(output, result, userLines("Deleted user", result)...)
+}
+
+// findUser looks a user up by ID or email
+func findUser(ctx context.Context, store *repository.Store, id, email string) (*models.User, error) {
+	var user *models.User
+	var err error
+	switch {
+	case id != "" && email != "":
+		return nil, usagef("use either -id or -email, not both")
+	case id != "":
+		user, err = store.Users.Get(ctx, id)
+	case email != "":
+		user, err = store.Users.GetByEmail(ctx, email)
+	default:
+		return nil, usagef("-id or -email is required")
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, errors.New("user not found")
+	}
+	return user, err
+}
+
+// userLines formats a user result as text
+func userLines(heading string, result userResult) []string {
+	lines := []string{
+		heading + ":",
+		"  ID:    " + result.User.ID,
+		"  Email: " + result.User.Email,
+		"  Name:  " + result.User.Name,
+		"  Role:  " + result.User.Role,
+	}
+	if result.Password != "" {
+		lines = append(lines, "  Generated password: "+result.Password)
+	}
+	return lines
+}
+
+// generatePassword returns a random 128-bit password
+func generatePassword() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"go-backend/models"
+)
+
+// AuthService calls the auth endpoints
+type AuthService struct {
+	c *Client
+}
+
+// Login exchanges credentials for a token, which the client sends with
+// every following request
+func (s *AuthService) Login(ctx context.Context, email, password string) (*models.LoginResponse, error) {
+	login, err := s.login(ctx, email, password)
+	if err != nil {
+		return nil, err
+	}
+	s.c.setToken(login.Token, login.ExpiresAt)
+	return login, nil
+}
+
+func (s *AuthService) login(ctx context.Context, email, password string) (*models.LoginResponse, error) {
+	var login models.LoginResponse
+	body := models.LoginRequest{Email: email, Password: password}
+	if err := s.c.do(ctx, request{method: http.MethodPost, path: "/auth/login", body: body, once: true}, &login); err != nil {
+		return nil, err
+	}
+	return &login, nil
+}
+
+// Logout revokes the client's token
+func (s *AuthService) Logout(ctx context.Context) error {
+	if err := s.c.do(ctx, request{method: http.MethodPost, path: "/auth/logout", auth: true}, nil); err != nil {
+		return err
+	}
+	s.c.clearToken()
+	return nil
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"go-backend/models"
+)
+
+// CategoriesService calls the category endpoints
+type CategoriesService struct {
+	c *Client
+}
+
+// CategoryNode is a category with its subcategories
+type CategoryNode struct {
+	models.Category
+	Children []*CategoryNode `json:"children"`
+}
+
+// List returns all categories
+func (s *CategoriesService) List(ctx context.Context) ([]models.Category, error) {
+	var categories []models.Category
+	err := s.c.do(ctx, request{method: http.MethodGet, path: "/categories", auth: true}, &categories)
+	return categories, err
+}
+
+// Tree returns the top-level categories with their subcategories, sorted
+// by name at every level. Categories whose parent does not exist are
+// treated as top-level.
+func (s *CategoriesService) Tree(ctx context.Context) ([]*CategoryNode, error) {
+	categories, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return BuildTree(categories), nil
+}
+
+// BuildTree arranges categories by their parent IDs
+func BuildTree(categories []models.Category) []*CategoryNode {
+	nodes := make(map[string]*CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &CategoryNode{Category: category}
+	}
+
+	var roots []*CategoryNode
+	for _, category := range categories {
+		node := nodes[category.ID]
+		parent, ok := (*CategoryNode)(nil), false
+		if category.ParentID != nil {
+			parent, ok = nodes[*category.ParentID]
+		}
+		if ok && parent != node {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	sortNodes(roots)
+	return roots
+}
+
+func sortNodes(nodes []*CategoryNode) {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	for _, node := range nodes {
+		sortNodes(node.Children)
+	}
+}
@@ -0,0 +1,262 @@
+// Package client is a typed Go client for the v2 API. It logs in and
+// renews its token on its own, retries failed requests with backoff and
+// decodes problem responses into *Error.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options configures a Client. The zero value talks to the API without
+// credentials and retries three times.
+type Options struct {
+	// HTTPClient sends the requests; http.DefaultClient when nil
+	HTTPClient *http.Client
+
+	// Email and Password are used to log in before the first request that
+	// needs a token and again whenever the token expires or is rejected
+	Email    string
+	Password string
+	// Token is a bearer token to start with, e.g. from an earlier Login
+	Token string
+
+	// MaxRetries is how often a request is retried after a 429 or 5xx
+	// response or a network error; negative disables retries. Logins are
+	// never retried.
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the exponential backoff between
+	// attempts. A Retry-After header is honored up to MaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	UserAgent string
+}
+
+// Client calls the API. It is safe for concurrent use.
+type Client struct {
+	Products   *ProductsService
+	Categories *CategoriesService
+	Auth       *AuthService
+
+	baseURL *url.URL
+	opts    Options
+
+	// mu guards the token, which is renewed by whichever request finds it
+	// expired first
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// New creates a client for the API at baseURL, e.g. http://localhost:8080
+func New(baseURL string, opts Options) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("client: invalid base URL %q", baseURL)
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = 3
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = 200 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 10 * time.Second
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = "go-backend-client"
+	}
+
+	c := &Client{baseURL: u, opts: opts, token: opts.Token}
+	c.Products = &ProductsService{c}
+	c.Categories = &CategoriesService{c}
+	c.Auth = &AuthService{c}
+	return c, nil
+}
+
+// request describes one API call
+type request struct {
+	method string
+	path   string // below /api/v2
+	query  url.Values
+	body   any
+
+	// auth sends the token, logging in first when credentials are set
+	auth bool
+	// once sends the request a single time. Failed logins count against
+	// the account's lockout, so they are never repeated.
+	once bool
+}
+
+// do sends req and decodes a successful JSON response into out, which may
+// be nil. Failed attempts are retried; 401 responses renew the token once.
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return fmt.Errorf("client: encoding request body: %w", err)
+		}
+	}
+
+	renewed := false
+	var apiErr *Error
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, req, body)
+		if errors.As(err, &apiErr) {
+			// Logging in for the token failed; retrying would only repeat
+			// the rejected credentials
+			return err
+		}
+		if err == nil && resp.StatusCode == http.StatusUnauthorized && req.auth && !renewed && c.canLogin() {
+			// The token expired or was revoked
+			resp.Body.Close()
+			c.clearToken()
+			renewed = true
+			attempt--
+			continue
+		}
+
+		if wait, ok := c.backoff(attempt, resp); ok && attempt < c.opts.MaxRetries && !req.once && retryable(req.method, resp, err) {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			select {
+			case <-time.After(wait):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if err != nil {
+			return err
+		}
+		return decode(resp, req, out)
+	}
+}
+
+func (c *Client) send(ctx context.Context, req request, body []byte) (*http.Response, error) {
+	u := *c.baseURL
+	u.Path += "/api/v2" + req.path
+	u.RawQuery = req.query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), reader)
+	if err != nil {
+		return nil, fmt.Errorf("client: %w", err)
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("User-Agent", c.opts.UserAgent)
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if req.auth {
+		token, err := c.currentToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if token != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	return c.opts.HTTPClient.Do(httpReq)
+}
+
+// decode closes resp after decoding its body into out, or into an *Error
+// for unsuccessful statuses
+func decode(resp *http.Response, req request, out any) error {
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return newError(resp, req)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("client: decoding %s %s response: %w", req.method, req.path, err)
+	}
+	return nil
+}
+
+// retryable reports whether an attempt may be repeated. Only network
+// errors and 429 or 5xx responses are retried. Requests that
+// change data are only repeated when the server refused them outright
+// with 429, so a timed out create is not applied twice.
+func retryable(method string, resp *http.Response, err error) bool {
+	idempotent := method != http.MethodPost && method != http.MethodPatch
+	if err != nil {
+		// Canceled and timed out contexts end the retries
+		return idempotent && !isContextError(err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case resp.StatusCode >= 500:
+		return idempotent
+	}
+	return false
+}
+
+// backoff is the wait before retrying after attempt: exponential with
+// jitter, or the server's Retry-After. Waits longer than MaxBackoff, such
+// as a login lockout, are not worth retrying.
+func (c *Client) backoff(attempt int, resp *http.Response) (time.Duration, bool) {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			wait := time.Duration(seconds) * time.Second
+			return wait, wait <= c.opts.MaxBackoff
+		}
+	}
+	ceiling := min(c.opts.MinBackoff<<min(attempt, 16), c.opts.MaxBackoff)
+	return ceiling/2 + rand.N(ceiling/2+1), true
+}
+
+func (c *Client) canLogin() bool {
+	return c.opts.Email != "" && c.opts.Password != ""
+}
+
+// currentToken returns the token, logging in first when there is none or
+// it has expired
+func (c *Client) currentToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	valid := c.token != "" && (c.expiresAt.IsZero() || time.Until(c.expiresAt) > 30*time.Second)
+	if valid || !c.canLogin() {
+		return c.token, nil
+	}
+	login, err := c.Auth.login(ctx, c.opts.Email, c.opts.Password)
+	if err != nil {
+		return "", err
+	}
+	c.token, c.expiresAt = login.Token, login.ExpiresAt
+	return c.token, nil
+}
+
+func (c *Client) setToken(token string, expiresAt time.Time) {
+	c.mu.Lock()
+	c.token, c.expiresAt = token, expiresAt
+	c.mu.Unlock()
+}
+
+func (c *Client) clearToken() {
+	c.setToken("", time.Time{})
+}
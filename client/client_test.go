@@ -0,0 +1,198 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-backend/client"
+	"go-backend/config"
+	"go-backend/handlers"
+	"go-backend/idempotency"
+	"go-backend/middleware"
+	"go-backend/models"
+	"go-backend/ratelimit"
+	"go-backend/repository"
+	"go-backend/routes"
+
+	"github.com/gorilla/mux"
+)
+
+// newTestClient serves the API from an in-memory store and returns a
+// client for it, along with the store
+func newTestClient(t *testing.T) (*client.Client, *repository.Store) {
+	t.Helper()
+	store := repository.NewMemoryStore()
+	handlers.SetStore(store)
+
+	cfg := config.Default()
+	router := mux.NewRouter()
+	err := routes.RegisterRoutes(router, routes.Options{
+		CORS:             middleware.NewCORS(router, cfg.CORS),
+		RateLimiter:      middleware.NewRateLimiter(ratelimit.NewMemoryStore(), cfg.Limits.RateLimit),
+		Deadlines:        middleware.NewDeadlines(cfg.Limits.Timeouts),
+		BodyLimits:       middleware.NewBodyLimits(cfg.Limits.Body),
+		Validation:       cfg.Validation,
+		GraphQL:          cfg.Limits.GraphQL,
+		Compression:      cfg.Compression,
+		Idempotency:      cfg.Idempotency,
+		Store:            store,
+		IdempotencyStore: idempotency.NewMemoryStore(),
+	})
+	if err != nil {
+		t.Fatalf("RegisterRoutes: %v", err)
+	}
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	c, err := client.New(server.URL, client.Options{HTTPClient: server.Client(), MaxRetries: -1})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c, store
+}
+
+func TestProducts(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestClient(t)
+
+	created, err := c.Products.Create(ctx, models.Product{
+		Name:       "Chair",
+		CategoryID: "furniture",
+		Attributes: []models.Attribute{{Code: "color", Value: "red", Type: "string"}},
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.ID.IsZero() || created.Name != "Chair" {
+		t.Fatalf("Create = %+v, want Chair with an ID", created)
+	}
+
+	got, err := c.Products.Get(ctx, created.ID.Hex())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Name != "Chair" || len(got.Attributes) != 1 || got.Attributes[0].Value != "red" {
+		t.Errorf("Get = %+v, want the created product", got)
+	}
+
+	got.Name = "Armchair"
+	updated, err := c.Products.Update(ctx, *got)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.Name != "Armchair" || updated.Version <= created.Version {
+		t.Errorf("Update = %+v, want the new name and a higher version than %d", updated, created.Version)
+	}
+}
+
+func TestProductsAllFetchesEveryPage(t *testing.T) {
+	ctx := context.Background()
+	c, store := newTestClient(t)
+
+	for _, name := range []string{"A", "B", "C", "D", "E"} {
+		if err := store.Products.Create(ctx, &models.Product{Name: name, CategoryID: "letters"}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	if err := store.Products.Create(ctx, &models.Product{Name: "1", CategoryID: "digits"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	var names []string
+	for product, err := range c.Products.All(ctx, client.ListOptions{PageSize: 2, CategoryID: "letters", Sort: "name"}) {
+		if err != nil {
+			t.Fatalf("All: %v", err)
+		}
+		names = append(names, product.Name)
+	}
+	if got := strings.Join(names, ""); got != "ABCDE" {
+		t.Errorf("All = %v, want the products A to E in order", names)
+	}
+}
+
+func TestProductErrors(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestClient(t)
+
+	_, err := c.Products.Get(ctx, "64b7f0c2a1b2c3d4e5f60718")
+	if !client.IsNotFound(err) {
+		t.Errorf("Get of a missing product = %v, want a 404", err)
+	}
+
+	_, err = c.Products.Create(ctx, models.Product{CategoryID: "furniture"})
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Create without a name = %v, want a 400", err)
+	}
+	if apiErr.Problem.Status != http.StatusBadRequest || apiErr.Problem.Detail == "" {
+		t.Errorf("Problem = %+v, want the decoded problem details", apiErr.Problem)
+	}
+}
+
+func TestCategoriesTree(t *testing.T) {
+	ctx := context.Background()
+	c, store := newTestClient(t)
+
+	parent := "furniture"
+	for _, category := range []models.Category{
+		{ID: "furniture", Name: "Furniture"},
+		{ID: "chairs", Name: "Chairs", ParentID: &parent},
+		{ID: "tables", Name: "Tables", ParentID: &parent},
+		{ID: "garden", Name: "Garden"},
+	} {
+		if err := store.Categories.Upsert(ctx, &category); err != nil {
+			t.Fatalf("Upsert: %v", err)
+		}
+	}
+
+	tree, err := c.Categories.Tree(ctx)
+	if err != nil {
+		t.Fatalf("Tree: %v", err)
+	}
+	if len(tree) != 2 || tree[0].Name != "Furniture" || tree[1].Name != "Garden" {
+		t.Fatalf("Tree roots = %+v, want Furniture and Garden", tree)
+	}
+	if children := tree[0].Children; len(children) != 2 || children[0].Name != "Chairs" || children[1].Name != "Tables" {
+		t.Errorf("Furniture children = %+v, want Chairs and Tables", children)
+	}
+}
+
+func TestFailedLoginsAreNotRetried(t *testing.T) {
+	for _, status := range []int{http.StatusUnauthorized, http.StatusTooManyRequests} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			logins, calls := 0, 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/api/v2/auth/login" {
+					logins++
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(status)
+					return
+				}
+				calls++
+			}))
+			defer server.Close()
+
+			c, err := client.New(server.URL, client.Options{
+				HTTPClient: server.Client(),
+				Email:      "ada@example.com",
+				Password:   "wrong",
+				MinBackoff: time.Millisecond,
+			})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			var apiErr *client.Error
+			if _, err := c.Categories.List(context.Background()); !errors.As(err, &apiErr) || apiErr.StatusCode != status {
+				t.Errorf("List = %v, want the %d from the login", err, status)
+			}
+			if logins != 1 || calls != 0 {
+				t.Errorf("sent %d logins and %d other requests, want one login only", logins, calls)
+			}
+		})
+	}
+}
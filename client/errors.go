@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-backend/problem"
+)
+
+// Error is an unsuccessful response. Problem holds the decoded
+// application/problem+json body; other bodies are kept as text in Detail.
+type Error struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Problem    problem.Problem
+	// RetryAfter is the server's Retry-After, zero when not sent
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s %s: %d %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Problem.Detail != "" {
+		msg += ": " + e.Problem.Detail
+	}
+	return msg
+}
+
+// IsNotFound reports whether err is a 404 response
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsConflict reports whether err is a 409 response
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+// IsUnauthorized reports whether err is a 401 response
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+func hasStatus(err error, status int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// newError reads the body of an unsuccessful response
+func newError(resp *http.Response, req request) error {
+	e := &Error{Method: req.method, Path: "/api/v2" + req.path, StatusCode: resp.StatusCode}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		e.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == problem.ContentType && json.Unmarshal(body, &e.Problem) == nil {
+		return e
+	}
+	e.Problem = problem.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(resp.StatusCode),
+		Status: resp.StatusCode,
+		Detail: string(body),
+	}
+	return e
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
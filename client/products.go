@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"go-backend/models"
+)
+
+// ProductsService calls the product endpoints
+type ProductsService struct {
+	c *Client
+}
+
+// ListOptions filters, sorts and pages product listings. Zero values use
+// the server defaults.
+type ListOptions struct {
+	Page     int
+	PageSize int
+
+	CategoryID    string
+	CategoryGroup string // top-level category
+
+	Sort  string // field to sort by
+	Order string // asc or desc
+}
+
+func (o ListOptions) query() url.Values {
+	q := url.Values{}
+	if o.Page > 0 {
+		q.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.PageSize > 0 {
+		q.Set("page_size", strconv.Itoa(o.PageSize))
+	}
+	for key, value := range map[string]string{
+		"category_id":    o.CategoryID,
+		"category_group": o.CategoryGroup,
+		"_sort":          o.Sort,
+		"_order":         o.Order,
+	} {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	return q
+}
+
+// List returns one page of products
+func (s *ProductsService) List(ctx context.Context, opts ListOptions) (*models.ProductPage, error) {
+	var page models.ProductPage
+	err := s.c.do(ctx, request{method: http.MethodGet, path: "/products", query: opts.query(), auth: true}, &page)
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// All iterates over every product matching opts, fetching the pages from
+// opts.Page on as the loop reaches them. An error ends the iteration.
+//
+//	for product, err := range c.Products.All(ctx, client.ListOptions{CategoryID: "2"}) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (s *ProductsService) All(ctx context.Context, opts ListOptions) iter.Seq2[models.Product, error] {
+	return func(yield func(models.Product, error) bool) {
+		opts.Page = max(opts.Page, 1)
+		for {
+			page, err := s.List(ctx, opts)
+			if err != nil {
+				yield(models.Product{}, err)
+				return
+			}
+			for _, product := range page.Products {
+				if !yield(product, nil) {
+					return
+				}
+			}
+			if !page.Pagination.HasMore || len(page.Products) == 0 {
+				return
+			}
+			opts.Page = page.Pagination.Page + 1
+		}
+	}
+}
+
+// Get returns the product with the given ID
+func (s *ProductsService) Get(ctx context.Context, id string) (*models.Product, error) {
+	var product models.Product
+	err := s.c.do(ctx, request{method: http.MethodGet, path: "/products/" + url.PathEscape(id), auth: true}, &product)
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// Create creates a product and returns it with its generated ID
+func (s *ProductsService) Create(ctx context.Context, product models.Product) (*models.Product, error) {
+	var created models.Product
+	err := s.c.do(ctx, request{method: http.MethodPost, path: "/products", body: product, auth: true}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Update replaces the product with product.ID
+func (s *ProductsService) Update(ctx context.Context, product models.Product) (*models.Product, error) {
+	var updated models.Product
+	err := s.c.do(ctx, request{method: http.MethodPut, path: "/products/" + product.ID.Hex(), body: product, auth: true}, &updated)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
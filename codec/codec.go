@@ -0,0 +1,373 @@
+// Package codec encodes responses and decodes request bodies in the media
+// types the API supports. Every format carries the same data as the JSON
+// encoding of a value, with the same field names, so one schema describes
+// them all.
+package codec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"go-backend/config"
+)
+
+// ErrUnsupportedMediaType is returned for request bodies of a content type
+// no codec decodes
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// BodyError is returned for bodies that are well-formed but refused, such
+// as a body with a field the decoded type does not have. Its message is
+// meant for the client.
+type BodyError struct {
+	Detail string
+}
+
+func (e *BodyError) Error() string {
+	return e.Detail
+}
+
+// MaxDepth is how deeply the objects and lists of a request body may nest
+var MaxDepth = config.Default().Limits.Body.MaxDepth
+
+// Codec encodes and decodes one media type
+type Codec struct {
+	// MediaType is the Content-Type of encoded values, e.g. application/json
+	MediaType string
+
+	Encode func(w io.Writer, v any) error
+
+	// Decode is nil for codecs that only write responses
+	Decode func(r io.Reader, v any) error
+
+	// ListsOnly codecs, like CSV, can only encode lists of items
+	ListsOnly bool
+
+	// TextScalars codecs, like XML, carry every scalar as text, so the
+	// trees they decode hold strings where numbers and booleans are meant
+	TextScalars bool
+
+	// decodeTree decodes without a Go type; Decode into an any otherwise
+	decodeTree func(r io.Reader) (any, error)
+}
+
+// contentType is the Content-Type header of encoded values
+func (c *Codec) contentType() string {
+	if strings.HasPrefix(c.MediaType, "text/") {
+		return c.MediaType + "; charset=utf-8"
+	}
+	return c.MediaType
+}
+
+// codecs are the registered codecs in order of preference. JSON comes first,
+// so it is chosen when the client has no preference.
+var codecs []*Codec
+
+// Register adds a codec. Codecs registered later are preferred less.
+func Register(c *Codec) {
+	codecs = append(codecs, c)
+}
+
+// Lookup returns the codec of a media type, or nil
+func Lookup(mediaType string) *Codec {
+	for _, c := range codecs {
+		if c.MediaType == mediaType {
+			return c
+		}
+	}
+	return nil
+}
+
+// All returns the registered codecs in order of preference
+func All() []*Codec {
+	return codecs
+}
+
+// JSON returns the JSON codec, used when nothing was negotiated
+func JSON() *Codec {
+	return Lookup("application/json")
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the codec chosen for the response
+func NewContext(ctx context.Context, c *Codec) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the codec chosen for the response, or JSON if none was
+func FromContext(ctx context.Context) *Codec {
+	if c, ok := ctx.Value(contextKey{}).(*Codec); ok {
+		return c
+	}
+	return JSON()
+}
+
+// Negotiate picks the codec for an Accept header from offered, which is in
+// order of preference. The media range that matches a codec most
+// specifically gives its quality; the codec with the highest quality wins,
+// and ties go to the earlier codec. An empty header accepts anything. It
+// returns nil if no codec is acceptable.
+func Negotiate(accept string, offered []*Codec) *Codec {
+	if strings.TrimSpace(accept) == "" {
+		if len(offered) == 0 {
+			return nil
+		}
+		return offered[0]
+	}
+	ranges := parseAccept(accept)
+
+	var best *Codec
+	bestQuality := 0.0
+	for _, c := range offered {
+		quality, specificity := 0.0, -1
+		for _, mr := range ranges {
+			if s := mr.matches(c.MediaType); s > specificity {
+				quality, specificity = mr.quality, s
+			}
+		}
+		if quality > bestQuality {
+			best, bestQuality = c, quality
+		}
+	}
+	return best
+}
+
+// mediaRange is one entry of an Accept header
+type mediaRange struct {
+	typ, subtype string
+	quality      float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok {
+			continue
+		}
+		mr := mediaRange{typ: typ, subtype: subtype, quality: 1}
+		if q, ok := params["q"]; ok {
+			if quality, err := strconv.ParseFloat(q, 64); err == nil && quality >= 0 && quality <= 1 {
+				mr.quality = quality
+			}
+		}
+		ranges = append(ranges, mr)
+	}
+	return ranges
+}
+
+// matches returns how specifically the range matches mediaType: 2 for the
+// exact type, 1 for type/* and 0 for */*, or -1 if it does not match
+func (mr mediaRange) matches(mediaType string) int {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	switch {
+	case mr.typ == typ && mr.subtype == subtype:
+		return 2
+	case mr.typ == typ && mr.subtype == "*":
+		return 1
+	case mr.typ == "*" && mr.subtype == "*":
+		return 0
+	}
+	return -1
+}
+
+// Write sends v in the codec negotiated for r
+func Write(w http.ResponseWriter, r *http.Request, status int, v any) {
+	c := FromContext(r.Context())
+	if c.ListsOnly {
+		c = JSON()
+	}
+	write(w, r, c, status, v)
+}
+
+// WriteList sends a list response in the codec negotiated for r: v for
+// most codecs, and items, the slice v holds, for codecs of lists only
+func WriteList(w http.ResponseWriter, r *http.Request, status int, v, items any) {
+	c := FromContext(r.Context())
+	if c.ListsOnly {
+		v = items
+	}
+	write(w, r, c, status, v)
+}
+
+// write encodes v before sending it, so encoding errors still get a 500
+func write(w http.ResponseWriter, r *http.Request, c *Codec, status int, v any) {
+	var buf bytes.Buffer
+	if err := c.Encode(&buf, v); err != nil {
+		log.Printf("Error encoding %s response for %s %s: %v", c.MediaType, r.Method, r.URL.Path, err)
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", c.contentType())
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// Decode decodes the body of r with the codec of its Content-Type. A
+// missing Content-Type is taken to be JSON. Every codec decodes strictly:
+// fields v does not have, anything after the first value and nesting
+// deeper than MaxDepth are refused with a *BodyError.
+func Decode(r *http.Request, v any) error {
+	c := JSON()
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return fmt.Errorf("%w: %q", ErrUnsupportedMediaType, contentType)
+		}
+		c = Lookup(mediaType)
+		if c == nil || c.Decode == nil {
+			return fmt.Errorf("%w: %s", ErrUnsupportedMediaType, mediaType)
+		}
+	}
+	return c.Decode(r.Body, v)
+}
+
+// DecodeTree decodes a body into its JSON data model, with json.Number for
+// numbers, so it can be checked before it is decoded into a Go type.
+// Trailing data and nesting deeper than MaxDepth are refused as by Decode.
+func (c *Codec) DecodeTree(r io.Reader) (any, error) {
+	var tree any
+	var err error
+	if c.decodeTree != nil {
+		tree, err = c.decodeTree(r)
+	} else {
+		err = c.Decode(r, &tree)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(tree)
+	if err != nil {
+		return nil, err
+	}
+	if MaxDepth > 0 && depth(b) > MaxDepth {
+		return nil, &BodyError{Detail: fmt.Sprintf("The body is nested deeper than %d levels", MaxDepth)}
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// toTree converts v to its JSON data model: nil, bool, int64, float64,
+// string, []any and map[string]any
+func toTree(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return numbers(tree), nil
+}
+
+// numbers replaces the json.Numbers of a tree with int64 or float64
+func numbers(tree any) any {
+	switch t := tree.(type) {
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return n
+		}
+		f, _ := t.Float64()
+		return f
+	case []any:
+		for i, item := range t {
+			t[i] = numbers(item)
+		}
+	case map[string]any:
+		for k, item := range t {
+			t[k] = numbers(item)
+		}
+	}
+	return tree
+}
+
+// fromTree stores a decoded tree in v as if it had been decoded from JSON
+func fromTree(tree any, v any) error {
+	b, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return unmarshal(b, v)
+}
+
+// unmarshal decodes the JSON document b into v, refusing unknown fields,
+// values after the first and nesting deeper than MaxDepth
+func unmarshal(b []byte, v any) error {
+	if MaxDepth > 0 && depth(b) > MaxDepth {
+		return &BodyError{Detail: fmt.Sprintf("The body is nested deeper than %d levels", MaxDepth)}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		// The only error encoding/json reports this way
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return &BodyError{Detail: "The body has an unknown field " + field}
+		}
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errTrailingData
+	}
+	return nil
+}
+
+// errTrailingData is returned for bodies with more than one value
+var errTrailingData = &BodyError{Detail: "The body must hold a single value"}
+
+// depth returns how deeply the objects and lists of a JSON document nest
+func depth(b []byte) int {
+	deepest, level := 0, 0
+	inString, escaped := false, false
+	for _, c := range b {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			escaped = c == '\\'
+			inString = c != '"'
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			level++
+			deepest = max(deepest, level)
+		case c == '}' || c == ']':
+			level--
+		}
+	}
+	return deepest
+}
+
+// typeName is the name of v's type with pointers and slices removed
+func typeName(v any) string {
+	t := reflect.TypeOf(v)
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		t = t.Elem()
+	}
+	if t == nil {
+		return ""
+	}
+	return t.Name()
+}
@@ -0,0 +1,156 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Record is implemented by list items that choose their own CSV columns.
+// Other items get a column per JSON field, with nested objects flattened
+// to columns like pagination.page and lists written as JSON.
+type Record interface {
+	CSVRecord() (columns, values []string)
+}
+
+// table collects rows whose columns are the union of every row's columns,
+// in the order they were first seen
+type table struct {
+	columns []string
+	index   map[string]int
+	rows    []map[string]string
+}
+
+func (t *table) add(row map[string]string, columns []string) {
+	for _, column := range columns {
+		if _, ok := t.index[column]; !ok {
+			t.index[column] = len(t.columns)
+			t.columns = append(t.columns, column)
+		}
+	}
+	t.rows = append(t.rows, row)
+}
+
+// encodeCSV writes a slice with a header line and one line per item
+func encodeCSV(w io.Writer, v any) error {
+	items := reflect.Indirect(reflect.ValueOf(v))
+	if items.Kind() != reflect.Slice && items.Kind() != reflect.Array {
+		return fmt.Errorf("CSV needs a list, got %T", v)
+	}
+
+	t := &table{index: make(map[string]int)}
+	for i := range items.Len() {
+		item := items.Index(i).Interface()
+		if record, ok := item.(Record); ok {
+			columns, values := record.CSVRecord()
+			row := make(map[string]string, len(columns))
+			for i, column := range columns {
+				row[column] = values[i]
+			}
+			t.add(row, columns)
+			continue
+		}
+
+		raw, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		row := make(map[string]string)
+		var columns []string
+		if err := flatten(raw, "", func(column, value string) {
+			row[column] = value
+			columns = append(columns, column)
+		}); err != nil {
+			return err
+		}
+		t.add(row, columns)
+	}
+
+	writer := csv.NewWriter(w)
+	line := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		line[i] = inert(column)
+	}
+	if err := writer.Write(line); err != nil {
+		return err
+	}
+	for _, row := range t.rows {
+		for i, column := range t.columns {
+			line[i] = inert(row[column])
+		}
+		if err := writer.Write(line); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// flatten calls set for each field of a JSON object in order. Values that
+// are not objects are a single column named value.
+func flatten(raw json.RawMessage, prefix string, set func(column, value string)) error {
+	if len(raw) == 0 || raw[0] != '{' {
+		column := prefix
+		if column == "" {
+			column = "value"
+		}
+		set(column, cell(raw))
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil { // {
+		return err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		column := prefix + key.(string)
+		if len(value) > 0 && value[0] == '{' {
+			if err := flatten(value, column+".", set); err != nil {
+				return err
+			}
+			continue
+		}
+		set(column, cell(value))
+	}
+	return nil
+}
+
+// cell formats a JSON value for a CSV cell: strings without quotes, null
+// as an empty cell, and lists and objects as JSON
+func cell(raw json.RawMessage) string {
+	switch {
+	case len(raw) == 0 || string(raw) == "null":
+		return ""
+	case raw[0] == '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return s
+		}
+	}
+	return string(raw)
+}
+
+// inert keeps spreadsheets from running a cell as a formula. Cells that
+// start like one, other than plain numbers, are prefixed with a quote.
+func inert(s string) string {
+	if s == "" || !strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return s
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return s
+	}
+	return "'" + s
+}
@@ -0,0 +1,52 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/csv"
+	"slices"
+	"testing"
+)
+
+func TestCSVCellsAreNotFormulas(t *testing.T) {
+	type item struct {
+		Name  string  `json:"name"`
+		Price float64 `json:"price"`
+	}
+	items := []item{
+		{`=HYPERLINK("http://evil.example","x")`, -5},
+		{"+1+cmd|' /C calc'!A0", 1},
+		{"-2", 2},
+		{"@SUM(A1)", 3},
+		{"\tTab", 4},
+		{"\rReturn", 5},
+		{"Chair", 6},
+	}
+
+	var buf bytes.Buffer
+	if err := encodeCSV(&buf, items); err != nil {
+		t.Fatalf("encodeCSV: %v", err)
+	}
+	lines, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("reading the CSV back: %v", err)
+	}
+
+	want := [][]string{
+		{"name", "price"},
+		{`'=HYPERLINK("http://evil.example","x")`, "-5"},
+		{"'+1+cmd|' /C calc'!A0", "1"},
+		{"-2", "2"},
+		{"'@SUM(A1)", "3"},
+		{"'\tTab", "4"},
+		{"'\rReturn", "5"},
+		{"Chair", "6"},
+	}
+	for i, line := range lines {
+		if i >= len(want) || !slices.Equal(line, want[i]) {
+			t.Errorf("line %d = %q, want %q", i, line, want[min(i, len(want)-1)])
+		}
+	}
+	if len(lines) != len(want) {
+		t.Errorf("got %d lines, want %d", len(lines), len(want))
+	}
+}
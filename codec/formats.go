@@ -0,0 +1,80 @@
+package codec
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func init() {
+	Register(&Codec{MediaType: "application/json", Encode: encodeJSON, Decode: decodeJSON})
+	Register(&Codec{MediaType: "application/msgpack", Encode: encodeMsgpack, Decode: decodeMsgpack})
+	Register(&Codec{MediaType: "application/cbor", Encode: encodeCBOR, Decode: decodeCBOR})
+	Register(&Codec{MediaType: "application/xml", Encode: encodeXML, Decode: decodeXML, TextScalars: true, decodeTree: decodeXMLTree})
+	Register(&Codec{MediaType: "text/csv", Encode: encodeCSV, ListsOnly: true})
+}
+
+func encodeJSON(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func decodeJSON(r io.Reader, v any) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return unmarshal(b, v)
+}
+
+// Map keys are sorted so equal values encode to equal bytes
+func encodeMsgpack(w io.Writer, v any) error {
+	tree, err := toTree(v)
+	if err != nil {
+		return err
+	}
+	enc := msgpack.NewEncoder(w)
+	enc.SetSortMapKeys(true)
+	enc.UseCompactInts(true)
+	return enc.Encode(tree)
+}
+
+func decodeMsgpack(r io.Reader, v any) error {
+	dec := msgpack.NewDecoder(r)
+	dec.UseLooseInterfaceDecoding(true)
+	var tree, next any
+	if err := dec.Decode(&tree); err != nil {
+		return err
+	}
+	if err := dec.Decode(&next); err != io.EOF {
+		return errTrailingData
+	}
+	return fromTree(tree, v)
+}
+
+var (
+	cborEncoder, _ = cbor.EncOptions{Sort: cbor.SortCoreDeterministic, ShortestFloat: cbor.ShortestFloat16}.EncMode()
+	cborDecoder, _ = cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]any(nil))}.DecMode()
+)
+
+func encodeCBOR(w io.Writer, v any) error {
+	tree, err := toTree(v)
+	if err != nil {
+		return err
+	}
+	return cborEncoder.NewEncoder(w).Encode(tree)
+}
+
+func decodeCBOR(r io.Reader, v any) error {
+	dec := cborDecoder.NewDecoder(r)
+	var tree, next any
+	if err := dec.Decode(&tree); err != nil {
+		return err
+	}
+	if err := dec.Decode(&next); err != io.EOF {
+		return errTrailingData
+	}
+	return fromTree(tree, v)
+}
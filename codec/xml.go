@@ -0,0 +1,364 @@
+package codec
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// XML documents mirror the JSON encoding: object fields become elements
+// named after their JSON field, the items of an array become elements named
+// after the singular of the array's field, e.g. <attributes><attribute>,
+// and null values are left out. The root element is named after the Go
+// type, e.g. <product> or <categories><category>. Keys that are not XML
+// names are written as <entry key="...">.
+
+// encodeXML writes v by walking its JSON encoding, which keeps the order of
+// struct fields
+func encodeXML(w io.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	root := snakeCase(typeName(v))
+	if root == "" {
+		root = "response"
+	}
+	if kind := reflect.Indirect(reflect.ValueOf(v)).Kind(); kind == reflect.Slice || kind == reflect.Array {
+		root = plural(root)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	if err := writeXML(enc, dec, root); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// writeXML writes the next JSON value of dec as an element called name
+func writeXML(enc *xml.Encoder, dec *json.Decoder, name string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if !isXMLName(name) {
+		start = xml.StartElement{
+			Name: xml.Name{Local: "entry"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: name}},
+		}
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		item := singular(name)
+		for dec.More() {
+			if t == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				item = key.(string)
+			}
+			if err := writeXML(enc, dec, item); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil { // closing delimiter
+			return err
+		}
+	case string:
+		err = enc.EncodeToken(xml.CharData(t))
+	case json.Number:
+		err = enc.EncodeToken(xml.CharData(t.String()))
+	case bool:
+		err = enc.EncodeToken(xml.CharData(strconv.FormatBool(t)))
+	}
+	if err != nil {
+		return err
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// xmlNode is a parsed element
+type xmlNode struct {
+	name     string
+	text     string
+	children []*xmlNode
+}
+
+// decodeXML parses the document and converts it to JSON guided by the type
+// of v, so that fields typed as numbers or lists decode as such
+func decodeXML(r io.Reader, v any) error {
+	root, err := parseXML(r)
+	if err != nil {
+		return err
+	}
+	return fromTree(xmlValue(root, reflect.TypeOf(v)), v)
+}
+
+// decodeXMLTree parses the document into the JSON data model without a
+// type to guide it, keeping every scalar as text
+func decodeXMLTree(r io.Reader) (any, error) {
+	root, err := parseXML(r)
+	if err != nil {
+		return nil, err
+	}
+	return xmlTree(root, func(n *xmlNode) any { return n.text }), nil
+}
+
+func parseXML(r io.Reader) (*xmlNode, error) {
+	dec := xml.NewDecoder(r)
+	var stack []*xmlNode
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil, errors.New("XML document has no root element")
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name.Local}
+			for _, attr := range t.Attr {
+				if node.name == "entry" && attr.Name.Local == "key" {
+					node.name = attr.Value
+				}
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			}
+			stack = append(stack, node)
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		case xml.EndElement:
+			node := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return node, trailingXML(dec)
+			}
+		}
+	}
+}
+
+// trailingXML checks that nothing but whitespace, comments and processing
+// instructions follows the root element
+func trailingXML(dec *xml.Decoder) error {
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.Comment, xml.ProcInst:
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				return errTrailingData
+			}
+		default:
+			return errTrailingData
+		}
+	}
+}
+
+var (
+	jsonUnmarshaler = reflect.TypeFor[json.Unmarshaler]()
+	textUnmarshaler = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// xmlValue converts n to the JSON data model of type t. Text that does not
+// fit t is kept as a string, so decoding reports the mismatch.
+func xmlValue(n *xmlNode, t reflect.Type) any {
+	for t != nil && t.Kind() == reflect.Pointer {
+		if t.Implements(jsonUnmarshaler) || t.Implements(textUnmarshaler) {
+			return n.text
+		}
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() == reflect.Interface {
+		return xmlAny(n)
+	}
+	if reflect.PointerTo(t).Implements(jsonUnmarshaler) || reflect.PointerTo(t).Implements(textUnmarshaler) {
+		return n.text
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := jsonFields(t)
+		object := make(map[string]any, len(n.children))
+		for _, child := range n.children {
+			field, ok := fields[child.name]
+			if !ok {
+				object[child.name] = xmlAny(child)
+				continue
+			}
+			object[child.name] = xmlValue(child, field)
+		}
+		return object
+	case reflect.Map:
+		object := make(map[string]any, len(n.children))
+		for _, child := range n.children {
+			object[child.name] = xmlValue(child, t.Elem())
+		}
+		return object
+	case reflect.Slice, reflect.Array:
+		items := make([]any, len(n.children))
+		for i, child := range n.children {
+			items[i] = xmlValue(child, t.Elem())
+		}
+		return items
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(strings.TrimSpace(n.text)); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if number := json.Number(strings.TrimSpace(n.text)); json.Valid([]byte(number)) {
+			return number
+		}
+	}
+	return n.text
+}
+
+// xmlAny converts an element without a Go type to guide it. Text is a
+// boolean, a number or a string, whichever it reads as.
+func xmlAny(n *xmlNode) any {
+	return xmlTree(n, func(n *xmlNode) any {
+		text := strings.TrimSpace(n.text)
+		if text == "true" || text == "false" {
+			return text == "true"
+		}
+		if number := json.Number(text); text != "" && json.Valid([]byte(number)) && (text[0] == '-' || unicode.IsDigit(rune(text[0]))) {
+			return number
+		}
+		return n.text
+	})
+}
+
+// xmlTree converts an element by its shape: elements whose children are
+// all named after the singular of its name are lists, other elements with
+// children are objects, and elements without children are scalars
+func xmlTree(n *xmlNode, scalar func(*xmlNode) any) any {
+	if len(n.children) == 0 {
+		return scalar(n)
+	}
+
+	item := singular(n.name)
+	list := true
+	for _, child := range n.children {
+		list = list && child.name == item
+	}
+	if list {
+		items := make([]any, len(n.children))
+		for i, child := range n.children {
+			items[i] = xmlTree(child, scalar)
+		}
+		return items
+	}
+	object := make(map[string]any, len(n.children))
+	for _, child := range n.children {
+		object[child.name] = xmlTree(child, scalar)
+	}
+	return object
+}
+
+// jsonFields maps the JSON names of a struct's fields to their types
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// singular names the items of a list field: categories holds category and
+// products holds product. Other names hold item.
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss"):
+		return strings.TrimSuffix(name, "s")
+	}
+	return "item"
+}
+
+func plural(name string) string {
+	if strings.HasSuffix(name, "y") {
+		return strings.TrimSuffix(name, "y") + "ies"
+	}
+	return name + "s"
+}
+
+// snakeCase converts a Go type name like ProductPage to product_page
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isXMLName reports whether name can be used as an element name as is
+func isXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+		case i > 0 && (r == '-' || r == '.' || unicode.IsDigit(r)):
+		default:
+			return false
+		}
+	}
+	return true
+}
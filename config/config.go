@@ -0,0 +1,443 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"go-backend/ratelimit"
+)
+
+// Environments
+const (
+	EnvDevelopment = "development"
+	EnvTest        = "test"
+	EnvStaging     = "staging"
+	EnvProduction  = "production"
+)
+
+// Rate limit keys
+const (
+	RateLimitByIP     = "ip"      // client address
+	RateLimitByAPIKey = "api_key" // authenticated API key, falling back to the client address
+	RateLimitByUser   = "user"    // authenticated user, falling back to the client address
+)
+
+// Config is the complete application configuration.
+//
+// Fields are read from, in increasing priority: defaults, a YAML or TOML
+// config file, .env, environment variables (`env` tag) and command line
+// flags named after the YAML path (e.g. -server.port). Fields tagged
+// `secret` are redacted when the configuration is printed.
+type Config struct {
+	// Environment is development, test, staging or production
+	Environment string `yaml:"environment" toml:"environment" env:"APP_ENV"`
+
+	Server   Server   `yaml:"server" toml:"server"`
+	GRPC     GRPC     `yaml:"grpc" toml:"grpc"`
+	Database Database `yaml:"database" toml:"database"`
+	Auth     Auth     `yaml:"auth" toml:"auth"`
+	CORS     CORS     `yaml:"cors" toml:"cors"`
+	Logging  Logging  `yaml:"logging" toml:"logging"`
+	Limits   Limits   `yaml:"limits" toml:"limits"`
+	Tracing  Tracing  `yaml:"tracing" toml:"tracing"`
+	Health   Health   `yaml:"health" toml:"health"`
+
+	Compression Compression `yaml:"compression" toml:"compression"`
+	Idempotency Idempotency `yaml:"idempotency" toml:"idempotency"`
+
+	Validation Validation `yaml:"validation" toml:"validation"`
+	API        API        `yaml:"api" toml:"api"`
+
+	// Debug re-raises recovered handler panics
+	Debug bool `yaml:"debug" toml:"debug" env:"DEBUG"`
+}
+
+// Server holds the HTTP server settings
+type Server struct {
+	Port              string        `yaml:"port" toml:"port" env:"PORT"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes" toml:"max_header_bytes" env:"SERVER_MAX_HEADER_BYTES"`
+
+	// ShutdownDelay is how long readiness fails before the listener closes,
+	// giving load balancers time to stop sending traffic
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" toml:"shutdown_delay" env:"SERVER_SHUTDOWN_DELAY"`
+	// ShutdownTimeout bounds how long in-flight requests may take to drain
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+
+	// TrustedProxies lists proxy IPs or CIDR ranges allowed to set X-Forwarded-For
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+}
+
+// GRPC holds the settings of the gRPC server for internal services. It
+// shares the shutdown settings of the HTTP server.
+type GRPC struct {
+	Enabled bool   `yaml:"enabled" toml:"enabled" env:"GRPC_ENABLED"`
+	Port    string `yaml:"port" toml:"port" env:"GRPC_PORT"`
+	// Reflection lets tools like grpcurl list the services
+	Reflection bool `yaml:"reflection" toml:"reflection" env:"GRPC_REFLECTION"`
+}
+
+// Database holds the MongoDB connection settings. Options set here take
+// precedence over the same options in the URI.
+type Database struct {
+	URI     string `yaml:"uri" toml:"uri" env:"MONGODB_URI" secret:"url"`
+	Name    string `yaml:"name" toml:"name" env:"DB_NAME"`
+	AppName string `yaml:"app_name" toml:"app_name" env:"DB_APP_NAME"` // shown in server logs and currentOp
+
+	// MigrateOnStart applies pending migrations before serving
+	MigrateOnStart bool `yaml:"migrate_on_start" toml:"migrate_on_start" env:"MIGRATE_ON_START"`
+
+	// Connection pool
+	MaxPoolSize     int           `yaml:"max_pool_size" toml:"max_pool_size" env:"DB_MAX_POOL_SIZE"`
+	MinPoolSize     int           `yaml:"min_pool_size" toml:"min_pool_size" env:"DB_MIN_POOL_SIZE"`
+	MaxConnIdleTime time.Duration `yaml:"max_conn_idle_time" toml:"max_conn_idle_time" env:"DB_MAX_CONN_IDLE_TIME"` // 0 keeps idle connections
+
+	// Timeouts
+	ConnectTimeout         time.Duration `yaml:"connect_timeout" toml:"connect_timeout" env:"DB_CONNECT_TIMEOUT"`
+	ServerSelectionTimeout time.Duration `yaml:"server_selection_timeout" toml:"server_selection_timeout" env:"DB_SERVER_SELECTION_TIMEOUT"`
+	SocketTimeout          time.Duration `yaml:"socket_timeout" toml:"socket_timeout" env:"DB_SOCKET_TIMEOUT"` // 0 relies on request deadlines
+	HeartbeatInterval      time.Duration `yaml:"heartbeat_interval" toml:"heartbeat_interval" env:"DB_HEARTBEAT_INTERVAL"`
+
+	// ReadPreference is primary, primaryPreferred, secondary,
+	// secondaryPreferred or nearest
+	ReadPreference string `yaml:"read_preference" toml:"read_preference" env:"DB_READ_PREFERENCE"`
+	// ReadConcern is local, available, majority, linearizable or snapshot;
+	// empty uses the server default
+	ReadConcern  string       `yaml:"read_concern" toml:"read_concern" env:"DB_READ_CONCERN"`
+	WriteConcern WriteConcern `yaml:"write_concern" toml:"write_concern" env:"DB_WRITE_CONCERN"`
+
+	TLS DatabaseTLS `yaml:"tls" toml:"tls" env:"DB_TLS"`
+
+	// StartupTimeout is how long to keep retrying the first connection,
+	// with exponential backoff starting at StartupBackoff
+	StartupTimeout time.Duration `yaml:"startup_timeout" toml:"startup_timeout" env:"DB_STARTUP_TIMEOUT"`
+	StartupBackoff time.Duration `yaml:"startup_backoff" toml:"startup_backoff" env:"DB_STARTUP_BACKOFF"`
+
+	CircuitBreaker CircuitBreaker `yaml:"circuit_breaker" toml:"circuit_breaker" env:"DB_CIRCUIT_BREAKER"`
+}
+
+// WriteConcern controls write acknowledgement
+type WriteConcern struct {
+	W        string        `yaml:"w" toml:"w" env:"W"` // "majority", a number of nodes, or empty for the server default
+	Journal  bool          `yaml:"journal" toml:"journal" env:"JOURNAL"`
+	WTimeout time.Duration `yaml:"wtimeout" toml:"wtimeout" env:"WTIMEOUT"`
+}
+
+// DatabaseTLS holds TLS settings for the MongoDB connection
+type DatabaseTLS struct {
+	Enabled            bool   `yaml:"enabled" toml:"enabled" env:"ENABLED"`
+	CAFile             string `yaml:"ca_file" toml:"ca_file" env:"CA_FILE"`
+	CertFile           string `yaml:"cert_file" toml:"cert_file" env:"CERT_FILE"` // client certificate
+	KeyFile            string `yaml:"key_file" toml:"key_file" env:"KEY_FILE"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" toml:"insecure_skip_verify" env:"INSECURE_SKIP_VERIFY"`
+}
+
+// CircuitBreaker makes requests fail fast with 503 while the database is
+// unreachable
+type CircuitBreaker struct {
+	Enabled bool `yaml:"enabled" toml:"enabled" env:"ENABLED"`
+	// Cooldown is how often a request is let through to probe the database
+	Cooldown time.Duration `yaml:"cooldown" toml:"cooldown" env:"COOLDOWN"`
+}
+
+// Auth holds session and brute-force protection settings
+type Auth struct {
+	// SessionTTL is how long an access token stays valid
+	SessionTTL time.Duration `yaml:"session_ttl" toml:"session_ttl" env:"SESSION_TTL"`
+	Lockout    Lockout       `yaml:"lockout" toml:"lockout"`
+}
+
+// Lockout controls brute-force protection for logins
+type Lockout struct {
+	MaxAccountFailures int           `yaml:"max_account_failures" toml:"max_account_failures" env:"LOGIN_MAX_ACCOUNT_FAILURES"`
+	MaxIPFailures      int           `yaml:"max_ip_failures" toml:"max_ip_failures" env:"LOGIN_MAX_IP_FAILURES"`
+	LockoutDuration    time.Duration `yaml:"lockout_duration" toml:"lockout_duration" env:"LOGIN_LOCKOUT_DURATION"`
+	FailureWindow      time.Duration `yaml:"failure_window" toml:"failure_window" env:"LOGIN_FAILURE_WINDOW"`
+	BaseDelay          time.Duration `yaml:"base_delay" toml:"base_delay" env:"LOGIN_BASE_DELAY"`
+	MaxDelay           time.Duration `yaml:"max_delay" toml:"max_delay" env:"LOGIN_MAX_DELAY"`
+}
+
+// CORSPolicy describes which cross-origin requests are allowed
+type CORSPolicy struct {
+	// AllowedOrigins lists exact origins ("https://app.example.com"),
+	// wildcard subdomain patterns ("https://*.example.com") or "*"
+	AllowedOrigins   []string `yaml:"allowed_origins" toml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowedMethods   []string `yaml:"allowed_methods" toml:"allowed_methods" env:"CORS_ALLOWED_METHODS"`
+	AllowedHeaders   []string `yaml:"allowed_headers" toml:"allowed_headers" env:"CORS_ALLOWED_HEADERS"`
+	ExposedHeaders   []string `yaml:"exposed_headers" toml:"exposed_headers" env:"CORS_EXPOSED_HEADERS"`
+	MaxAge           int      `yaml:"max_age" toml:"max_age" env:"CORS_MAX_AGE"` // seconds
+	AllowCredentials bool     `yaml:"allow_credentials" toml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+}
+
+// CORS is the default CORS policy plus per-route overrides keyed by path template
+type CORS struct {
+	CORSPolicy `yaml:",inline"`
+	Routes     map[string]CORSPolicy `yaml:"routes" toml:"routes"`
+}
+
+// Logging holds log output settings
+type Logging struct {
+	Level  string `yaml:"level" toml:"level" env:"LOG_LEVEL"`    // debug, info, warn or error
+	Format string `yaml:"format" toml:"format" env:"LOG_FORMAT"` // text or json
+}
+
+// Limits holds request limits
+type Limits struct {
+	RateLimit RateLimits `yaml:"rate_limit" toml:"rate_limit"`
+	Timeouts  Timeouts   `yaml:"timeouts" toml:"timeouts"`
+	GraphQL   GraphQL    `yaml:"graphql" toml:"graphql" env:"GRAPHQL"`
+	Body      Body       `yaml:"body" toml:"body" env:"BODY"`
+}
+
+// Timeouts holds the time budget of each request: a default plus overrides
+// keyed by route, either "METHOD /path/template" or "/path/template"
+type Timeouts struct {
+	Default time.Duration            `yaml:"default" toml:"default" env:"REQUEST_TIMEOUT"`
+	Routes  map[string]time.Duration `yaml:"routes" toml:"routes"`
+}
+
+// GraphQL bounds the cost of a GraphQL operation before it runs
+type GraphQL struct {
+	MaxDepth      int `yaml:"max_depth" toml:"max_depth" env:"MAX_DEPTH"`                // levels of nested fields
+	MaxComplexity int `yaml:"max_complexity" toml:"max_complexity" env:"MAX_COMPLEXITY"` // fields, multiplied by list sizes
+}
+
+// Body bounds request bodies: their size in bytes, a default plus overrides
+// keyed by route like Timeouts, and the shape of the documents they hold
+type Body struct {
+	MaxBytes      int64            `yaml:"max_bytes" toml:"max_bytes" env:"MAX_BYTES"`
+	Routes        map[string]int64 `yaml:"routes" toml:"routes"`
+	MaxDepth      int              `yaml:"max_depth" toml:"max_depth" env:"MAX_DEPTH"`                // levels of nested objects and lists
+	MaxAttributes int              `yaml:"max_attributes" toml:"max_attributes" env:"MAX_ATTRIBUTES"` // attributes of a product
+}
+
+// RateLimits holds the policies applied by the rate limiter
+type RateLimits struct {
+	Enabled bool            `yaml:"enabled" toml:"enabled" env:"RATE_LIMIT_ENABLED"`
+	Read    RateLimitPolicy `yaml:"read" toml:"read" env:"RATE_LIMIT_READ"`    // safe methods
+	Write   RateLimitPolicy `yaml:"write" toml:"write" env:"RATE_LIMIT_WRITE"` // unsafe methods
+	Auth    RateLimitPolicy `yaml:"auth" toml:"auth" env:"RATE_LIMIT_AUTH"`    // endpoints that check credentials
+}
+
+// RateLimitPolicy is a named limit applied per key
+type RateLimitPolicy struct {
+	Name  string          `yaml:"-" toml:"-"`
+	Limit ratelimit.Limit `yaml:"limit" toml:"limit" env:""`
+	Key   string          `yaml:"key" toml:"key" env:"KEY"`
+}
+
+// Tracing holds OpenTelemetry settings
+type Tracing struct {
+	Exporter     string  `yaml:"exporter" toml:"exporter" env:"TRACING_EXPORTER"` // otlp, stdout, file or none
+	OTLPEndpoint string  `yaml:"otlp_endpoint" toml:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT"`
+	File         string  `yaml:"file" toml:"file" env:"TRACING_FILE"`
+	SampleRatio  float64 `yaml:"sample_ratio" toml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+	ServiceName  string  `yaml:"service_name" toml:"service_name" env:"TRACING_SERVICE_NAME"`
+}
+
+// Health holds health check settings
+type Health struct {
+	CacheTTL     time.Duration `yaml:"cache_ttl" toml:"cache_ttl" env:"HEALTH_CACHE_TTL"`
+	CheckTimeout time.Duration `yaml:"check_timeout" toml:"check_timeout" env:"HEALTH_CHECK_TIMEOUT"`
+}
+
+// Compression controls compressing responses and decompressing request
+// bodies. Encodings are listed in order of preference, used when a client
+// accepts several equally.
+type Compression struct {
+	Enabled bool `yaml:"enabled" toml:"enabled" env:"COMPRESSION_ENABLED"`
+	// MinSize is the smallest response, in bytes, worth compressing
+	MinSize      int      `yaml:"min_size" toml:"min_size" env:"COMPRESSION_MIN_SIZE"`
+	Encodings    []string `yaml:"encodings" toml:"encodings" env:"COMPRESSION_ENCODINGS"`             // zstd, br and gzip
+	ContentTypes []string `yaml:"content_types" toml:"content_types" env:"COMPRESSION_CONTENT_TYPES"` // media types that are compressed
+	// DecompressRequests accepts request bodies sent with a Content-Encoding
+	// of one of Encodings
+	DecompressRequests bool `yaml:"decompress_requests" toml:"decompress_requests" env:"COMPRESSION_DECOMPRESS_REQUESTS"`
+}
+
+// Idempotency controls replaying the stored response to unsafe requests
+// retried with the same Idempotency-Key header
+type Idempotency struct {
+	Enabled bool          `yaml:"enabled" toml:"enabled" env:"IDEMPOTENCY_ENABLED"`
+	TTL     time.Duration `yaml:"ttl" toml:"ttl" env:"IDEMPOTENCY_TTL"` // how long responses are kept
+}
+
+// Validation controls checking requests and responses against the OpenAPI
+// document
+type Validation struct {
+	// Requests rejects requests whose parameters or body do not match the
+	// documented operation
+	Requests bool `yaml:"requests" toml:"requests" env:"VALIDATE_REQUESTS"`
+	// Responses replaces responses that do not match the document with a
+	// 500; development and test only
+	Responses bool `yaml:"responses" toml:"responses" env:"VALIDATE_RESPONSES"`
+}
+
+// API holds the lifecycle of each API version
+type API struct {
+	V1 Deprecation `yaml:"v1" toml:"v1" env:"API_V1"`
+}
+
+// Deprecation holds the dates, as YYYY-MM-DD, when an API version was
+// deprecated and when it stops being served. They are required in staging
+// and production; elsewhere empty dates are not announced.
+type Deprecation struct {
+	Since  string `yaml:"deprecated" toml:"deprecated" env:"DEPRECATED"`
+	Sunset string `yaml:"sunset" toml:"sunset" env:"SUNSET"`
+}
+
+// DateLayout is the layout of dates in the configuration
+const DateLayout = time.DateOnly
+
+// Dates parses the deprecation and sunset dates, zero when empty
+func (d Deprecation) Dates() (since, sunset time.Time, err error) {
+	if d.Since != "" {
+		if since, err = time.Parse(DateLayout, d.Since); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid deprecation date %q, expected YYYY-MM-DD", d.Since)
+		}
+	}
+	if d.Sunset != "" {
+		if sunset, err = time.Parse(DateLayout, d.Sunset); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid sunset date %q, expected YYYY-MM-DD", d.Sunset)
+		}
+	}
+	return since, sunset, nil
+}
+
+// Default returns the configuration used when nothing else is set
+func Default() *Config {
+	return &Config{
+		Environment: EnvDevelopment,
+		Server: Server{
+			Port:              "8080",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			MaxHeaderBytes:    1 << 20,
+			ShutdownDelay:     5 * time.Second,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		GRPC: GRPC{
+			Enabled:    true,
+			Port:       "9090",
+			Reflection: true,
+		},
+		Database: Database{
+			URI:                    "mongodb://localhost:27017",
+			Name:                   "mydb",
+			AppName:                "go-backend",
+			MaxPoolSize:            100,
+			ConnectTimeout:         10 * time.Second,
+			ServerSelectionTimeout: 5 * time.Second,
+			HeartbeatInterval:      10 * time.Second,
+			ReadPreference:         "primary",
+			StartupTimeout:         time.Minute,
+			StartupBackoff:         500 * time.Millisecond,
+			CircuitBreaker: CircuitBreaker{
+				Enabled:  true,
+				Cooldown: 5 * time.Second,
+			},
+		},
+		Auth: Auth{
+			SessionTTL: 24 * time.Hour,
+			Lockout: Lockout{
+				MaxAccountFailures: 5,
+				MaxIPFailures:      20,
+				LockoutDuration:    15 * time.Minute,
+				FailureWindow:      15 * time.Minute,
+				BaseDelay:          time.Second,
+				MaxDelay:           30 * time.Second,
+			},
+		},
+		CORS: CORS{
+			CORSPolicy: CORSPolicy{
+				AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+				AllowedHeaders: []string{"Content-Type", "Authorization", "X-Requested-With"},
+				MaxAge:         3600,
+			},
+		},
+		Logging: Logging{
+			Level:  "info",
+			Format: "text",
+		},
+		Limits: Limits{
+			RateLimit: RateLimits{
+				Enabled: true,
+				Read:    RateLimitPolicy{Name: "read", Limit: ratelimit.Limit{Requests: 300, Per: time.Minute, Burst: 300}, Key: RateLimitByIP},
+				Write:   RateLimitPolicy{Name: "write", Limit: ratelimit.Limit{Requests: 60, Per: time.Minute, Burst: 60}, Key: RateLimitByIP},
+				Auth:    RateLimitPolicy{Name: "auth", Limit: ratelimit.Limit{Requests: 10, Per: time.Minute, Burst: 5}, Key: RateLimitByIP},
+			},
+			Timeouts: Timeouts{
+				Default: 10 * time.Second,
+			},
+			GraphQL: GraphQL{
+				MaxDepth:      10,
+				MaxComplexity: 1000,
+			},
+			Body: Body{
+				MaxBytes:      1 << 20,
+				MaxDepth:      32,
+				MaxAttributes: 100,
+			},
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			File:        "traces.json",
+			SampleRatio: 1,
+			ServiceName: "go-backend",
+		},
+		Health: Health{
+			CacheTTL:     2 * time.Second,
+			CheckTimeout: 2 * time.Second,
+		},
+		Compression: Compression{
+			Enabled:   true,
+			MinSize:   1024,
+			Encodings: []string{"zstd", "br", "gzip"},
+			ContentTypes: []string{
+				"application/json", "application/problem+json", "application/xml",
+				"application/msgpack", "application/cbor", "text/csv", "text/plain", "text/html",
+			},
+			DecompressRequests: true,
+		},
+		Idempotency: Idempotency{
+			Enabled: true,
+			TTL:     24 * time.Hour,
+		},
+		Validation: Validation{
+			Requests: true,
+		},
+	}
+}
+
+// IsProduction reports whether the production environment is configured
+func (c *Config) IsProduction() bool {
+	return c.Environment == EnvProduction
+}
+
+// TrustedProxyNetworks parses the trusted proxy list
+func (s Server) TrustedProxyNetworks() ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range s.TrustedProxies {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
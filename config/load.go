@@ -0,0 +1,304 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// field is a configurable leaf value
+type field struct {
+	path   string // dotted YAML path, also the flag name
+	env    string // environment variable, "" if none
+	secret string // value of the secret tag
+	value  reflect.Value
+}
+
+// Load builds the configuration from all sources and validates it. The
+// config file is taken from -config or CONFIG_FILE. Arguments left after
+// flag parsing are returned.
+func Load(args []string) (*Config, []string, error) {
+	cfg := Default()
+
+	// The config file has to be known before flags are applied
+	path := configFileFromArgs(args)
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := loadFile(cfg, path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// .env never overrides variables already set in the environment
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("reading .env: %w", err)
+	}
+
+	if err := applyEnv(cfg); err != nil {
+		return nil, nil, err
+	}
+
+	rest, err := applyFlags(cfg, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, rest, nil
+}
+
+// loadFile overlays a YAML or TOML file onto cfg
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("parsing %s: unknown key %q", path, undecoded[0].String())
+		}
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// applyEnv sets every field whose environment variable is set
+func applyEnv(cfg *Config) error {
+	for _, f := range fields(cfg) {
+		if f.env == "" {
+			continue
+		}
+		if s, ok := os.LookupEnv(f.env); ok && s != "" {
+			if err := setValue(f.value, s); err != nil {
+				return fmt.Errorf("%s: %w", f.env, err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyFlags parses -<yaml path> flags into cfg
+func applyFlags(cfg *Config, args []string) ([]string, error) {
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	fs.String("config", "", "YAML or TOML config file (env CONFIG_FILE)")
+
+	for _, f := range fields(cfg) {
+		usage := "set " + f.path
+		if f.env != "" {
+			usage += " (env " + f.env + ")"
+		}
+		fs.Var(flagValue{f.value}, f.path, usage)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return fs.Args(), nil
+}
+
+// configFileFromArgs finds -config/--config without parsing other flags
+func configFileFromArgs(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// fields lists the configurable leaves of cfg
+func fields(cfg *Config) []field {
+	return walk(reflect.ValueOf(cfg).Elem(), "", "", false)
+}
+
+func walk(v reflect.Value, path, envPrefix string, hasPrefix bool) []field {
+	var result []field
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+
+		// Environment names nest under a parent's env tag; an empty tag
+		// means the parent's name itself
+		envTag, hasEnv := sf.Tag.Lookup("env")
+		env := envTag
+		if hasPrefix {
+			switch {
+			case !hasEnv:
+				env = ""
+			case envTag == "":
+				env = envPrefix
+			default:
+				env = envPrefix + "_" + envTag
+			}
+		}
+
+		fv := v.Field(i)
+		if sf.Anonymous || opts == "inline" {
+			result = append(result, walk(fv, path, envPrefix, hasPrefix)...)
+			continue
+		}
+		if fv.Kind() == reflect.Struct && !isTextValue(fv) {
+			result = append(result, walk(fv, fieldPath, env, hasEnv)...)
+			continue
+		}
+		if fv.Kind() == reflect.Map {
+			continue
+		}
+
+		result = append(result, field{
+			path:   fieldPath,
+			env:    env,
+			secret: sf.Tag.Get("secret"),
+			value:  fv,
+		})
+	}
+	return result
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func isTextValue(v reflect.Value) bool {
+	_, ok := v.Addr().Interface().(encoding.TextUnmarshaler)
+	return ok
+}
+
+// setValue parses s into v
+func setValue(v reflect.Value, s string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q (e.g. 30s, 5m)", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported config type %s", v.Type())
+	}
+	return nil
+}
+
+// formatValue renders v the way setValue reads it
+func formatValue(v reflect.Value) string {
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, _ := m.MarshalText()
+		return string(text)
+	}
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	if v.Kind() == reflect.Slice {
+		return strings.Join(v.Interface().([]string), ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// flagValue adapts a config field to flag.Value
+type flagValue struct {
+	v reflect.Value
+}
+
+func (f flagValue) String() string {
+	if !f.v.IsValid() {
+		return ""
+	}
+	return formatValue(f.v)
+}
+
+func (f flagValue) Set(s string) error {
+	return setValue(f.v, s)
+}
+
+func (f flagValue) IsBoolFlag() bool {
+	return f.v.IsValid() && f.v.Kind() == reflect.Bool
+}
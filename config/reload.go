@@ -0,0 +1,238 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadable lists the config paths that can change without a restart
+var reloadable = []string{
+	"cors.",
+	"logging.level",
+	"limits.rate_limit.",
+	"limits.timeouts.",
+	"limits.body.max_bytes",
+}
+
+// Change is a single difference between two configurations
+type Change struct {
+	Path       string
+	Old        string
+	New        string
+	Reloadable bool
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Path, c.Old, c.New)
+}
+
+// Diff lists the differences between two configurations, with secrets masked
+func Diff(prev, next *Config) []Change {
+	oldFields := fields(prev.Redacted())
+	newFields := fields(next.Redacted())
+
+	var changes []Change
+	for i, f := range oldFields {
+		oldValue, newValue := formatValue(f.value), formatValue(newFields[i].value)
+		if oldValue != newValue {
+			changes = append(changes, Change{
+				Path:       f.path,
+				Old:        oldValue,
+				New:        newValue,
+				Reloadable: isReloadable(f.path),
+			})
+		}
+	}
+
+	// Maps are not walked as fields
+	if !reflect.DeepEqual(prev.CORS.Routes, next.CORS.Routes) {
+		changes = append(changes, Change{
+			Path:       "cors.routes",
+			Old:        fmt.Sprint(prev.CORS.Routes),
+			New:        fmt.Sprint(next.CORS.Routes),
+			Reloadable: true,
+		})
+	}
+	if !reflect.DeepEqual(prev.Limits.Timeouts.Routes, next.Limits.Timeouts.Routes) {
+		changes = append(changes, Change{
+			Path:       "limits.timeouts.routes",
+			Old:        fmt.Sprint(prev.Limits.Timeouts.Routes),
+			New:        fmt.Sprint(next.Limits.Timeouts.Routes),
+			Reloadable: true,
+		})
+	}
+	if !reflect.DeepEqual(prev.Limits.Body.Routes, next.Limits.Body.Routes) {
+		changes = append(changes, Change{
+			Path:       "limits.body.routes",
+			Old:        fmt.Sprint(prev.Limits.Body.Routes),
+			New:        fmt.Sprint(next.Limits.Body.Routes),
+			Reloadable: true,
+		})
+	}
+	return changes
+}
+
+func isReloadable(path string) bool {
+	for _, prefix := range reloadable {
+		if path == prefix || strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reloader reloads the configuration on SIGHUP or when the config file
+// changes, and hands valid configurations to an apply function
+type Reloader struct {
+	args  []string
+	apply func(*Config)
+
+	mu      sync.Mutex
+	current *Config
+}
+
+// NewReloader creates a reloader for a configuration loaded from args.
+// apply must swap the reloadable settings into the running server.
+func NewReloader(current *Config, args []string, apply func(*Config)) *Reloader {
+	return &Reloader{args: args, apply: apply, current: current}
+}
+
+// Current returns the configuration in effect
+func (r *Reloader) Current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// Reload loads and validates the configuration again. On error the running
+// configuration is kept. Only reloadable settings take effect; other changes
+// are logged as needing a restart.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, _, err := Load(r.args)
+	if err != nil {
+		log.Printf("Config reload rejected, keeping current configuration: %v", err)
+		return err
+	}
+
+	changes := Diff(r.current, next)
+	if len(changes) == 0 {
+		log.Println("Config reloaded: no changes")
+		return nil
+	}
+
+	applied := *r.current
+	for _, change := range changes {
+		if change.Reloadable {
+			log.Printf("Config reloaded: %s", change)
+		} else {
+			log.Printf("Config change needs a restart to take effect: %s", change)
+		}
+	}
+
+	// Keep non-reloadable settings as they are so Current reflects reality
+	applied.CORS = next.CORS
+	applied.Logging.Level = next.Logging.Level
+	applied.Limits.RateLimit = next.Limits.RateLimit
+	applied.Limits.Timeouts = next.Limits.Timeouts
+	applied.Limits.Body.MaxBytes = next.Limits.Body.MaxBytes
+	applied.Limits.Body.Routes = next.Limits.Body.Routes
+
+	r.apply(&applied)
+	r.current = &applied
+	return nil
+}
+
+// Run reloads on SIGHUP and on writes to the config file until ctx is done
+func (r *Reloader) Run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	fileChanged := r.watchFile(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			log.Println("SIGHUP received, reloading configuration")
+			r.Reload()
+		case <-fileChanged:
+			log.Println("Config file changed, reloading configuration")
+			r.Reload()
+		}
+	}
+}
+
+// watchFile reports changes to the config file, debounced so editors that
+// write in several steps trigger a single reload
+func (r *Reloader) watchFile(ctx context.Context) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+
+	path := configFileFromArgs(r.args)
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path == "" {
+		return changed
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("Config file watching disabled: %v", err)
+		return changed
+	}
+
+	// Watch the directory so files replaced by rename are still seen
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		log.Printf("Config file watching disabled: %v", err)
+		watcher.Close()
+		return changed
+	}
+	name := filepath.Clean(path)
+
+	go func() {
+		defer watcher.Close()
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) == name && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+					debounce = time.After(200 * time.Millisecond)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Config file watcher error: %v", err)
+			case <-debounce:
+				debounce = nil
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changed
+}
@@ -0,0 +1,56 @@
+package config_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-backend/config"
+	"go-backend/middleware"
+)
+
+func TestReloadAppliesBodyLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(body string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte("limits:\n  body:\n"+body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("    max_bytes: 1024\n    max_depth: 32\n")
+
+	args := []string{"-config", path}
+	cfg, _, err := config.Load(args)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	limits := middleware.NewBodyLimits(cfg.Limits.Body)
+	reloader := config.NewReloader(cfg, args, func(next *config.Config) {
+		limits.Update(next.Limits.Body)
+	})
+
+	write("    max_bytes: 16\n    max_depth: 8\n    routes:\n      PUT /items: 64\n")
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	// The new limits are enforced
+	handler := limits.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(strings.Repeat("x", 32))))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status of a 32 byte body = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+
+	// max_depth needs a restart, so Current keeps the value in effect
+	current := reloader.Current().Limits.Body
+	if current.MaxBytes != 16 || current.Routes["PUT /items"] != 64 {
+		t.Errorf("current body limits = %+v, want max_bytes 16 and the PUT /items route", current)
+	}
+	if current.MaxDepth != 32 {
+		t.Errorf("current max_depth = %d, want 32 until a restart", current.MaxDepth)
+	}
+}
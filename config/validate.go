@@ -0,0 +1,353 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Validate checks the whole configuration and reports every problem found
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(path, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	switch c.Environment {
+	case EnvDevelopment, EnvTest, EnvStaging, EnvProduction:
+	default:
+		fail("environment", "must be development, test, staging or production, got %q", c.Environment)
+	}
+
+	// Server
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		fail("server.port", "must be a number between 1 and 65535, got %q", c.Server.Port)
+	}
+	for path, d := range map[string]int64{
+		"server.read_timeout":        int64(c.Server.ReadTimeout),
+		"server.read_header_timeout": int64(c.Server.ReadHeaderTimeout),
+		"server.write_timeout":       int64(c.Server.WriteTimeout),
+		"server.idle_timeout":        int64(c.Server.IdleTimeout),
+		"server.shutdown_timeout":    int64(c.Server.ShutdownTimeout),
+	} {
+		if d <= 0 {
+			fail(path, "must be positive")
+		}
+	}
+	if c.Server.ShutdownDelay < 0 {
+		fail("server.shutdown_delay", "must not be negative")
+	}
+	if c.Server.MaxHeaderBytes <= 0 {
+		fail("server.max_header_bytes", "must be positive")
+	}
+	if _, err := c.Server.TrustedProxyNetworks(); err != nil {
+		fail("server.trusted_proxies", "%v", err)
+	}
+
+	// gRPC
+	if c.GRPC.Enabled {
+		if port, err := strconv.Atoi(c.GRPC.Port); err != nil || port < 1 || port > 65535 {
+			fail("grpc.port", "must be a number between 1 and 65535, got %q", c.GRPC.Port)
+		} else if c.GRPC.Port == c.Server.Port {
+			fail("grpc.port", "must differ from server.port")
+		}
+	}
+
+	// Database
+	if !strings.HasPrefix(c.Database.URI, "mongodb://") && !strings.HasPrefix(c.Database.URI, "mongodb+srv://") {
+		fail("database.uri", "must start with mongodb:// or mongodb+srv://")
+	}
+	if c.Database.Name == "" {
+		fail("database.name", "must not be empty")
+	}
+	if c.Database.MaxPoolSize < 0 || c.Database.MinPoolSize < 0 {
+		fail("database.max_pool_size", "pool sizes must not be negative")
+	} else if c.Database.MaxPoolSize > 0 && c.Database.MinPoolSize > c.Database.MaxPoolSize {
+		fail("database.min_pool_size", "must not exceed max_pool_size")
+	}
+	for path, d := range map[string]time.Duration{
+		"database.max_conn_idle_time":     c.Database.MaxConnIdleTime,
+		"database.socket_timeout":         c.Database.SocketTimeout,
+		"database.write_concern.wtimeout": c.Database.WriteConcern.WTimeout,
+		"database.startup_timeout":        c.Database.StartupTimeout,
+	} {
+		if d < 0 {
+			fail(path, "must not be negative")
+		}
+	}
+	for path, d := range map[string]time.Duration{
+		"database.connect_timeout":          c.Database.ConnectTimeout,
+		"database.server_selection_timeout": c.Database.ServerSelectionTimeout,
+		"database.heartbeat_interval":       c.Database.HeartbeatInterval,
+		"database.startup_backoff":          c.Database.StartupBackoff,
+	} {
+		if d <= 0 {
+			fail(path, "must be positive")
+		}
+	}
+	if c.Database.HeartbeatInterval > 0 && c.Database.HeartbeatInterval < 500*time.Millisecond {
+		fail("database.heartbeat_interval", "must be at least 500ms")
+	}
+	switch c.Database.ReadPreference {
+	case "primary", "primaryPreferred", "secondary", "secondaryPreferred", "nearest":
+	default:
+		fail("database.read_preference", "must be primary, primaryPreferred, secondary, secondaryPreferred or nearest, got %q", c.Database.ReadPreference)
+	}
+	switch c.Database.ReadConcern {
+	case "", "local", "available", "majority", "linearizable", "snapshot":
+	default:
+		fail("database.read_concern", "must be local, available, majority, linearizable or snapshot, got %q", c.Database.ReadConcern)
+	}
+	if w := c.Database.WriteConcern.W; w != "" && w != "majority" {
+		if n, err := strconv.Atoi(w); err != nil || n < 0 {
+			fail("database.write_concern.w", "must be \"majority\" or a number of nodes, got %q", w)
+		}
+	}
+	if tls := c.Database.TLS; (tls.CertFile == "") != (tls.KeyFile == "") {
+		fail("database.tls", "cert_file and key_file must be set together")
+	}
+	if c.Database.CircuitBreaker.Enabled && c.Database.CircuitBreaker.Cooldown <= 0 {
+		fail("database.circuit_breaker.cooldown", "must be positive")
+	}
+
+	// Auth
+	if c.Auth.SessionTTL <= 0 {
+		fail("auth.session_ttl", "must be positive")
+	}
+	if c.Auth.Lockout.MaxAccountFailures <= 0 {
+		fail("auth.lockout.max_account_failures", "must be positive")
+	}
+	if c.Auth.Lockout.MaxIPFailures <= 0 {
+		fail("auth.lockout.max_ip_failures", "must be positive")
+	}
+	if c.Auth.Lockout.LockoutDuration <= 0 {
+		fail("auth.lockout.lockout_duration", "must be positive")
+	}
+	if c.Auth.Lockout.FailureWindow <= 0 {
+		fail("auth.lockout.failure_window", "must be positive")
+	}
+	if c.Auth.Lockout.BaseDelay < 0 || c.Auth.Lockout.MaxDelay < c.Auth.Lockout.BaseDelay {
+		fail("auth.lockout.max_delay", "must be at least base_delay")
+	}
+
+	// CORS
+	if err := c.CORS.CORSPolicy.Validate(); err != nil {
+		fail("cors", "%v", err)
+	}
+	for route, policy := range c.CORS.Routes {
+		if err := policy.Validate(); err != nil {
+			fail("cors.routes."+route, "%v", err)
+		}
+	}
+
+	// Logging
+	switch strings.ToLower(c.Logging.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		fail("logging.level", "must be debug, info, warn or error, got %q", c.Logging.Level)
+	}
+	switch strings.ToLower(c.Logging.Format) {
+	case "text", "json":
+	default:
+		fail("logging.format", "must be text or json, got %q", c.Logging.Format)
+	}
+
+	// Limits
+	for _, p := range []struct {
+		path   string
+		policy RateLimitPolicy
+	}{
+		{"limits.rate_limit.read", c.Limits.RateLimit.Read},
+		{"limits.rate_limit.write", c.Limits.RateLimit.Write},
+		{"limits.rate_limit.auth", c.Limits.RateLimit.Auth},
+	} {
+		if p.policy.Limit.Requests <= 0 || p.policy.Limit.Per <= 0 || p.policy.Limit.Burst <= 0 {
+			fail(p.path+".limit", "must be written as requests/duration, e.g. 100/1m")
+		}
+		switch p.policy.Key {
+		case RateLimitByIP, RateLimitByAPIKey, RateLimitByUser:
+		default:
+			fail(p.path+".key", "must be ip, api_key or user, got %q", p.policy.Key)
+		}
+	}
+
+	c.Limits.Timeouts.validate(c.Server.WriteTimeout, fail)
+	if c.Limits.GraphQL.MaxDepth <= 0 {
+		fail("limits.graphql.max_depth", "must be positive")
+	}
+	if c.Limits.GraphQL.MaxComplexity <= 0 {
+		fail("limits.graphql.max_complexity", "must be positive")
+	}
+	c.Limits.Body.validate(fail)
+
+	// Tracing
+	switch strings.ToLower(c.Tracing.Exporter) {
+	case "none", "otlp", "stdout", "file":
+	default:
+		fail("tracing.exporter", "must be otlp, stdout, file or none, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sample_ratio", "must be between 0 and 1")
+	}
+
+	// Health
+	if c.Health.CacheTTL < 0 {
+		fail("health.cache_ttl", "must not be negative")
+	}
+	if c.Health.CheckTimeout <= 0 {
+		fail("health.check_timeout", "must be positive")
+	}
+
+	// Compression
+	if c.Compression.MinSize < 0 {
+		fail("compression.min_size", "must not be negative")
+	}
+	for _, encoding := range c.Compression.Encodings {
+		switch encoding {
+		case "zstd", "br", "gzip":
+		default:
+			fail("compression.encodings", "must be zstd, br or gzip, got %q", encoding)
+		}
+	}
+	if (c.Compression.Enabled || c.Compression.DecompressRequests) && len(c.Compression.Encodings) == 0 {
+		fail("compression.encodings", "must not be empty")
+	}
+
+	// Idempotency
+	if c.Idempotency.TTL <= 0 {
+		fail("idempotency.ttl", "must be positive")
+	}
+
+	// Validation
+	if c.Validation.Responses && c.Environment != EnvDevelopment && c.Environment != EnvTest {
+		fail("validation.responses", "is only allowed in the development and test environments")
+	}
+
+	// API versions
+	if c.Environment == EnvStaging || c.Environment == EnvProduction {
+		if c.API.V1.Since == "" {
+			fail("api.v1.deprecated", "must be set in the %s environment", c.Environment)
+		}
+		if c.API.V1.Sunset == "" {
+			fail("api.v1.sunset", "must be set in the %s environment", c.Environment)
+		}
+	}
+	if since, sunset, err := c.API.V1.Dates(); err != nil {
+		fail("api.v1", "%v", err)
+	} else if !since.IsZero() && !sunset.IsZero() && !sunset.After(since) {
+		fail("api.v1.sunset", "must be after the deprecation date")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Validate rejects CORS policies browsers would refuse or that are unsafe
+func (p CORSPolicy) Validate() error {
+	for _, origin := range p.AllowedOrigins {
+		if origin == "*" {
+			if p.AllowCredentials {
+				return errors.New("the \"*\" origin cannot be combined with credentials")
+			}
+			continue
+		}
+		if !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			return fmt.Errorf("origin %q must start with http:// or https://", origin)
+		}
+		if strings.Count(origin, "*") > 1 || (strings.Contains(origin, "*") && !strings.Contains(origin, "://*.")) {
+			return fmt.Errorf("origin %q may only use a leading \"*.\" subdomain wildcard", origin)
+		}
+	}
+	if p.MaxAge < 0 {
+		return errors.New("max_age must not be negative")
+	}
+	return nil
+}
+
+// Redacted returns a copy of the configuration with secrets masked
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	for _, f := range fields(&redacted) {
+		if f.secret == "" || f.value.Kind() != reflect.String || f.value.String() == "" {
+			continue
+		}
+		if f.secret == "url" {
+			if u, err := url.Parse(f.value.String()); err == nil {
+				f.value.SetString(u.Redacted())
+				continue
+			}
+		}
+		f.value.SetString("REDACTED")
+	}
+	return &redacted
+}
+
+// String renders the configuration as YAML with secrets masked
+func (c *Config) String() string {
+	out, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return err.Error()
+	}
+	return string(out)
+}
+
+// validate checks request budgets; they must end before the server gives up
+// writing the response, or clients never see the 504
+func (t Timeouts) validate(writeTimeout time.Duration, fail func(path, format string, args ...any)) {
+	check := func(path string, d time.Duration) {
+		switch {
+		case d <= 0:
+			fail(path, "must be positive")
+		case writeTimeout > 0 && d >= writeTimeout:
+			fail(path, "must be shorter than server.write_timeout (%s)", writeTimeout)
+		}
+	}
+
+	check("limits.timeouts.default", t.Default)
+	for route, d := range t.Routes {
+		path := "limits.timeouts.routes." + route
+		if !validRouteKey(route) {
+			fail(path, `must be keyed by "METHOD /path" or "/path"`)
+		}
+		check(path, d)
+	}
+}
+
+func (b Body) validate(fail func(path, format string, args ...any)) {
+	if b.MaxBytes <= 0 {
+		fail("limits.body.max_bytes", "must be positive")
+	}
+	for route, limit := range b.Routes {
+		path := "limits.body.routes." + route
+		if !validRouteKey(route) {
+			fail(path, `must be keyed by "METHOD /path" or "/path"`)
+		}
+		if limit <= 0 {
+			fail(path, "must be positive")
+		}
+	}
+	if b.MaxDepth <= 0 {
+		fail("limits.body.max_depth", "must be positive")
+	}
+	if b.MaxAttributes <= 0 {
+		fail("limits.body.max_attributes", "must be positive")
+	}
+}
+
+// validRouteKey reports whether route is written as "METHOD /path/template"
+// or "/path/template"
+func validRouteKey(route string) bool {
+	method, tmpl, hasMethod := strings.Cut(route, " ")
+	if !hasMethod {
+		tmpl = method
+	}
+	return strings.HasPrefix(tmpl, "/") && (!hasMethod || (method != "" && strings.ToUpper(method) == method))
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateRequiresAPIDatesInProduction(t *testing.T) {
+	cfg := Default()
+	cfg.Environment = EnvProduction
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate accepted production without the API v1 dates")
+	}
+	for _, path := range []string{"api.v1.deprecated", "api.v1.sunset"} {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("Validate error does not mention %s: %v", path, err)
+		}
+	}
+
+	cfg.API.V1 = Deprecation{Since: "2026-10-19", Sunset: "2027-04-30"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate with both dates: %v", err)
+	}
+}
+
+func TestValidateAPIDates(t *testing.T) {
+	tests := []struct {
+		name string
+		v1   Deprecation
+		want string
+	}{
+		{"empty in development", Deprecation{}, ""},
+		{"malformed", Deprecation{Since: "19.10.2026", Sunset: "2027-04-30"}, "api.v1"},
+		{"sunset before deprecation", Deprecation{Since: "2027-04-30", Sunset: "2026-10-19"}, "api.v1.sunset"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.API.V1 = tt.v1
+			err := cfg.Validate()
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("Validate: %v", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("Validate = %v, want an error for %s", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"log/slog"
+
+	"go-backend/circuit"
+	"go-backend/config"
+	"go-backend/metrics"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// Breaker is open while no server is available for the configured read
+// preference. Requests can check it to fail fast instead of waiting for
+// server selection to time out.
+var Breaker = newBreaker()
+
+func newBreaker() *circuit.Breaker {
+	b := circuit.New(config.Default().Database.CircuitBreaker.Cooldown)
+	b.OnChange = func(from, to circuit.State) {
+		metrics.DatabaseCircuit.Set(to.String())
+		switch to {
+		case circuit.Open:
+			slog.Warn("Database unavailable, circuit breaker opened")
+		case circuit.Closed:
+			slog.Info("Database available again, circuit breaker closed")
+		}
+	}
+	return b
+}
+
+// breakerMonitor trips the breaker when the driver loses every usable
+// server and resets it as soon as one is back. The driver notices failures
+// from operations immediately and from heartbeats otherwise.
+func breakerMonitor(mode readpref.Mode) *event.ServerMonitor {
+	return &event.ServerMonitor{
+		TopologyDescriptionChanged: func(e *event.TopologyDescriptionChangedEvent) {
+			switch {
+			case e.NewDescription.HasReadableServer(mode):
+				Breaker.Reset()
+			case e.PreviousDescription.HasReadableServer(mode):
+				Breaker.Trip()
+			}
+		},
+	}
+}
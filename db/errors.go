@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+// IsUnavailable reports whether err means no MongoDB server could be
+// reached, as opposed to a server that answered too slowly
+func IsUnavailable(err error) bool {
+	var selectionErr topology.ServerSelectionError
+	switch {
+	case errors.As(err, &selectionErr):
+		return true
+	case errors.Is(err, mongo.ErrClientDisconnected), errors.Is(err, topology.ErrTopologyClosed):
+		return true
+	}
+	return mongo.IsNetworkError(err) && !mongo.IsTimeout(err)
+}
+
+// IsTimeout reports whether err means an operation ran out of time, either
+// the request deadline or the server-side maxTimeMS
+func IsTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || mongo.IsTimeout(err)
+}
+
+// MaxTime returns the server-side time limit for an operation run under
+// ctx: the remaining budget minus a margin for the round trip, so the
+// server gives up before the client does. It returns 0, meaning no limit,
+// when ctx has no deadline.
+func MaxTime(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0
+	}
+	remaining := time.Until(deadline)
+	margin := min(remaining/10, 100*time.Millisecond)
+	return max(remaining-margin, time.Millisecond)
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"go-backend/config"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+)
+
+// clientOptions builds driver options from the configuration
+func clientOptions(cfg config.Database, serverMonitor *event.ServerMonitor) (*options.ClientOptions, error) {
+	opts := options.Client().
+		ApplyURI(cfg.URI).
+		SetAppName(cfg.AppName).
+		SetMaxPoolSize(uint64(cfg.MaxPoolSize)).
+		SetMinPoolSize(uint64(cfg.MinPoolSize)).
+		SetMaxConnIdleTime(cfg.MaxConnIdleTime).
+		SetConnectTimeout(cfg.ConnectTimeout).
+		SetServerSelectionTimeout(cfg.ServerSelectionTimeout).
+		SetHeartbeatInterval(cfg.HeartbeatInterval).
+		// Record a child span for every MongoDB command
+		SetMonitor(otelmongo.NewMonitor()).
+		SetServerMonitor(serverMonitor)
+
+	if cfg.SocketTimeout > 0 {
+		opts.SetSocketTimeout(cfg.SocketTimeout)
+	}
+
+	mode, err := readpref.ModeFromString(cfg.ReadPreference)
+	if err != nil {
+		return nil, err
+	}
+	rp, err := readpref.New(mode)
+	if err != nil {
+		return nil, err
+	}
+	opts.SetReadPreference(rp)
+
+	if cfg.ReadConcern != "" {
+		opts.SetReadConcern(&readconcern.ReadConcern{Level: cfg.ReadConcern})
+	}
+
+	if wc := writeConcern(cfg.WriteConcern); wc != nil {
+		opts.SetWriteConcern(wc)
+	}
+
+	if cfg.TLS.Enabled {
+		tlsConfig, err := tlsConfig(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	return opts, opts.Validate()
+}
+
+// writeConcern returns nil when nothing is configured
+func writeConcern(cfg config.WriteConcern) *writeconcern.WriteConcern {
+	if cfg.W == "" && !cfg.Journal && cfg.WTimeout == 0 {
+		return nil
+	}
+
+	wc := &writeconcern.WriteConcern{WTimeout: cfg.WTimeout}
+	if cfg.W == "majority" {
+		wc.W = "majority"
+	} else if n, err := strconv.Atoi(cfg.W); err == nil {
+		wc.W = n
+	}
+	if cfg.Journal {
+		journal := true
+		wc.Journal = &journal
+	}
+	return wc
+}
+
+func tlsConfig(cfg config.DatabaseTLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading database CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("database CA file contains no certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading database client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
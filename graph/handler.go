@@ -0,0 +1,167 @@
+// Package graph serves a GraphQL API over the same store as the REST
+// handlers. Lookups of categories and of the products of many categories
+// are batched per request, and operations are checked against depth and
+// complexity limits before they run.
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go-backend/config"
+	"go-backend/middleware"
+	"go-backend/models"
+	"go-backend/openapi"
+	"go-backend/problem"
+	"go-backend/repository"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Handler runs GraphQL operations sent as GET query parameters or as a
+// POST JSON body
+type Handler struct {
+	schema graphql.Schema
+	store  *repository.Store
+	limits config.GraphQL
+}
+
+// New creates the GraphQL handler. Product inputs are validated against
+// the request body schemas of the matching REST operations in spec. The
+// schema is fixed, so failing to build it is a bug and panics.
+func New(store *repository.Store, spec *openapi.Document, limits config.GraphQL) *Handler {
+	schema, err := newSchema(store, spec)
+	if err != nil {
+		panic(fmt.Sprintf("graph: building schema: %v", err))
+	}
+	return &Handler{schema: schema, store: store, limits: limits}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req models.GraphQLRequest
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				problem.Write(w, r, http.StatusBadRequest, "variables must be a JSON object")
+				return
+			}
+		}
+	default:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				problem.Write(w, r, http.StatusRequestEntityTooLarge, middleware.TooLargeDetail(tooLarge.Limit))
+				return
+			}
+			problem.Write(w, r, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	if req.Query == "" {
+		problem.Write(w, r, http.StatusBadRequest, "query is required")
+		return
+	}
+
+	// Parse, validate and measure the operation before running anything
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"})})
+	if err != nil {
+		writeRejected(w, CodeParseFailed, gqlerrors.FormatErrors(err))
+		return
+	}
+	if validation := graphql.ValidateDocument(&h.schema, doc, nil); !validation.IsValid {
+		writeRejected(w, CodeValidationFailed, validation.Errors)
+		return
+	}
+	if op := operation(doc, req.OperationName); op != nil {
+		// Reads must not change data, so GET only runs queries
+		if r.Method == http.MethodGet && op.Operation != ast.OperationTypeQuery {
+			w.Header().Set("Allow", "POST")
+			problem.Write(w, r, http.StatusMethodNotAllowed, "Mutations must be sent with POST")
+			return
+		}
+		c := measure(&h.schema, doc, op, req.Variables)
+		if err := checkLimits(c, h.limits.MaxDepth, h.limits.MaxComplexity); err != nil {
+			writeRejected(w, CodeQueryTooComplex, gqlerrors.FormatErrors(err))
+			return
+		}
+	}
+
+	ctx := withLoaders(r.Context(), newLoaders(r.Context(), h.store))
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+	for i, err := range result.Errors {
+		if err.Extensions == nil {
+			result.Errors[i].Extensions = extensions(err)
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// operation returns the operation to run, or nil when there is no single
+// match, which Execute reports
+func operation(doc *ast.Document, name string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			if found != nil {
+				return nil
+			}
+			found = op
+		} else if op.Name != nil && op.Name.Value == name {
+			return op
+		}
+	}
+	return found
+}
+
+// writeRejected answers an operation that did not run. The response has no
+// data entry, and every error gets code.
+func writeRejected(w http.ResponseWriter, code string, errs []gqlerrors.FormattedError) {
+	for i := range errs {
+		errs[i].Extensions = map[string]any{"code": code}
+	}
+	writeJSON(w, http.StatusBadRequest, map[string]any{"errors": errs})
+}
+
+func writeJSON(w http.ResponseWriter, status int, result any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// extensions finds the extensions of an *Error wrapped by the executor.
+// Errors returned from thunks lose them on the way.
+func extensions(err error) map[string]any {
+	for err != nil {
+		switch e := err.(type) {
+		case *Error:
+			return e.Extensions()
+		case gqlerrors.FormattedError:
+			err = e.OriginalError()
+		case *gqlerrors.Error:
+			err = e.OriginalError
+		default:
+			return nil
+		}
+	}
+	return nil
+}
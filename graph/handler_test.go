@@ -0,0 +1,157 @@
+package graph_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-backend/config"
+	"go-backend/graph"
+	"go-backend/models"
+	"go-backend/repository"
+	"go-backend/routes"
+)
+
+// response is the JSON body of a GraphQL response
+type response struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+// execute runs an operation against a handler over store
+func execute(t *testing.T, store *repository.Store, query string, variables map[string]any) response {
+	t.Helper()
+	body, _ := json.Marshal(models.GraphQLRequest{Query: query, Variables: variables})
+	rec := httptest.NewRecorder()
+	h := graph.New(store, routes.Spec(), config.Default().Limits.GraphQL)
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body)))
+
+	var resp response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response %q: %v", rec.Body.String(), err)
+	}
+	return resp
+}
+
+const createProduct = `mutation($input: ProductInput!) { createProduct(input: $input) { id name } }`
+
+func TestCreateProduct(t *testing.T) {
+	store := repository.NewMemoryStore()
+	resp := execute(t, store, createProduct, map[string]any{
+		"input": map[string]any{"name": "Chair", "categoryId": "furniture"},
+	})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("errors: %+v", resp.Errors)
+	}
+
+	var created struct{ ID, Name string }
+	json.Unmarshal(resp.Data["createProduct"], &created)
+	product, err := store.Products.Get(context.Background(), created.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if product.Name != "Chair" {
+		t.Errorf("stored product = %+v, want Chair", product)
+	}
+}
+
+func TestProductInputsAreValidated(t *testing.T) {
+	store := repository.NewMemoryStore()
+	existing := &models.Product{Name: "Chair", CategoryID: "furniture"}
+	if err := store.Products.Create(context.Background(), existing); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		field     string
+	}{
+		{
+			name:      "create without a name",
+			query:     createProduct,
+			variables: map[string]any{"input": map[string]any{"name": "", "categoryId": "furniture"}},
+			field:     "input.name",
+		},
+		{
+			name:  "create with an empty attribute code",
+			query: createProduct,
+			variables: map[string]any{"input": map[string]any{
+				"name": "Chair", "categoryId": "furniture",
+				"attributes": []any{map[string]any{"code": "", "value": 1}},
+			}},
+			field: "input.attributes[0].code",
+		},
+		{
+			name:  "update without a category",
+			query: `mutation($id: ID!, $input: ProductInput!) { updateProduct(id: $id, input: $input) { id } }`,
+			variables: map[string]any{
+				"id":    existing.ID.Hex(),
+				"input": map[string]any{"name": "Armchair", "categoryId": ""},
+			},
+			field: "input.category_id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := execute(t, store, tt.query, tt.variables)
+			if len(resp.Errors) != 1 {
+				t.Fatalf("errors = %+v, want one", resp.Errors)
+			}
+			if code := resp.Errors[0].Extensions["code"]; code != graph.CodeBadUserInput {
+				t.Errorf("code = %v, want %s", code, graph.CodeBadUserInput)
+			}
+			if !strings.Contains(resp.Errors[0].Message, tt.field) {
+				t.Errorf("message = %q, want it to name %s", resp.Errors[0].Message, tt.field)
+			}
+		})
+	}
+
+	product, err := store.Products.Get(context.Background(), existing.ID.Hex())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if product.Name != "Chair" || product.Version != 1 {
+		t.Errorf("product = %+v, want it unchanged", product)
+	}
+}
+
+func TestProductAttributesAreCapped(t *testing.T) {
+	defer func(max int) { models.MaxAttributes = max }(models.MaxAttributes)
+	models.MaxAttributes = 2
+
+	attributes := []any{
+		map[string]any{"code": "a", "value": 1},
+		map[string]any{"code": "b", "value": 2},
+		map[string]any{"code": "c", "value": 3},
+	}
+	resp := execute(t, repository.NewMemoryStore(), createProduct, map[string]any{
+		"input": map[string]any{"name": "Chair", "categoryId": "furniture", "attributes": attributes},
+	})
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != graph.CodeBadUserInput {
+		t.Fatalf("errors = %+v, want one %s", resp.Errors, graph.CodeBadUserInput)
+	}
+	if !strings.Contains(resp.Errors[0].Message, "at most 2 attributes") {
+		t.Errorf("message = %q, want it to name the limit", resp.Errors[0].Message)
+	}
+}
+
+func TestPageSizeIsCapped(t *testing.T) {
+	resp := execute(t, repository.NewMemoryStore(), `{ products(pageSize: 101) { products { id } } }`, nil)
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != graph.CodeBadUserInput {
+		t.Fatalf("errors = %+v, want one %s", resp.Errors, graph.CodeBadUserInput)
+	}
+
+	resp = execute(t, repository.NewMemoryStore(), `{ products(pageSize: 100000) { products { id name } } }`, nil)
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != graph.CodeQueryTooComplex {
+		t.Errorf("errors = %+v, want the query refused as too complex", resp.Errors)
+	}
+}
@@ -0,0 +1,187 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// defaultListSize is the assumed length of lists without a size argument
+const defaultListSize = 10
+
+// sizeArguments bound the length of the lists a field returns
+var sizeArguments = []string{"first", "pageSize"}
+
+// cost is the depth and complexity of a selection
+type cost struct {
+	depth      int
+	complexity int
+}
+
+// measure computes the depth and complexity of an operation. Every field
+// costs 1, and the cost of a list's selection is multiplied by the list's
+// size argument, or defaultListSize. A size argument on a field returning
+// a page, such as products(pageSize:), sizes the lists selected directly
+// inside it. Introspection fields are free.
+func measure(schema *graphql.Schema, doc *ast.Document, op *ast.OperationDefinition, variables map[string]any) cost {
+	m := &measurer{
+		schema:    schema,
+		fragments: make(map[string]*ast.FragmentDefinition),
+		variables: variables,
+		visiting:  make(map[string]bool),
+	}
+	for _, def := range doc.Definitions {
+		if fragment, ok := def.(*ast.FragmentDefinition); ok {
+			m.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	root := schema.QueryType()
+	if op.Operation == ast.OperationTypeMutation {
+		root = schema.MutationType()
+	}
+	return m.selections(root, op.SelectionSet, 0)
+}
+
+type measurer struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+	visiting  map[string]bool // fragments being expanded, against cycles
+}
+
+// selections measures a selection set. size is the parent field's size
+// argument, or 0, and applies to the lists selected in the set.
+func (m *measurer) selections(parent *graphql.Object, set *ast.SelectionSet, size int) cost {
+	var total cost
+	if set == nil || parent == nil {
+		return total
+	}
+	for _, selection := range set.Selections {
+		var c cost
+		switch selection := selection.(type) {
+		case *ast.Field:
+			c = m.field(parent, selection, size)
+		case *ast.InlineFragment:
+			c = m.selections(m.object(parent, selection.TypeCondition), selection.SelectionSet, size)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := m.fragments[name]
+			if !ok || m.visiting[name] {
+				continue
+			}
+			m.visiting[name] = true
+			c = m.selections(m.object(parent, fragment.TypeCondition), fragment.SelectionSet, size)
+			m.visiting[name] = false
+		}
+		total.depth = max(total.depth, c.depth)
+		total.complexity += c.complexity
+	}
+	return total
+}
+
+func (m *measurer) field(parent *graphql.Object, field *ast.Field, parentSize int) cost {
+	name := field.Name.Value
+	if strings.HasPrefix(name, "__") {
+		return cost{}
+	}
+	def, ok := parent.Fields()[name]
+	if !ok {
+		return cost{depth: 1, complexity: 1}
+	}
+
+	// Unwrap non-null and list types down to the object type
+	size, list := 1, false
+	t := def.Type
+	for {
+		switch wrapped := t.(type) {
+		case *graphql.NonNull:
+			t = wrapped.OfType
+			continue
+		case *graphql.List:
+			size *= m.listSize(field, parentSize)
+			list = true
+			t = wrapped.OfType
+			continue
+		}
+		break
+	}
+
+	// A size argument on a field that is not a list sizes the lists inside
+	childSize := 0
+	if !list {
+		childSize, _ = m.sizeArgument(field)
+	}
+	object, _ := t.(*graphql.Object)
+	children := m.selections(object, field.SelectionSet, childSize)
+	return cost{
+		depth:      1 + children.depth,
+		complexity: 1 + size*children.complexity,
+	}
+}
+
+// listSize is the length of a list field: its own size argument, else its
+// parent's, else defaultListSize
+func (m *measurer) listSize(field *ast.Field, parentSize int) int {
+	if n, ok := m.sizeArgument(field); ok {
+		return n
+	}
+	if parentSize > 0 {
+		return parentSize
+	}
+	return defaultListSize
+}
+
+// sizeArgument reads a field's size argument
+func (m *measurer) sizeArgument(field *ast.Field) (int, bool) {
+	for _, arg := range field.Arguments {
+		for _, name := range sizeArguments {
+			if arg.Name.Value == name {
+				if n, ok := m.intValue(arg.Value); ok && n > 0 {
+					return n, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+func (m *measurer) intValue(value ast.Value) (int, bool) {
+	switch value := value.(type) {
+	case *ast.IntValue:
+		n, err := strconv.Atoi(value.Value)
+		return n, err == nil
+	case *ast.Variable:
+		switch n := m.variables[value.Name.Value].(type) {
+		case float64:
+			return int(n), true
+		case int:
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// object returns the object type of a fragment's type condition
+func (m *measurer) object(parent *graphql.Object, condition *ast.Named) *graphql.Object {
+	if condition == nil {
+		return parent
+	}
+	object, _ := m.schema.Type(condition.Name.Value).(*graphql.Object)
+	return object
+}
+
+// checkLimits rejects operations nested deeper than maxDepth or whose
+// complexity exceeds maxComplexity
+func checkLimits(c cost, maxDepth, maxComplexity int) error {
+	if c.depth > maxDepth {
+		return fmt.Errorf("query depth %d exceeds the limit of %d", c.depth, maxDepth)
+	}
+	if c.complexity > maxComplexity {
+		return fmt.Errorf("query complexity %d exceeds the limit of %d", c.complexity, maxComplexity)
+	}
+	return nil
+}
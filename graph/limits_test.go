@@ -0,0 +1,39 @@
+package graph
+
+import (
+	"testing"
+
+	"go-backend/repository"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+func TestPageSizeMultipliesTheProductsSelected(t *testing.T) {
+	schema, err := newSchema(repository.NewMemoryStore(), nil)
+	if err != nil {
+		t.Fatalf("newSchema: %v", err)
+	}
+
+	tests := []struct {
+		query      string
+		variables  map[string]any
+		complexity int
+	}{
+		// products, products, and id and name for each of the 10 default items
+		{`{ products { products { id name } } }`, nil, 2 + 10*2},
+		{`{ products(pageSize: 50) { products { id name } } }`, nil, 2 + 50*2},
+		{`query($n: Int) { products(pageSize: $n) { products { id } } }`, map[string]any{"n": float64(1000)}, 2 + 1000},
+		{`{ products(pageSize: 50) { pagination { total } products { id } } }`, nil, 1 + 2 + 1 + 50},
+	}
+	for _, tt := range tests {
+		doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+		if err != nil {
+			t.Fatalf("parse %q: %v", tt.query, err)
+		}
+		op := doc.Definitions[0].(*ast.OperationDefinition)
+		if c := measure(&schema, doc, op, tt.variables); c.complexity != tt.complexity {
+			t.Errorf("complexity of %s = %d, want %d", tt.query, c.complexity, tt.complexity)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package graph
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"go-backend/models"
+	"go-backend/repository"
+)
+
+// loader batches the keys requested while one level of a query resolves.
+// Load returns a thunk; graphql-go calls the thunks of a level only after
+// all fields of that level have been resolved, so the first thunk called
+// fetches every key loaded so far in one call.
+type loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	results map[K]V
+	errs    map[K]error
+}
+
+func newLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch, results: make(map[K]V), errs: make(map[K]error)}
+}
+
+// Load queues key, unless it is queued or loaded already, and returns a
+// thunk for its value. Missing keys resolve to the zero value.
+func (l *loader[K, V]) Load(ctx context.Context, key K) func() (any, error) {
+	l.mu.Lock()
+	_, done := l.results[key]
+	if !done && l.errs[key] == nil && !slices.Contains(l.pending, key) {
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (any, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if len(l.pending) > 0 {
+			keys := l.pending
+			l.pending = nil
+			values, err := l.fetch(ctx, keys)
+			for _, k := range keys {
+				if err != nil {
+					l.errs[k] = err
+				} else {
+					l.results[k] = values[k]
+				}
+			}
+		}
+		if err := l.errs[key]; err != nil {
+			return nil, err
+		}
+		return l.results[key], nil
+	}
+}
+
+// productsKey selects the first products of a category
+type productsKey struct {
+	categoryID string
+	limit      int
+}
+
+// loaders holds the per-request caches and batches
+type loaders struct {
+	// categories are few, so the first lookup loads all of them; parents,
+	// children and product categories then come from memory
+	categories func() (*categoryIndex, error)
+	products   *loader[productsKey, []models.Product]
+}
+
+// categoryIndex holds every category in store order
+type categoryIndex struct {
+	list     []models.Category
+	byID     map[string]models.Category
+	children map[string][]models.Category
+}
+
+func newLoaders(ctx context.Context, store *repository.Store) *loaders {
+	return &loaders{
+		categories: sync.OnceValues(func() (*categoryIndex, error) {
+			list, err := store.Categories.List(ctx)
+			if err != nil {
+				return nil, err
+			}
+			index := &categoryIndex{
+				list:     list,
+				byID:     make(map[string]models.Category, len(list)),
+				children: make(map[string][]models.Category),
+			}
+			for _, category := range list {
+				index.byID[category.ID] = category
+				if category.ParentID != nil {
+					index.children[*category.ParentID] = append(index.children[*category.ParentID], category)
+				}
+			}
+			return index, nil
+		}),
+		products: newLoader(func(ctx context.Context, keys []productsKey) (map[productsKey][]models.Product, error) {
+			// One query per distinct limit, usually just one
+			byLimit := make(map[int][]string)
+			for _, key := range keys {
+				byLimit[key.limit] = append(byLimit[key.limit], key.categoryID)
+			}
+			results := make(map[productsKey][]models.Product, len(keys))
+			for limit, categoryIDs := range byLimit {
+				products, err := store.Products.ListByCategories(ctx, categoryIDs, limit)
+				if err != nil {
+					return nil, err
+				}
+				for _, id := range categoryIDs {
+					results[productsKey{id, limit}] = products[id]
+				}
+			}
+			return results, nil
+		}),
+	}
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
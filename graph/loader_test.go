@@ -0,0 +1,39 @@
+package graph
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestLoaderBatchesDistinctKeys(t *testing.T) {
+	var batches [][]string
+	l := newLoader(func(ctx context.Context, keys []string) (map[string]int, error) {
+		batches = append(batches, keys)
+		values := make(map[string]int, len(keys))
+		for _, key := range keys {
+			values[key] = len(key)
+		}
+		return values, nil
+	})
+
+	ctx := context.Background()
+	var thunks []func() (any, error)
+	for _, key := range []string{"a", "bb", "a", "ccc", "bb"} {
+		thunks = append(thunks, l.Load(ctx, key))
+	}
+	for i, want := range []int{1, 2, 1, 3, 2} {
+		value, err := thunks[i]()
+		if err != nil || value != want {
+			t.Errorf("thunk %d = %v, %v, want %d", i, value, err, want)
+		}
+	}
+
+	// Loaded keys come from the cache
+	if value, _ := l.Load(ctx, "a")(); value != 1 {
+		t.Errorf("cached value = %v, want 1", value)
+	}
+	if len(batches) != 1 || !slices.Equal(batches[0], []string{"a", "bb", "ccc"}) {
+		t.Errorf("batches = %v, want one batch of the distinct keys", batches)
+	}
+}
@@ -0,0 +1,610 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"go-backend/auth"
+	"go-backend/db"
+	"go-backend/models"
+	"go-backend/openapi"
+	"go-backend/repository"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxFirst caps the products listed per category, and maxPageSize the
+// products listed per page
+const (
+	maxFirst    = 100
+	maxPageSize = 100
+)
+
+// Error codes, returned in the code extension of each error
+const (
+	CodeBadUserInput    = "BAD_USER_INPUT"
+	CodeNotFound        = "NOT_FOUND"
+	CodeConflict        = "CONFLICT"
+	CodeUnauthenticated = "UNAUTHENTICATED"
+	CodeForbidden       = "FORBIDDEN"
+	CodeTimeout         = "TIMEOUT"
+	CodeUnavailable     = "UNAVAILABLE"
+	CodeInternal        = "INTERNAL_SERVER_ERROR"
+
+	// The operation did not run
+	CodeParseFailed      = "GRAPHQL_PARSE_FAILED"
+	CodeValidationFailed = "GRAPHQL_VALIDATION_FAILED"
+	CodeQueryTooComplex  = "QUERY_TOO_COMPLEX"
+)
+
+// Error is an error with a code clients can match on
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Extensions adds the code to the error in the response
+func (e *Error) Extensions() map[string]any {
+	return map[string]any{"code": e.Code}
+}
+
+func newError(code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// storeError maps repository errors to client errors. Unexpected errors are
+// logged and replaced with detail.
+func storeError(err error, detail string) error {
+	switch {
+	case errors.Is(err, repository.ErrInvalidID):
+		return newError(CodeBadUserInput, "Invalid ObjectID format")
+	case errors.Is(err, repository.ErrConflict):
+		return newError(CodeConflict, "%s", err.Error())
+	case db.IsUnavailable(err):
+		return newError(CodeUnavailable, "The database is unavailable, try again later")
+	case db.IsTimeout(err):
+		return newError(CodeTimeout, "The request took too long to complete")
+	}
+	log.Printf("%s: %v", detail, err)
+	return newError(CodeInternal, "%s", detail)
+}
+
+// requireAdmin fails unless the request is authenticated as an admin
+func requireAdmin(p graphql.ResolveParams) error {
+	session := auth.FromContext(p.Context)
+	switch {
+	case session == nil:
+		return newError(CodeUnauthenticated, "Authentication required")
+	case session.Role != auth.RoleAdmin:
+		return newError(CodeForbidden, "This field requires the %s role", auth.RoleAdmin)
+	}
+	return nil
+}
+
+// from resolves a field of a source of type T
+func from[T any](fn func(T) any) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		return fn(p.Source.(T)), nil
+	}
+}
+
+// jsonScalar passes attribute values through as they are
+var jsonScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:         "JSON",
+	Description:  "Any JSON value",
+	Serialize:    func(value any) any { return value },
+	ParseValue:   func(value any) any { return value },
+	ParseLiteral: parseLiteral,
+})
+
+func parseLiteral(value ast.Value) any {
+	switch value := value.(type) {
+	case *ast.StringValue:
+		return value.Value
+	case *ast.BooleanValue:
+		return value.Value
+	case *ast.IntValue:
+		n, _ := strconv.ParseInt(value.Value, 10, 64)
+		return n
+	case *ast.FloatValue:
+		f, _ := strconv.ParseFloat(value.Value, 64)
+		return f
+	case *ast.ListValue:
+		list := make([]any, len(value.Values))
+		for i, v := range value.Values {
+			list[i] = parseLiteral(v)
+		}
+		return list
+	case *ast.ObjectValue:
+		object := make(map[string]any, len(value.Fields))
+		for _, field := range value.Fields {
+			object[field.Name.Value] = parseLiteral(field.Value)
+		}
+		return object
+	}
+	return nil
+}
+
+// newSchema builds the schema over the store's products, categories and
+// users. The writes mirror the REST API: anyone may create and update
+// products, and category and user changes need the admin role.
+func newSchema(store *repository.Store, spec *openapi.Document) (graphql.Schema, error) {
+	attributeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Attribute",
+		Fields: graphql.Fields{
+			"code":  {Type: graphql.NewNonNull(graphql.String)},
+			"value": {Type: jsonScalar},
+			"type":  {Type: graphql.String, Description: "Type of value, e.g. string, number or boolean"},
+			"label": {Type: graphql.String},
+		},
+	})
+
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":    {Type: graphql.NewNonNull(graphql.ID)},
+			"email": {Type: graphql.NewNonNull(graphql.String)},
+			"name":  {Type: graphql.String},
+			"role":  {Type: graphql.String},
+		},
+	})
+
+	// Products and categories refer to each other, so their fields are thunks
+	var productType, categoryType *graphql.Object
+	productType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Product",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":            {Type: graphql.NewNonNull(graphql.ID), Resolve: from(func(p models.Product) any { return p.ID.Hex() })},
+				"name":          {Type: graphql.NewNonNull(graphql.String), Resolve: from(func(p models.Product) any { return p.Name })},
+				"categoryId":    {Type: graphql.NewNonNull(graphql.String), Resolve: from(func(p models.Product) any { return p.CategoryID })},
+				"categoryGroup": {Type: graphql.String, Description: "Top-level category of categoryId", Resolve: from(func(p models.Product) any { return p.CategoryGroup })},
+				"category": {
+					Type: categoryType,
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return category(p, p.Source.(models.Product).CategoryID)
+					},
+				},
+				"attributes": {Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(attributeType))), Resolve: from(func(p models.Product) any { return nonNil(p.Attributes) })},
+				"version":    {Type: graphql.NewNonNull(graphql.Int), Description: "Incremented on every update", Resolve: from(func(p models.Product) any { return p.Version })},
+			}
+		}),
+	})
+
+	categoryType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Category",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":   {Type: graphql.NewNonNull(graphql.ID), Resolve: from(func(c models.Category) any { return c.ID })},
+				"name": {Type: graphql.NewNonNull(graphql.String), Resolve: from(func(c models.Category) any { return c.Name })},
+				"parentId": {
+					Type:        graphql.ID,
+					Description: "Null for top-level categories",
+					Resolve: from(func(c models.Category) any {
+						if c.ParentID == nil {
+							return nil
+						}
+						return *c.ParentID
+					}),
+				},
+				"parent": {
+					Type: categoryType,
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						parentID := p.Source.(models.Category).ParentID
+						if parentID == nil {
+							return nil, nil
+						}
+						return category(p, *parentID)
+					},
+				},
+				"children": {
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(categoryType))),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						index, err := loadersFrom(p.Context).categories()
+						if err != nil {
+							return nil, storeError(err, "Error fetching categories")
+						}
+						return nonNil(index.children[p.Source.(models.Category).ID]), nil
+					},
+				},
+				"products": {
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(productType))),
+					Description: "The first products of the category itself, in ID order",
+					Args: graphql.FieldConfigArgument{
+						"first": {Type: graphql.Int, DefaultValue: 10},
+					},
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						first, _ := p.Args["first"].(int)
+						if first < 1 || first > maxFirst {
+							return nil, newError(CodeBadUserInput, "first must be between 1 and %d", maxFirst)
+						}
+						thunk := loadersFrom(p.Context).products.Load(p.Context, productsKey{p.Source.(models.Category).ID, first})
+						return func() (any, error) {
+							products, err := thunk()
+							if err != nil {
+								return nil, storeError(err, "Error fetching products")
+							}
+							return nonNil(products.([]models.Product)), nil
+						}, nil
+					},
+				},
+			}
+		}),
+	})
+
+	paginationType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Pagination",
+		Fields: graphql.Fields{
+			"page":       {Type: graphql.NewNonNull(graphql.Int), Resolve: from(func(p models.Pagination) any { return p.Page })},
+			"pageSize":   {Type: graphql.NewNonNull(graphql.Int), Resolve: from(func(p models.Pagination) any { return p.PageSize })},
+			"offset":     {Type: graphql.NewNonNull(graphql.Int), Resolve: from(func(p models.Pagination) any { return p.Offset })},
+			"total":      {Type: graphql.NewNonNull(graphql.Int), Resolve: from(func(p models.Pagination) any { return p.Total })},
+			"totalPages": {Type: graphql.NewNonNull(graphql.Int), Resolve: from(func(p models.Pagination) any { return p.TotalPages })},
+			"hasMore":    {Type: graphql.NewNonNull(graphql.Boolean), Resolve: from(func(p models.Pagination) any { return p.HasMore })},
+		},
+	})
+
+	productPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ProductPage",
+		Fields: graphql.Fields{
+			"products":   {Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(productType))), Resolve: from(func(p models.ProductPage) any { return p.Products })},
+			"pagination": {Type: graphql.NewNonNull(paginationType), Resolve: from(func(p models.ProductPage) any { return p.Pagination })},
+		},
+	})
+
+	sortOrderType := graphql.NewEnum(graphql.EnumConfig{
+		Name: "SortOrder",
+		Values: graphql.EnumValueConfigMap{
+			"ASC":  {Value: "asc"},
+			"DESC": {Value: "desc"},
+		},
+	})
+
+	attributeInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "AttributeInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"code":  {Type: graphql.NewNonNull(graphql.String)},
+			"value": {Type: jsonScalar},
+			"type":  {Type: graphql.String},
+			"label": {Type: graphql.String},
+		},
+	})
+	productInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "ProductInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":          {Type: graphql.NewNonNull(graphql.String)},
+			"categoryId":    {Type: graphql.NewNonNull(graphql.String)},
+			"categoryGroup": {Type: graphql.String},
+			"attributes":    {Type: graphql.NewList(graphql.NewNonNull(attributeInput))},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"product": {
+				Type: productType,
+				Args: graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					product, err := store.Products.Get(p.Context, p.Args["id"].(string))
+					if errors.Is(err, repository.ErrNotFound) {
+						return nil, nil
+					}
+					if err != nil {
+						return nil, storeError(err, "Error fetching product")
+					}
+					return *product, nil
+				},
+			},
+			"products": {
+				Type: graphql.NewNonNull(productPageType),
+				Args: graphql.FieldConfigArgument{
+					"categoryId":    {Type: graphql.String},
+					"categoryGroup": {Type: graphql.String},
+					"page":          {Type: graphql.Int, DefaultValue: 1},
+					"pageSize":      {Type: graphql.Int, DefaultValue: 10},
+					"sort":          {Type: graphql.String, Description: "Field to sort by"},
+					"order":         {Type: sortOrderType, DefaultValue: "asc"},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					params := models.PaginationParams{SortOrder: "asc"}
+					params.Page, _ = p.Args["page"].(int)
+					params.PageSize, _ = p.Args["pageSize"].(int)
+					if params.Page < 1 || params.PageSize < 1 {
+						return nil, newError(CodeBadUserInput, "page and pageSize must be positive")
+					}
+					if params.PageSize > maxPageSize {
+						return nil, newError(CodeBadUserInput, "pageSize must be at most %d", maxPageSize)
+					}
+					params.CategoryID, _ = p.Args["categoryId"].(string)
+					params.CategoryGroup, _ = p.Args["categoryGroup"].(string)
+					params.SortField, _ = p.Args["sort"].(string)
+					if order, _ := p.Args["order"].(string); order == "desc" {
+						params.SortOrder = order
+					}
+					params.Start = (params.Page - 1) * params.PageSize
+					params.Limit = params.PageSize
+
+					products, total, err := store.Products.List(p.Context, params)
+					if err != nil {
+						return nil, storeError(err, "Error fetching products")
+					}
+					return models.ProductPage{
+						Products:   nonNil(products),
+						Pagination: models.NewPagination(params, len(products), total),
+					}, nil
+				},
+			},
+			"category": {
+				Type: categoryType,
+				Args: graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return category(p, p.Args["id"].(string))
+				},
+			},
+			"categories": {
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(categoryType))),
+				Description: "All categories; select children to walk the tree from the roots",
+				Args: graphql.FieldConfigArgument{
+					"roots": {Type: graphql.Boolean, DefaultValue: false, Description: "Only top-level categories"},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					index, err := loadersFrom(p.Context).categories()
+					if err != nil {
+						return nil, storeError(err, "Error fetching categories")
+					}
+					if roots, _ := p.Args["roots"].(bool); !roots {
+						return nonNil(index.list), nil
+					}
+					categories := []models.Category{}
+					for _, c := range index.list {
+						if c.ParentID == nil {
+							categories = append(categories, c)
+						}
+					}
+					return categories, nil
+				},
+			},
+			"user": {
+				Type: userType,
+				Args: graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return user(p, store, p.Args["id"].(string))
+				},
+			},
+			"users": {
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userType))),
+				Args: graphql.FieldConfigArgument{
+					"email": {Type: graphql.String, Description: "Only the user with this email"},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					email, _ := p.Args["email"].(string)
+					users, err := store.Users.List(p.Context, email)
+					if err != nil {
+						return nil, storeError(err, "Error fetching users")
+					}
+					responses := make([]models.UserResponse, len(users))
+					for i, u := range users {
+						responses[i] = toUserResponse(u)
+					}
+					return responses, nil
+				},
+			},
+			"me": {
+				Type:        userType,
+				Description: "The authenticated user, null for anonymous requests",
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					userID := auth.UserID(p.Context)
+					if userID == "" {
+						return nil, nil
+					}
+					return user(p, store, userID)
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createProduct": {
+				Type: graphql.NewNonNull(productType),
+				Args: graphql.FieldConfigArgument{"input": {Type: graphql.NewNonNull(productInput)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					product := productFromInput(p.Args["input"])
+					if err := validateProduct(spec, "POST", "/api/v2/products", product); err != nil {
+						return nil, err
+					}
+					if err := store.Products.Create(p.Context, &product); err != nil {
+						return nil, storeError(err, "Error creating product")
+					}
+					return product, nil
+				},
+			},
+			"updateProduct": {
+				Type: graphql.NewNonNull(productType),
+				Args: graphql.FieldConfigArgument{
+					"id":    {Type: graphql.NewNonNull(graphql.ID)},
+					"input": {Type: graphql.NewNonNull(productInput)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					id, err := primitive.ObjectIDFromHex(p.Args["id"].(string))
+					if err != nil {
+						return nil, newError(CodeBadUserInput, "Invalid ObjectID format")
+					}
+					product := productFromInput(p.Args["input"])
+					if err := validateProduct(spec, "PUT", "/api/v2/products/{id}", product); err != nil {
+						return nil, err
+					}
+					product.ID = id
+					if err := store.Products.Update(p.Context, &product); err != nil {
+						if errors.Is(err, repository.ErrNotFound) {
+							return nil, newError(CodeNotFound, "Product not found")
+						}
+						return nil, storeError(err, "Error updating product")
+					}
+					return product, nil
+				},
+			},
+			"moveCategory": {
+				Type:        graphql.NewNonNull(categoryType),
+				Description: "Moves a category under parentId, or to the top level when it is null. Products in the moved subtree get the category group of their new top-level category. Admin only.",
+				Args: graphql.FieldConfigArgument{
+					"id":       {Type: graphql.NewNonNull(graphql.ID)},
+					"parentId": {Type: graphql.ID},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if err := requireAdmin(p); err != nil {
+						return nil, err
+					}
+					id := p.Args["id"].(string)
+					var parentID *string
+					if parent, ok := p.Args["parentId"].(string); ok {
+						parentID = &parent
+					}
+					if err := store.MoveCategory(p.Context, id, parentID); err != nil {
+						return nil, categoryError(err, "Error moving category")
+					}
+					moved, err := store.Categories.Get(p.Context, id)
+					if err != nil {
+						return nil, categoryError(err, "Error fetching category")
+					}
+					return *moved, nil
+				},
+			},
+			"deleteCategory": {
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "Deletes a category. Subcategories and products move to the parent; top-level categories must not hold products. Admin only.",
+				Args:        graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if err := requireAdmin(p); err != nil {
+						return nil, err
+					}
+					if err := store.DeleteCategory(p.Context, p.Args["id"].(string)); err != nil {
+						return nil, categoryError(err, "Error deleting category")
+					}
+					return true, nil
+				},
+			},
+			"deleteUser": {
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "Deletes a user and revokes their tokens. Admin only.",
+				Args:        graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if err := requireAdmin(p); err != nil {
+						return nil, err
+					}
+					if err := store.DeleteUser(p.Context, p.Args["id"].(string)); err != nil {
+						if errors.Is(err, repository.ErrNotFound) {
+							return nil, newError(CodeNotFound, "User not found")
+						}
+						return nil, storeError(err, "Error deleting user")
+					}
+					return true, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+// category resolves a category from the per-request index, or null
+func category(p graphql.ResolveParams, id string) (any, error) {
+	index, err := loadersFrom(p.Context).categories()
+	if err != nil {
+		return nil, storeError(err, "Error fetching categories")
+	}
+	if c, ok := index.byID[id]; ok {
+		return c, nil
+	}
+	return nil, nil
+}
+
+// categoryError maps repository errors of category changes
+func categoryError(err error, detail string) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return newError(CodeNotFound, "Category not found")
+	}
+	return storeError(err, detail)
+}
+
+// user resolves a user without the password, or null
+func user(p graphql.ResolveParams, store *repository.Store, id string) (any, error) {
+	u, err := store.Users.Get(p.Context, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, storeError(err, "Error fetching user")
+	}
+	return toUserResponse(*u), nil
+}
+
+func toUserResponse(u models.User) models.UserResponse {
+	return models.UserResponse{ID: u.ID, Email: u.Email, Name: u.Name, Role: u.Role}
+}
+
+// validateProduct checks a product input against the product limits and
+// the request body schema of the REST operation it corresponds to, like the
+// REST and gRPC APIs do
+func validateProduct(spec *openapi.Document, method, path string, product models.Product) error {
+	if err := product.CheckLimits(); err != nil {
+		return newError(CodeBadUserInput, "%s", err.Error())
+	}
+	op := spec.Operation(method, path)
+	if op == nil {
+		return nil
+	}
+	err := spec.ValidateBody(op, product)
+	var invalid *openapi.ValidationError
+	if !errors.As(err, &invalid) {
+		if err != nil {
+			return storeError(err, "Error validating product")
+		}
+		return nil
+	}
+	reasons := make([]string, len(invalid.Invalid))
+	for n, i := range invalid.Invalid {
+		reasons[n] = "input." + i.Name + ": " + i.Reason
+	}
+	return newError(CodeBadUserInput, "%s", strings.Join(reasons, "; "))
+}
+
+// productFromInput converts a ProductInput argument
+func productFromInput(arg any) models.Product {
+	input := arg.(map[string]any)
+	var product models.Product
+	product.Name, _ = input["name"].(string)
+	product.CategoryID, _ = input["categoryId"].(string)
+	product.CategoryGroup, _ = input["categoryGroup"].(string)
+	attributes, _ := input["attributes"].([]any)
+	for _, a := range attributes {
+		attribute := a.(map[string]any)
+		code, _ := attribute["code"].(string)
+		typ, _ := attribute["type"].(string)
+		label, _ := attribute["label"].(string)
+		product.Attributes = append(product.Attributes, models.Attribute{
+			Code:  code,
+			Value: attribute["value"],
+			Type:  typ,
+			Label: label,
+		})
+	}
+	return product
+}
+
+// nonNil turns a nil slice into an empty one for non-null list fields
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,166 @@
+package grpcapi
+
+import (
+	"time"
+
+	"go-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// msg gives access to the fields of a dynamic message by name
+type msg struct {
+	*dynamicpb.Message
+}
+
+func newMsg(name string) msg {
+	return msg{dynamicpb.NewMessage(messageType(name))}
+}
+
+func (m msg) field(name string) protoreflect.FieldDescriptor {
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+	if fd == nil {
+		panic("grpcapi: unknown field " + string(m.Descriptor().Name()) + "." + name)
+	}
+	return fd
+}
+
+func (m msg) str(name string) string { return m.Get(m.field(name)).String() }
+func (m msg) int(name string) int64  { return m.Get(m.field(name)).Int() }
+func (m msg) has(name string) bool   { return m.Has(m.field(name)) }
+
+func (m msg) enum(name string) protoreflect.EnumNumber { return m.Get(m.field(name)).Enum() }
+
+func (m msg) msg(name string) msg {
+	return msg{m.Get(m.field(name)).Message().Interface().(*dynamicpb.Message)}
+}
+
+// list calls fn for each message of a repeated field
+func (m msg) list(name string, fn func(msg)) {
+	list := m.Get(m.field(name)).List()
+	for i := range list.Len() {
+		fn(msg{list.Get(i).Message().Interface().(*dynamicpb.Message)})
+	}
+}
+
+// set sets a scalar field; v must have the field's Go type, e.g. int32
+func (m msg) set(name string, v any) {
+	m.Set(m.field(name), protoreflect.ValueOf(v))
+}
+
+func (m msg) setMsg(name string, v protoreflect.ProtoMessage) {
+	m.Set(m.field(name), protoreflect.ValueOfMessage(v.ProtoReflect()))
+}
+
+func (m msg) add(name string, v protoreflect.ProtoMessage) {
+	m.Mutable(m.field(name)).List().Append(protoreflect.ValueOfMessage(v.ProtoReflect()))
+}
+
+func productMsg(product models.Product) msg {
+	m := newMsg("Product")
+	m.set("id", product.ID.Hex())
+	m.set("name", product.Name)
+	m.set("category_id", product.CategoryID)
+	m.set("category_group", product.CategoryGroup)
+	for _, attribute := range product.Attributes {
+		a := newMsg("Attribute")
+		a.set("code", attribute.Code)
+		if value, err := structpb.NewValue(normalize(attribute.Value)); err == nil {
+			a.setMsg("value", value)
+		}
+		a.set("type", attribute.Type)
+		a.set("label", attribute.Label)
+		m.add("attributes", a)
+	}
+	m.set("version", product.Version)
+	return m
+}
+
+// productFromMsg converts the editable fields of a Product message
+func productFromMsg(m msg) models.Product {
+	product := models.Product{
+		Name:          m.str("name"),
+		CategoryID:    m.str("category_id"),
+		CategoryGroup: m.str("category_group"),
+	}
+	m.list("attributes", func(a msg) {
+		attribute := models.Attribute{Code: a.str("code"), Type: a.str("type"), Label: a.str("label")}
+		if a.has("value") {
+			attribute.Value = valueFromMsg(a.msg("value"))
+		}
+		product.Attributes = append(product.Attributes, attribute)
+	})
+	return product
+}
+
+// valueFromMsg converts a dynamic google.protobuf.Value to a Go value
+func valueFromMsg(m msg) any {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return nil
+	}
+	var value structpb.Value
+	if err := proto.Unmarshal(b, &value); err != nil {
+		return nil
+	}
+	return value.AsInterface()
+}
+
+// normalize converts the BSON types the driver decodes attribute values
+// into to types structpb accepts
+func normalize(v any) any {
+	switch v := v.(type) {
+	case primitive.A:
+		return normalize([]any(v))
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = normalize(item)
+		}
+		return out
+	case primitive.D:
+		out := make(map[string]any, len(v))
+		for _, e := range v {
+			out[e.Key] = normalize(e.Value)
+		}
+		return out
+	case primitive.M:
+		return normalize(map[string]any(v))
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = normalize(item)
+		}
+		return out
+	case primitive.DateTime:
+		return v.Time().UTC().Format(time.RFC3339Nano)
+	case primitive.ObjectID:
+		return v.Hex()
+	}
+	return v
+}
+
+func categoryMsg(category models.Category) msg {
+	m := newMsg("Category")
+	m.set("id", category.ID)
+	m.set("name", category.Name)
+	if category.ParentID != nil {
+		m.set("parent_id", *category.ParentID)
+	}
+	return m
+}
+
+func paginationMsg(p models.Pagination) msg {
+	m := newMsg("Pagination")
+	m.set("page", int32(p.Page))
+	m.set("page_size", int32(p.PageSize))
+	m.set("offset", int32(p.Offset))
+	m.set("total", p.Total)
+	m.set("total_pages", int32(p.TotalPages))
+	m.set("has_more", p.HasMore)
+	return m
+}
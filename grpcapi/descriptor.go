@@ -0,0 +1,216 @@
+package grpcapi
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/structpb" // registers google/protobuf/struct.proto
+)
+
+// ServiceName is the full name of the catalog service
+const ServiceName = "catalog.v1.CatalogService"
+
+// descriptor describes the catalog API. There is no protoc in the build, so
+// the file is built here and messages are dynamic; the proto command prints
+// it as a .proto file for clients to generate code from.
+var descriptor = &descriptorpb.FileDescriptorProto{
+	Name:       proto.String("catalog/v1/catalog.proto"),
+	Package:    proto.String("catalog.v1"),
+	Syntax:     proto.String("proto3"),
+	Dependency: []string{"google/protobuf/struct.proto"},
+	Options:    &descriptorpb.FileOptions{GoPackage: proto.String("go-backend/proto/catalog/v1;catalogv1")},
+	EnumType: []*descriptorpb.EnumDescriptorProto{
+		enum("SortOrder", "SORT_ORDER_UNSPECIFIED", "SORT_ORDER_ASC", "SORT_ORDER_DESC"),
+	},
+	MessageType: []*descriptorpb.DescriptorProto{
+		message("Attribute",
+			scalar(1, "code", descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			object(2, "value", ".google.protobuf.Value"),
+			scalar(3, "type", descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			scalar(4, "label", descriptorpb.FieldDescriptorProto_TYPE_STRING),
+		),
+		message("Product",
+			scalar(1, "id", descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			scalar(2, "name", descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			scalar(3, "category_id", descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			scalar(4, "category_group", descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			repeated(object(5, "attributes", ".catalog.v1.Attribute")),
+			scalar(6, "version", descriptorpb.FieldDescriptorProto_TYPE_INT64),
+		),
+		message("Category",
+			scalar(1, "id", descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			scalar(2, "name", descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			scalar(3, "parent_id", descriptorpb.FieldDescriptorProto_TYPE_STRING),
+		),
+		message("CategoryNode",
+			object(1, "category", ".catalog.v1.Category"),
+			repeated(object(2, "children", ".catalog.v1.CategoryNode")),
+		),
+		message("Pagination",
+			scalar(1, "page", descriptorpb.FieldDescriptorProto_TYPE_INT32),
+			scalar(2, "page_size", descriptorpb.FieldDescriptorProto_TYPE_INT32),
+			scalar(3, "offset", descriptorpb.FieldDescriptorProto_TYPE_INT32),
+			scalar(4, "total", descriptorpb.FieldDescriptorProto_TYPE_INT64),
+			scalar(5, "total_pages", descriptorpb.FieldDescriptorProto_TYPE_INT32),
+			scalar(6, "has_more", descriptorpb.FieldDescriptorProto_TYPE_BOOL),
+		),
+		message("ListProductsRequest",
+			scalar(1, "page", descriptorpb.FieldDescriptorProto_TYPE_INT32),
+			scalar(2, "page_size", descriptorpb.FieldDescriptorProto_TYPE_INT32),
+			scalar(3, "category_id", descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			scalar(4, "category_group", descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			scalar(5, "sort", descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			enumField(6, "order", ".catalog.v1.SortOrder"),
+		),
+		message("ListProductsResponse",
+			repeated(object(1, "products", ".catalog.v1.Product")),
+			object(2, "pagination", ".catalog.v1.Pagination"),
+		),
+		message("GetProductRequest",
+			scalar(1, "id", descriptorpb.FieldDescriptorProto_TYPE_STRING),
+		),
+		message("CreateProductRequest",
+			object(1, "product", ".catalog.v1.Product"),
+		),
+		message("UpdateProductRequest",
+			scalar(1, "id", descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			object(2, "product", ".catalog.v1.Product"),
+		),
+		message("ListCategoriesRequest"),
+		message("ListCategoriesResponse",
+			repeated(object(1, "categories", ".catalog.v1.Category")),
+		),
+		message("GetCategoryTreeRequest",
+			scalar(1, "root_id", descriptorpb.FieldDescriptorProto_TYPE_STRING),
+		),
+		message("GetCategoryTreeResponse",
+			repeated(object(1, "roots", ".catalog.v1.CategoryNode")),
+		),
+	},
+	Service: []*descriptorpb.ServiceDescriptorProto{{
+		Name: proto.String("CatalogService"),
+		Method: []*descriptorpb.MethodDescriptorProto{
+			method("ListProducts", "ListProductsRequest", "ListProductsResponse"),
+			method("GetProduct", "GetProductRequest", "Product"),
+			method("CreateProduct", "CreateProductRequest", "Product"),
+			method("UpdateProduct", "UpdateProductRequest", "Product"),
+			method("ListCategories", "ListCategoriesRequest", "ListCategoriesResponse"),
+			method("GetCategoryTree", "GetCategoryTreeRequest", "GetCategoryTreeResponse"),
+		},
+	}},
+}
+
+// comments document the elements of descriptor in the printed .proto file,
+// keyed by name relative to the package
+var comments = map[string]string{
+	"SortOrder":                      "Order of ListProducts results; unspecified sorts ascending",
+	"Attribute.value":                "Any JSON value",
+	"Attribute.type":                 "Type of value, e.g. string, number or boolean",
+	"Product.id":                     "Hex ObjectID, assigned on create",
+	"Product.category_group":         "Top-level category of category_id",
+	"Product.version":                "Incremented on every update",
+	"Category.parent_id":             "Empty for top-level categories",
+	"ListProductsRequest.page":       "Defaults to 1",
+	"ListProductsRequest.page_size":  "Defaults to 10",
+	"ListProductsRequest.sort":       "Field to sort by",
+	"GetCategoryTreeRequest.root_id": "Only the subtree of this category; empty for every top-level category",
+	"CatalogService":                 "Products and categories for internal services. Every call needs a bearer token in the authorization metadata.",
+	"CatalogService.GetProduct":      "Fails with NOT_FOUND for unknown and INVALID_ARGUMENT for malformed IDs",
+	"CatalogService.CreateProduct":   "Validated like POST /api/v2/products; the ID is generated",
+	"CatalogService.UpdateProduct":   "Replaces the editable fields, validated like PUT /api/v2/products/{id}",
+}
+
+// file is descriptor, checked and registered for the reflection service
+var file = func() protoreflect.FileDescriptor {
+	fd, err := protodesc.NewFile(descriptor, protoregistry.GlobalFiles)
+	if err != nil {
+		panic(fmt.Sprintf("grpcapi: invalid descriptor: %v", err))
+	}
+	if err := protoregistry.GlobalFiles.RegisterFile(fd); err != nil {
+		panic(fmt.Sprintf("grpcapi: registering descriptor: %v", err))
+	}
+	return fd
+}()
+
+// messageType returns the descriptor of a message of the catalog package
+func messageType(name string) protoreflect.MessageDescriptor {
+	md := file.Messages().ByName(protoreflect.Name(name))
+	if md == nil {
+		panic("grpcapi: unknown message " + name)
+	}
+	return md
+}
+
+func message(name string, fields ...*descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+	return &descriptorpb.DescriptorProto{Name: proto.String(name), Field: fields}
+}
+
+func scalar(number int32, name string, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+	return &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(jsonName(name)),
+		Number:   proto.Int32(number),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:     typ.Enum(),
+	}
+}
+
+func object(number int32, name, typeName string) *descriptorpb.FieldDescriptorProto {
+	f := scalar(number, name, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE)
+	f.TypeName = proto.String(typeName)
+	return f
+}
+
+func enumField(number int32, name, typeName string) *descriptorpb.FieldDescriptorProto {
+	f := scalar(number, name, descriptorpb.FieldDescriptorProto_TYPE_ENUM)
+	f.TypeName = proto.String(typeName)
+	return f
+}
+
+func repeated(f *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+	f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	return f
+}
+
+func enum(name string, values ...string) *descriptorpb.EnumDescriptorProto {
+	e := &descriptorpb.EnumDescriptorProto{Name: proto.String(name)}
+	for i, value := range values {
+		e.Value = append(e.Value, &descriptorpb.EnumValueDescriptorProto{
+			Name:   proto.String(value),
+			Number: proto.Int32(int32(i)),
+		})
+	}
+	return e
+}
+
+func method(name, input, output string) *descriptorpb.MethodDescriptorProto {
+	return &descriptorpb.MethodDescriptorProto{
+		Name:       proto.String(name),
+		InputType:  proto.String(".catalog.v1." + input),
+		OutputType: proto.String(".catalog.v1." + output),
+	}
+}
+
+// jsonName is the lowerCamelCase JSON name protoc derives from a field name
+func jsonName(name string) string {
+	out := make([]byte, 0, len(name))
+	upper := false
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '_':
+			upper = true
+		case upper && 'a' <= c && c <= 'z':
+			out = append(out, c-'a'+'A')
+			upper = false
+		default:
+			out = append(out, c)
+			upper = false
+		}
+	}
+	return string(out)
+}
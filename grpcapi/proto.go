@@ -0,0 +1,84 @@
+package grpcapi
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Proto returns the catalog API as a .proto file
+func Proto() string {
+	var b strings.Builder
+	b.WriteString("// Code generated by `go run . proto`. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "syntax = %q;\n\npackage %s;\n\n", descriptor.GetSyntax(), descriptor.GetPackage())
+	for _, dep := range descriptor.Dependency {
+		fmt.Fprintf(&b, "import %q;\n", dep)
+	}
+	if goPackage := descriptor.GetOptions().GetGoPackage(); goPackage != "" {
+		fmt.Fprintf(&b, "\noption go_package = %q;\n", goPackage)
+	}
+
+	for _, service := range descriptor.Service {
+		b.WriteString("\n")
+		writeComment(&b, "", service.GetName())
+		fmt.Fprintf(&b, "service %s {\n", service.GetName())
+		for _, m := range service.Method {
+			writeComment(&b, "  ", service.GetName()+"."+m.GetName())
+			fmt.Fprintf(&b, "  rpc %s(%s) returns (%s);\n", m.GetName(), localName(m.GetInputType()), localName(m.GetOutputType()))
+		}
+		b.WriteString("}\n")
+	}
+
+	for _, e := range descriptor.EnumType {
+		b.WriteString("\n")
+		writeComment(&b, "", e.GetName())
+		fmt.Fprintf(&b, "enum %s {\n", e.GetName())
+		for _, v := range e.Value {
+			fmt.Fprintf(&b, "  %s = %d;\n", v.GetName(), v.GetNumber())
+		}
+		b.WriteString("}\n")
+	}
+
+	for _, m := range descriptor.MessageType {
+		b.WriteString("\n")
+		writeComment(&b, "", m.GetName())
+		if len(m.Field) == 0 {
+			fmt.Fprintf(&b, "message %s {}\n", m.GetName())
+			continue
+		}
+		fmt.Fprintf(&b, "message %s {\n", m.GetName())
+		for _, f := range m.Field {
+			writeComment(&b, "  ", m.GetName()+"."+f.GetName())
+			label := ""
+			if f.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED {
+				label = "repeated "
+			}
+			fmt.Fprintf(&b, "  %s%s %s = %d;\n", label, fieldType(f), f.GetName(), f.GetNumber())
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+func writeComment(b *strings.Builder, indent, name string) {
+	if comment, ok := comments[name]; ok {
+		fmt.Fprintf(b, "%s// %s\n", indent, comment)
+	}
+}
+
+// fieldType is the type of f as written in a .proto file
+func fieldType(f *descriptorpb.FieldDescriptorProto) string {
+	if f.TypeName != nil {
+		return localName(f.GetTypeName())
+	}
+	return strings.ToLower(strings.TrimPrefix(f.GetType().String(), "TYPE_"))
+}
+
+// localName drops the package from type names of the catalog package
+func localName(typeName string) string {
+	if name, ok := strings.CutPrefix(typeName, "."+descriptor.GetPackage()+"."); ok {
+		return name
+	}
+	return strings.TrimPrefix(typeName, ".")
+}
@@ -0,0 +1,206 @@
+// Package grpcapi serves the catalog as a gRPC service for internal
+// consumers, next to the HTTP API and over the same store. The standard
+// health and reflection services are served alongside.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"go-backend/auth"
+	"go-backend/config"
+	"go-backend/health"
+	"go-backend/metrics"
+	"go-backend/openapi"
+	"go-backend/repository"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// healthInterval is how often the health service follows readiness
+const healthInterval = 5 * time.Second
+
+// Options configures a Server
+type Options struct {
+	Store *repository.Store
+	// Spec is the OpenAPI document whose request schemas products are
+	// validated against
+	Spec *openapi.Document
+	// Reflection lets tools like grpcurl list the services and messages
+	Reflection bool
+	// Timeout is the budget of calls that arrive without a deadline
+	Timeout time.Duration
+}
+
+// Server is the gRPC server
+type Server struct {
+	grpc     *grpc.Server
+	health   *grpchealth.Server
+	listener net.Listener
+}
+
+// New creates the server with the catalog, health and, optionally,
+// reflection services
+func New(opts Options) *Server {
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		logCalls,
+		recoverPanics,
+		withTimeout(opts.Timeout),
+		authenticate,
+	))
+
+	c := &catalog{store: opts.Store, spec: opts.Spec}
+	srv.RegisterService(c.serviceDesc(), c)
+
+	h := grpchealth.NewServer()
+	h.SetServingStatus(ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, h)
+
+	if opts.Reflection {
+		reflection.Register(srv)
+	}
+	return &Server{grpc: srv, health: h}
+}
+
+// Listen opens the port, so a port in use fails startup before serving
+func (s *Server) Listen(port string) error {
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return fmt.Errorf("gRPC server: %w", err)
+	}
+	s.listener = listener
+	return nil
+}
+
+// Serve serves until ctx is cancelled. Like server.Run, it then reports
+// NOT_SERVING for the shutdown delay and drains in-flight calls within the
+// shutdown timeout.
+func (s *Server) Serve(ctx context.Context, cfg config.Server) error {
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("gRPC server starting on %s", s.listener.Addr())
+		errCh <- s.grpc.Serve(s.listener)
+	}()
+	go s.followReadiness(ctx)
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.health.Shutdown()
+	time.Sleep(cfg.ShutdownDelay)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(cfg.ShutdownTimeout):
+		s.grpc.Stop()
+		return errors.New("gRPC graceful shutdown incomplete")
+	}
+	log.Println("gRPC server stopped")
+	return nil
+}
+
+// followReadiness reports the readiness checks through the health service
+func (s *Server) followReadiness(ctx context.Context) {
+	ticker := time.NewTicker(healthInterval)
+	defer ticker.Stop()
+	for {
+		serving := healthpb.HealthCheckResponse_NOT_SERVING
+		if health.Default.Run(ctx).Ready() {
+			serving = healthpb.HealthCheckResponse_SERVING
+		}
+		// Ignored once Shutdown has been called
+		s.health.SetServingStatus("", serving)
+		s.health.SetServingStatus(ServiceName, serving)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// logCalls logs and counts every call with its status code
+func logCalls(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	code := status.Code(err)
+	addr := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		addr = p.Addr.String()
+	}
+	log.Printf("gRPC %s %s %s %s", info.FullMethod, addr, code, time.Since(start))
+	metrics.GRPCRequests.Add(info.FullMethod+" "+code.String(), 1)
+	return resp, err
+}
+
+// recoverPanics turns a panic into an INTERNAL status, like the HTTP
+// recovery middleware
+func recoverPanics(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			metrics.Panics.Add(info.FullMethod, 1)
+			log.Printf("panic in %s: %v\n%s", info.FullMethod, p, debug.Stack())
+			err = status.Error(codes.Internal, "Internal server error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// withTimeout gives calls without a deadline the default request budget
+func withTimeout(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+			return handler(ctx, req)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// authenticate requires a bearer token in the authorization metadata for
+// catalog calls. Health and reflection calls need none.
+func authenticate(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !strings.HasPrefix(info.FullMethod, "/"+ServiceName+"/") {
+		return handler(ctx, req)
+	}
+
+	values := metadata.ValueFromIncomingContext(ctx, "authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "Authentication required")
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok || token == "" {
+		return nil, status.Error(codes.Unauthenticated, "authorization metadata must use the Bearer scheme")
+	}
+	session, err := auth.LookupSession(ctx, token)
+	if errors.Is(err, auth.ErrInvalidToken) {
+		return nil, status.Error(codes.Unauthenticated, "Invalid or expired token")
+	}
+	if err != nil {
+		return nil, storeError(err, "Error checking credentials")
+	}
+	return handler(auth.NewContext(ctx, session), req)
+}
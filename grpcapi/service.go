@@ -0,0 +1,285 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+
+	"go-backend/db"
+	"go-backend/models"
+	"go-backend/openapi"
+	"go-backend/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Sort orders of ListProductsRequest.order
+const (
+	sortOrderAsc  = 1
+	sortOrderDesc = 2
+)
+
+// catalog implements CatalogService over the same store and request
+// validation as the REST API
+type catalog struct {
+	store *repository.Store
+	spec  *openapi.Document
+}
+
+// serviceDesc registers the methods of catalog; the messages are dynamic,
+// so this takes the place of generated code
+func (c *catalog) serviceDesc() *grpc.ServiceDesc {
+	return &grpc.ServiceDesc{
+		ServiceName: ServiceName,
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{
+			unary("ListProducts", "ListProductsRequest", c.listProducts),
+			unary("GetProduct", "GetProductRequest", c.getProduct),
+			unary("CreateProduct", "CreateProductRequest", c.createProduct),
+			unary("UpdateProduct", "UpdateProductRequest", c.updateProduct),
+			unary("ListCategories", "ListCategoriesRequest", c.listCategories),
+			unary("GetCategoryTree", "GetCategoryTreeRequest", c.getCategoryTree),
+		},
+		Metadata: descriptor.GetName(),
+	}
+}
+
+// unary adapts fn to a method taking a message of type input
+func unary(name, input string, fn func(ctx context.Context, req msg) (msg, error)) grpc.MethodDesc {
+	inputType := messageType(input)
+	call := func(ctx context.Context, req any) (any, error) {
+		resp, err := fn(ctx, msg{req.(*dynamicpb.Message)})
+		if err != nil {
+			return nil, err
+		}
+		return resp.Message, nil
+	}
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := dynamicpb.NewMessage(inputType)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return call(ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/" + name}
+			return interceptor(ctx, req, info, call)
+		},
+	}
+}
+
+func (c *catalog) listProducts(ctx context.Context, req msg) (msg, error) {
+	params := models.PaginationParams{
+		Page:          int(req.int("page")),
+		PageSize:      int(req.int("page_size")),
+		CategoryID:    req.str("category_id"),
+		CategoryGroup: req.str("category_group"),
+		SortField:     req.str("sort"),
+		SortOrder:     "asc",
+	}
+	if params.Page == 0 {
+		params.Page = 1
+	}
+	if params.PageSize == 0 {
+		params.PageSize = 10
+	}
+	if params.Page < 0 || params.PageSize < 0 {
+		return msg{}, status.Error(codes.InvalidArgument, "page and page_size must not be negative")
+	}
+	switch req.enum("order") {
+	case 0, sortOrderAsc:
+	case sortOrderDesc:
+		params.SortOrder = "desc"
+	default:
+		return msg{}, status.Error(codes.InvalidArgument, "unknown sort order")
+	}
+	params.Start = (params.Page - 1) * params.PageSize
+	params.Limit = params.PageSize
+
+	products, total, err := c.store.Products.List(ctx, params)
+	if err != nil {
+		return msg{}, storeError(err, "Error fetching products")
+	}
+	resp := newMsg("ListProductsResponse")
+	for _, product := range products {
+		resp.add("products", productMsg(product))
+	}
+	resp.setMsg("pagination", paginationMsg(models.NewPagination(params, len(products), total)))
+	return resp, nil
+}
+
+func (c *catalog) getProduct(ctx context.Context, req msg) (msg, error) {
+	product, err := c.store.Products.Get(ctx, req.str("id"))
+	if err != nil {
+		return msg{}, productError(err, "Error fetching product")
+	}
+	return productMsg(*product), nil
+}
+
+func (c *catalog) createProduct(ctx context.Context, req msg) (msg, error) {
+	if !req.has("product") {
+		return msg{}, status.Error(codes.InvalidArgument, "product is required")
+	}
+	product := productFromMsg(req.msg("product"))
+	if err := c.validate("POST", "/api/v2/products", product); err != nil {
+		return msg{}, err
+	}
+	if err := c.store.Products.Create(ctx, &product); err != nil {
+		return msg{}, storeError(err, "Error creating product")
+	}
+	return productMsg(product), nil
+}
+
+func (c *catalog) updateProduct(ctx context.Context, req msg) (msg, error) {
+	id, err := primitive.ObjectIDFromHex(req.str("id"))
+	if err != nil {
+		return msg{}, badRequest("id", "must be a hex ObjectID")
+	}
+	if !req.has("product") {
+		return msg{}, status.Error(codes.InvalidArgument, "product is required")
+	}
+	product := productFromMsg(req.msg("product"))
+	if err := c.validate("PUT", "/api/v2/products/{id}", product); err != nil {
+		return msg{}, err
+	}
+	product.ID = id
+	if err := c.store.Products.Update(ctx, &product); err != nil {
+		return msg{}, productError(err, "Error updating product")
+	}
+	return productMsg(product), nil
+}
+
+func (c *catalog) listCategories(ctx context.Context, _ msg) (msg, error) {
+	categories, err := c.store.Categories.List(ctx)
+	if err != nil {
+		return msg{}, storeError(err, "Error fetching categories")
+	}
+	resp := newMsg("ListCategoriesResponse")
+	for _, category := range categories {
+		resp.add("categories", categoryMsg(category))
+	}
+	return resp, nil
+}
+
+func (c *catalog) getCategoryTree(ctx context.Context, req msg) (msg, error) {
+	categories, err := c.store.Categories.List(ctx)
+	if err != nil {
+		return msg{}, storeError(err, "Error fetching categories")
+	}
+
+	// Siblings are sorted by name; categories whose parent is missing are
+	// treated as top-level
+	slices.SortFunc(categories, func(a, b models.Category) int { return strings.Compare(a.Name, b.Name) })
+	byID := make(map[string]models.Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+	children := make(map[string][]models.Category)
+	var roots []models.Category
+	for _, category := range categories {
+		if category.ParentID != nil {
+			if _, ok := byID[*category.ParentID]; ok {
+				children[*category.ParentID] = append(children[*category.ParentID], category)
+				continue
+			}
+		}
+		roots = append(roots, category)
+	}
+	if rootID := req.str("root_id"); rootID != "" {
+		root, ok := byID[rootID]
+		if !ok {
+			return msg{}, status.Error(codes.NotFound, "Category not found")
+		}
+		roots = []models.Category{root}
+	}
+
+	var node func(models.Category) msg
+	node = func(category models.Category) msg {
+		n := newMsg("CategoryNode")
+		n.setMsg("category", categoryMsg(category))
+		for _, child := range children[category.ID] {
+			n.add("children", node(child))
+		}
+		return n
+	}
+	resp := newMsg("GetCategoryTreeResponse")
+	for _, root := range roots {
+		resp.add("roots", node(root))
+	}
+	return resp, nil
+}
+
+// validate checks a product against the product limits and the request
+// body schema of the REST operation it corresponds to
+func (c *catalog) validate(method, path string, product models.Product) error {
+	if err := product.CheckLimits(); err != nil {
+		return badRequest("product.attributes", err.Error())
+	}
+	op := c.spec.Operation(method, path)
+	if op == nil {
+		return nil
+	}
+	err := c.spec.ValidateBody(op, product)
+	var invalid *openapi.ValidationError
+	if !errors.As(err, &invalid) {
+		return err
+	}
+	details := &errdetails.BadRequest{}
+	reasons := make([]string, len(invalid.Invalid))
+	for n, i := range invalid.Invalid {
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       "product." + i.Name,
+			Description: i.Reason,
+		})
+		reasons[n] = "product." + i.Name + ": " + i.Reason
+	}
+	st, _ := status.New(codes.InvalidArgument, strings.Join(reasons, "; ")).WithDetails(details)
+	return st.Err()
+}
+
+func badRequest(field, reason string) error {
+	st, _ := status.New(codes.InvalidArgument, field+": "+reason).WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: reason}},
+	})
+	return st.Err()
+}
+
+// productError maps the repository errors of single product calls
+func productError(err error, detail string) error {
+	switch {
+	case errors.Is(err, repository.ErrInvalidID):
+		return badRequest("id", "must be a hex ObjectID")
+	case errors.Is(err, repository.ErrNotFound):
+		return status.Error(codes.NotFound, "Product not found")
+	}
+	return storeError(err, detail)
+}
+
+// storeError maps repository and database errors to status codes, like
+// middleware.WriteError does for HTTP. Unexpected errors are logged and
+// replaced with detail.
+func storeError(err error, detail string) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "The request was canceled")
+	case errors.Is(err, repository.ErrDuplicate):
+		return status.Error(codes.AlreadyExists, "The product already exists")
+	case errors.Is(err, repository.ErrConflict):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case db.IsUnavailable(err):
+		return status.Error(codes.Unavailable, "The database is unavailable, try again later")
+	case db.IsTimeout(err):
+		return status.Error(codes.DeadlineExceeded, "The request took too long to complete")
+	}
+	log.Printf("%s: %v", detail, err)
+	return status.Error(codes.Internal, detail)
+}
@@ -0,0 +1,68 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go-backend/models"
+	"go-backend/repository"
+	"go-backend/routes"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestProductAttributesAreCapped(t *testing.T) {
+	defer func(max int) { models.MaxAttributes = max }(models.MaxAttributes)
+	models.MaxAttributes = 2
+
+	ctx := context.Background()
+	c := &catalog{store: repository.NewMemoryStore(), spec: routes.Spec()}
+	existing := &models.Product{Name: "Chair", CategoryID: "furniture"}
+	if err := c.store.Products.Create(ctx, existing); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	product := models.Product{Name: "Chair", CategoryID: "furniture"}
+	for i := range 3 {
+		product.Attributes = append(product.Attributes, models.Attribute{Code: fmt.Sprint("code", i), Value: i})
+	}
+
+	create := request(t, "CreateProductRequest", func(m msg) { m.setMsg("product", productMsg(product)) })
+	if _, err := c.createProduct(ctx, create); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CreateProduct with 3 attributes = %v, want InvalidArgument", err)
+	}
+
+	update := request(t, "UpdateProductRequest", func(m msg) {
+		m.set("id", existing.ID.Hex())
+		m.setMsg("product", productMsg(product))
+	})
+	if _, err := c.updateProduct(ctx, update); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("UpdateProduct with 3 attributes = %v, want InvalidArgument", err)
+	}
+
+	product.Attributes = product.Attributes[:2]
+	create = request(t, "CreateProductRequest", func(m msg) { m.setMsg("product", productMsg(product)) })
+	if _, err := c.createProduct(ctx, create); err != nil {
+		t.Errorf("CreateProduct with 2 attributes: %v", err)
+	}
+}
+
+// request builds a message of type name the way the server receives it,
+// through the wire format
+func request(t *testing.T, name string, fill func(msg)) msg {
+	t.Helper()
+	m := newMsg(name)
+	fill(m)
+	b, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatalf("encoding %s: %v", name, err)
+	}
+	received := newMsg(name)
+	if err := proto.Unmarshal(b, received); err != nil {
+		t.Fatalf("decoding %s: %v", name, err)
+	}
+	return received
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-backend/auth"
+	"go-backend/codec"
+	"go-backend/middleware"
+	"go-backend/models"
+	"go-backend/problem"
+	"go-backend/repository"
+)
+
+// POST /auth/login endpoint
+func Login(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Parse request body
+	var req models.LoginRequest
+	if err := codec.Decode(r, &req); err != nil {
+		status, detail := bodyError(err)
+		problem.Write(w, r, status, detail)
+		return
+	}
+
+	if req.Email == "" || req.Password == "" {
+		problem.Write(w, r, http.StatusBadRequest, "Email and password are required")
+		return
+	}
+
+	user, ok := authenticate(ctx, w, r, req.Email, req.Password)
+	if !ok {
+		return
+	}
+
+	token, session, err := auth.CreateSession(ctx, *user)
+	if err != nil {
+		middleware.WriteError(w, r, err, "Error creating session")
+		return
+	}
+
+	codec.Write(w, r, http.StatusOK, models.LoginResponse{
+		Token:     token,
+		ExpiresAt: session.ExpiresAt,
+		User:      toUserResponse(*user),
+	})
+}
+
+// POST /auth/logout endpoint
+func Logout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	session := auth.FromContext(r.Context())
+	if err := auth.RevokeSession(ctx, session); err != nil {
+		middleware.WriteError(w, r, err, "Error ending session")
+		return
+	}
+
+	event := newAuthEvent(r, auth.EventLogout)
+	event.UserID = session.UserID
+	event.Email = session.Email
+	auth.RecordEvent(ctx, event)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /auth/unlock endpoint (admin only)
+func UnlockAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req models.UnlockRequest
+	if err := codec.Decode(r, &req); err != nil {
+		status, detail := bodyError(err)
+		problem.Write(w, r, status, detail)
+		return
+	}
+
+	if req.Email == "" && req.IP == "" {
+		problem.Write(w, r, http.StatusBadRequest, "Either email or ip is required")
+		return
+	}
+
+	unlocked, err := auth.Unlock(ctx, req.Email, req.IP)
+	if err != nil {
+		middleware.WriteError(w, r, err, "Error unlocking account")
+		return
+	}
+	if !unlocked {
+		problem.Write(w, r, http.StatusNotFound, "No failed attempts recorded for this account or address")
+		return
+	}
+
+	event := newAuthEvent(r, auth.EventUnlock)
+	event.Email = req.Email
+	event.UserID = auth.UserID(r.Context())
+	event.Reason = "unlocked by admin"
+	if req.IP != "" {
+		event.Reason += " for " + req.IP
+	}
+	auth.RecordEvent(ctx, event)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /auth/events endpoint (admin only)
+func GetAuthEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := r.URL.Query()
+	filter := auth.EventFilter{
+		UserID: query.Get("user_id"),
+		Email:  query.Get("email"),
+		Type:   query.Get("type"),
+		Limit:  100,
+	}
+
+	// Parse time range
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{
+		{"from", &filter.From},
+		{"to", &filter.To},
+	} {
+		if s := query.Get(p.name); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				problem.Write(w, r, http.StatusBadRequest, p.name+" must be an RFC 3339 timestamp")
+				return
+			}
+			*p.t = t
+		}
+	}
+
+	// Parse limit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.ParseInt(limitStr, 10, 64)
+		if err != nil || limit <= 0 || limit > 1000 {
+			problem.Write(w, r, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	events, err := auth.QueryEvents(ctx, filter)
+	if err != nil {
+		middleware.WriteError(w, r, err, "Error fetching auth events")
+		return
+	}
+
+	serializerFor(r).AuthEvents(w, r, events)
+}
+
+// authenticate checks credentials with brute-force protection and audits the
+// outcome. On failure it writes the error response and returns false.
+func authenticate(ctx context.Context, w http.ResponseWriter, r *http.Request, email, password string) (*models.User, bool) {
+	ip := middleware.ClientIP(r)
+
+	// Without an email every account would be a candidate, and the
+	// per-account lockout would not apply
+	if email == "" {
+		problem.Write(w, r, http.StatusBadRequest, "An email is required")
+		return nil, false
+	}
+
+	// Refuse attempts while the account or address is locked or delayed,
+	// and count this one before checking the password so parallel guesses
+	// cannot all get through
+	if err := auth.ReserveLogin(ctx, email, ip); err != nil {
+		var lockoutErr *auth.LockoutError
+		if errors.As(err, &lockoutErr) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(lockoutErr.RetryAfter.Seconds())+1))
+			detail := "Too many failed login attempts, try again later"
+			if lockoutErr.Locked {
+				detail = "Account temporarily locked after too many failed login attempts"
+			}
+			problem.Write(w, r, http.StatusTooManyRequests, detail)
+			return nil, false
+		}
+		middleware.WriteError(w, r, err, "Error checking login attempts")
+		return nil, false
+	}
+
+	// Check the password of the one user with this email
+	user, err := store.Users.GetByEmail(ctx, email)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		middleware.WriteError(w, r, err, "Error fetching user")
+		return nil, false
+	}
+	if user == nil {
+		// Take as long as for an existing account, so the response time
+		// does not reveal which emails have one
+		auth.CheckPassword(auth.UnknownUser, password)
+	} else if auth.CheckPassword(*user, password) {
+		if err := auth.RecordLoginSuccess(ctx, email, ip); err != nil {
+			log.Printf("Failed to reset login attempts: %v", err)
+		}
+		event := newAuthEvent(r, auth.EventLoginSuccess)
+		event.UserID = user.ID
+		event.Email = user.Email
+		auth.RecordEvent(ctx, event)
+		return user, true
+	}
+
+	// Keep the attempt counted and lock the account or address if needed
+	lockedOut, err := auth.RecordLoginFailure(ctx, email, ip)
+	if err != nil {
+		log.Printf("Failed to record login failure: %v", err)
+	}
+
+	event := newAuthEvent(r, auth.EventLoginFailure)
+	event.Email = email
+	event.Reason = "invalid credentials"
+	auth.RecordEvent(ctx, event)
+
+	if lockedOut {
+		event.Type = auth.EventLockout
+		event.Reason = "too many failed login attempts"
+		auth.RecordEvent(ctx, event)
+	}
+
+	problem.Write(w, r, http.StatusUnauthorized, "Invalid credentials")
+	return nil, false
+}
+
+// newAuthEvent starts an auth event describing the current request
+func newAuthEvent(r *http.Request, eventType string) models.AuthEvent {
+	return models.AuthEvent{
+		Type:      eventType,
+		IP:        middleware.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+}
+
+// toUserResponse strips sensitive fields from a user
+func toUserResponse(user models.User) models.UserResponse {
+	return models.UserResponse{
+		ID:    user.ID,
+		Email: user.Email,
+		Name:  user.Name,
+		Role:  user.Role,
+	}
+}
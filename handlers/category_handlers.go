@@ -1,48 +1,83 @@
 package handlers
 
 import (
-	"context"
-	"encoding/json"
+	"errors"
 	"net/http"
-	"time"
 
-	"go-backend/db"
+	"go-backend/codec"
+	"go-backend/middleware"
 	"go-backend/models"
+	"go-backend/problem"
+	"go-backend/repository"
 
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/gorilla/mux"
 )
 
 // GET /categories endpoint
 func GetCategories(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	collection := db.GetCategoriesCollection()
+	ctx := r.Context()
 
 	// Find all categories
-	cursor, err := collection.Find(ctx, bson.M{})
+	categories, err := store.Categories.List(ctx)
 	if err != nil {
-		http.Error(w, "Error fetching categories", http.StatusInternalServerError)
+		middleware.WriteError(w, r, err, "Error fetching categories")
+		return
+	}
+
+	// Return categories in the shape of the request's API version
+	serializerFor(r).Categories(w, r, categories)
+}
+
+// GET /categories/{id} endpoint
+func GetCategoryByID(w http.ResponseWriter, r *http.Request) {
+	// Neet to implement this
+}
+
+// PUT /categories/{id}/parent endpoint (admin only)
+func MoveCategory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+
+	var req models.MoveCategoryRequest
+	if err := codec.Decode(r, &req); err != nil {
+		status, detail := bodyError(err)
+		problem.Write(w, r, status, detail)
 		return
 	}
-	defer cursor.Close(ctx)
 
-	// Parse results
-	var categories []models.Category
-	if err = cursor.All(ctx, &categories); err != nil {
-		http.Error(w, "Error parsing categories", http.StatusInternalServerError)
+	// Products in the subtree are regrouped in the same transaction
+	if err := store.MoveCategory(ctx, id, req.ParentID); err != nil {
+		writeCategoryError(w, r, err, "Error moving category")
 		return
 	}
 
-	// Return categories as JSON
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(categories); err != nil {
-		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+	category, err := store.Categories.Get(ctx, id)
+	if err != nil {
+		writeCategoryError(w, r, err, "Error fetching category")
 		return
 	}
+
+	codec.Write(w, r, http.StatusOK, category)
 }
 
-// GET /categories/{id} endpoint
-func GetCategoryByID(w http.ResponseWriter, r *http.Request) {
-	// Neet to implement this
+// DELETE /categories/{id} endpoint (admin only)
+func DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	// Subcategories and products move to the parent in the same transaction
+	if err := store.DeleteCategory(r.Context(), mux.Vars(r)["id"]); err != nil {
+		writeCategoryError(w, r, err, "Error deleting category")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeCategoryError maps repository errors of category changes to responses
+func writeCategoryError(w http.ResponseWriter, r *http.Request, err error, detail string) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		problem.Write(w, r, http.StatusNotFound, "Category not found")
+	case errors.Is(err, repository.ErrConflict):
+		problem.Write(w, r, http.StatusConflict, err.Error())
+	default:
+		middleware.WriteError(w, r, err, detail)
+	}
 }
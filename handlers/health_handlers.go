@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"go-backend/health"
+)
+
+// GET /healthz endpoint: the process is up and serving
+func Liveness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status":"ok"}`))
+}
+
+// GET /readyz endpoint: dependencies are reachable and we are not shutting down
+func Readiness(w http.ResponseWriter, r *http.Request) {
+	report := health.Default.Run(context.WithoutCancel(r.Context()))
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"status": report.Status})
+}
+
+// GET /health/details endpoint (admin only): per-check status and latency
+func HealthDetails(w http.ResponseWriter, r *http.Request) {
+	report := health.Default.Run(context.WithoutCancel(r.Context()))
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
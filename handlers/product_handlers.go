@@ -1,188 +1,98 @@
 package handlers
 
 import (
-	"context"
-	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
-	"time"
 
-	"go-backend/db"
+	"go-backend/codec"
+	"go-backend/middleware"
 	"go-backend/models"
+	"go-backend/repository"
 
 	"github.com/gorilla/mux"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // GET /products endpoint with pagination and filtering
 func GetProducts(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	collection := db.GetProductsCollection()
+	ctx := r.Context()
 
 	// Parse query parameters
 	params := parseProductsQueryParams(r)
 
-	// Build filter
-	filter := bson.M{}
-	if params.CategoryID != "" {
-		filter["category_id"] = params.CategoryID
-	}
-	if params.CategoryGroup != "" {
-		filter["category_group"] = params.CategoryGroup
-	}
-
-	// Build options for sorting and pagination
-	findOptions := options.Find()
-	if params.SortField != "" {
-		sortValue := 1 // asc
-		if params.SortOrder == "desc" {
-			sortValue = -1 // desc
-		}
-
-		// Map API field names to MongoDB field names
-		sortFieldName := params.SortField
-		if params.SortField == "id" {
-			sortFieldName = "_id"
-		}
-
-		findOptions.SetSort(bson.D{{Key: sortFieldName, Value: sortValue}})
-
-		// Add case-insensitive collation for string fields
-		if params.SortField != "id" && params.SortField != "_id" {
-			// Use simple collation with case-insensitive comparison
-			findOptions.SetCollation(&options.Collation{
-				Locale:   "en",
-				Strength: 2, // 2 = case-insensitive
-			})
-		}
-	}
-
-	// First get total count
-	total, err := collection.CountDocuments(ctx, filter)
+	products, total, err := store.Products.List(ctx, params)
 	if err != nil {
-		http.Error(w, "Error counting products", http.StatusInternalServerError)
+		middleware.WriteError(w, r, err, "Error fetching products")
 		return
 	}
 
-	// Apply pagination
-	findOptions.SetSkip(int64(params.Start))
-	findOptions.SetLimit(int64(params.Limit))
-
-	// Execute query
-	cursor, err := collection.Find(ctx, filter, findOptions)
-	if err != nil {
-		http.Error(w, "Error fetching products", http.StatusInternalServerError)
-		return
-	}
-	defer cursor.Close(ctx)
-
-	// Parse results
-	var products []models.Product
-	if err = cursor.All(ctx, &products); err != nil {
-		http.Error(w, "Error parsing products", http.StatusInternalServerError)
-		return
-	}
-
-	// Build response
-	response := models.ProductsResponse{
-		Products: products,
-		Total:    total,
-	}
-
-	// Return products as JSON
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		http.Error(w, "Error encoding response", http.StatusInternalServerError)
-		return
-	}
+	// Return the page in the shape of the request's API version
+	serializerFor(r).Products(w, r, products, total, params)
 }
 
 // POST /products endpoint
 func CreateProduct(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	ctx := r.Context()
 
 	// Parse request body
 	var product models.Product
-	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := codec.Decode(r, &product); err != nil {
+		status, detail := bodyError(err)
+		serializerFor(r).Error(w, r, status, detail)
 		return
 	}
 
 	// Validate required fields (except ID which will be generated)
 	if product.Name == "" || product.CategoryID == "" {
-		http.Error(w, "Missing required fields", http.StatusBadRequest)
+		serializerFor(r).Error(w, r, http.StatusBadRequest, "Missing required fields")
 		return
 	}
-
-	// Generate a new ObjectID for the product
-	product.ID = primitive.NewObjectID()
-
-	collection := db.GetProductsCollection()
-
-	// Insert the product
-	_, err := collection.InsertOne(ctx, product)
-	if err != nil {
-		http.Error(w, "Error creating product", http.StatusInternalServerError)
+	if err := product.CheckLimits(); err != nil {
+		serializerFor(r).Error(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Return the created product with the generated ID
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(product); err != nil {
-		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+	// Insert the product with a newly generated ID
+	product.ID = primitive.NilObjectID
+	if err := store.Products.Create(ctx, &product); err != nil {
+		middleware.WriteError(w, r, err, "Error creating product")
 		return
 	}
+
+	// Return the created product with the generated ID
+	codec.Write(w, r, http.StatusCreated, product)
 }
 
 // GET /products/{id} endpoint
 func GetProductByID(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	ctx := r.Context()
 
 	// Get ID from URL
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	collection := db.GetProductsCollection()
-
-	// Try to convert the string ID to ObjectID
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		http.Error(w, "Invalid ObjectID format", http.StatusBadRequest)
-		return
-	}
-
-	// Find product by ObjectID using _id field
-	var product models.Product
-	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&product)
+	// Find product by ObjectID
+	product, err := store.Products.Get(ctx, id)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			http.Error(w, "Product not found", http.StatusNotFound)
-		} else {
-			http.Error(w, "Error fetching product", http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, repository.ErrInvalidID):
+			serializerFor(r).Error(w, r, http.StatusBadRequest, "Invalid ObjectID format")
+		case errors.Is(err, repository.ErrNotFound):
+			serializerFor(r).Error(w, r, http.StatusNotFound, "Product not found")
+		default:
+			middleware.WriteError(w, r, err, "Error fetching product")
 		}
 		return
 	}
 
-	// Return product as JSON
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(product); err != nil {
-		http.Error(w, "Error encoding response", http.StatusInternalServerError)
-		return
-	}
+	// Return product in the negotiated format
+	codec.Write(w, r, http.StatusOK, product)
 }
 
 // PUT /products/{id} endpoint
 func UpdateProduct(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	ctx := r.Context()
 
 	// Get ID from URL
 	vars := mux.Vars(r)
@@ -190,49 +100,39 @@ func UpdateProduct(w http.ResponseWriter, r *http.Request) {
 
 	// Parse request body
 	var product models.Product
-	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := codec.Decode(r, &product); err != nil {
+		status, detail := bodyError(err)
+		serializerFor(r).Error(w, r, status, detail)
+		return
+	}
+
+	if err := product.CheckLimits(); err != nil {
+		serializerFor(r).Error(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Try to convert the string ID to ObjectID
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		http.Error(w, "Invalid ObjectID format", http.StatusBadRequest)
+		serializerFor(r).Error(w, r, http.StatusBadRequest, "Invalid ObjectID format")
 		return
 	}
 
 	// Ensure we use the ID from the URL
 	product.ID = objectID
 
-	collection := db.GetProductsCollection()
-
 	// Update product
-	filter := bson.M{"_id": objectID}
-	update := bson.M{"$set": bson.M{
-		"name":           product.Name,
-		"category_id":    product.CategoryID,
-		"category_group": product.CategoryGroup,
-		"attributes":     product.Attributes,
-	}}
-
-	result, err := collection.UpdateOne(ctx, filter, update)
-	if err != nil {
-		http.Error(w, "Error updating product", http.StatusInternalServerError)
-		return
-	}
-
-	if result.MatchedCount == 0 {
-		http.Error(w, "Product not found", http.StatusNotFound)
+	if err := store.Products.Update(ctx, &product); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			serializerFor(r).Error(w, r, http.StatusNotFound, "Product not found")
+		} else {
+			middleware.WriteError(w, r, err, "Error updating product")
+		}
 		return
 	}
 
 	// Return updated product
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(product); err != nil {
-		http.Error(w, "Error encoding response", http.StatusInternalServerError)
-		return
-	}
+	codec.Write(w, r, http.StatusOK, product)
 }
 
 // Helper function to parse query parameters
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-backend/codec"
+	"go-backend/middleware"
+	"go-backend/models"
+	"go-backend/problem"
+)
+
+// serializer writes the responses whose shape differs between API versions.
+// Handlers stay the same for every version and hand their results to the
+// serializer of the request's version.
+type serializer interface {
+	Products(w http.ResponseWriter, r *http.Request, products []models.Product, total int64, params models.PaginationParams)
+	Users(w http.ResponseWriter, r *http.Request, users []models.UserResponse)
+	Categories(w http.ResponseWriter, r *http.Request, categories []models.Category)
+	AuthEvents(w http.ResponseWriter, r *http.Request, events []models.AuthEvent)
+
+	// Error writes an error found by the handler itself
+	Error(w http.ResponseWriter, r *http.Request, status int, detail string)
+}
+
+var serializers = map[int]serializer{
+	middleware.APIv1: v1Serializer{},
+	middleware.APIv2: v2Serializer{},
+}
+
+// serializerFor returns the serializer of r's API version. Routes outside
+// the versioned API get v1.
+func serializerFor(r *http.Request) serializer {
+	if s, ok := serializers[middleware.APIVersion(r.Context())]; ok {
+		return s
+	}
+	return serializers[middleware.APIv1]
+}
+
+// v1Serializer keeps the original response shapes: bare lists that are
+// null when empty, and plain text errors
+type v1Serializer struct{}
+
+func (v1Serializer) Products(w http.ResponseWriter, r *http.Request, products []models.Product, total int64, _ models.PaginationParams) {
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	codec.WriteList(w, r, http.StatusOK, models.ProductsResponse{Products: products, Total: total}, products)
+}
+
+func (v1Serializer) Users(w http.ResponseWriter, r *http.Request, users []models.UserResponse) {
+	codec.WriteList(w, r, http.StatusOK, users, users)
+}
+
+func (v1Serializer) Categories(w http.ResponseWriter, r *http.Request, categories []models.Category) {
+	codec.WriteList(w, r, http.StatusOK, categories, categories)
+}
+
+func (v1Serializer) AuthEvents(w http.ResponseWriter, r *http.Request, events []models.AuthEvent) {
+	codec.WriteList(w, r, http.StatusOK, events, events)
+}
+
+func (v1Serializer) Error(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	http.Error(w, detail, status)
+}
+
+// v2Serializer wraps lists in objects, never writes null for an empty
+// list, adds pagination metadata and writes every error as a problem
+type v2Serializer struct{}
+
+func (v2Serializer) Products(w http.ResponseWriter, r *http.Request, products []models.Product, total int64, params models.PaginationParams) {
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	products = nonNil(products)
+	codec.WriteList(w, r, http.StatusOK, models.ProductPage{
+		Products:   products,
+		Pagination: models.NewPagination(params, len(products), total),
+	}, products)
+}
+
+func (v2Serializer) Users(w http.ResponseWriter, r *http.Request, users []models.UserResponse) {
+	users = nonNil(users)
+	codec.WriteList(w, r, http.StatusOK, models.UserList{Users: users}, users)
+}
+
+func (v2Serializer) Categories(w http.ResponseWriter, r *http.Request, categories []models.Category) {
+	categories = nonNil(categories)
+	codec.WriteList(w, r, http.StatusOK, categories, categories)
+}
+
+func (v2Serializer) AuthEvents(w http.ResponseWriter, r *http.Request, events []models.AuthEvent) {
+	events = nonNil(events)
+	codec.WriteList(w, r, http.StatusOK, events, events)
+}
+
+func (v2Serializer) Error(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	problem.Write(w, r, status, detail)
+}
+
+// bodyError returns the status and detail of the response to a request
+// body that did not decode
+func bodyError(err error) (int, string) {
+	var tooLarge *http.MaxBytesError
+	var refused *codec.BodyError
+	switch {
+	case errors.Is(err, codec.ErrUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType, "The Content-Type of the body is not supported"
+	case errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge, middleware.TooLargeDetail(tooLarge.Limit)
+	case errors.As(err, &refused):
+		return http.StatusBadRequest, refused.Detail
+	}
+	return http.StatusBadRequest, "Invalid request body"
+}
+
+// nonNil turns a nil slice into an empty one, which encodes as [] instead
+// of null
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
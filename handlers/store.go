@@ -0,0 +1,11 @@
+package handlers
+
+import "go-backend/repository"
+
+// store is the data access layer shared by all handlers
+var store *repository.Store
+
+// SetStore sets the repositories the handlers read and write
+func SetStore(s *repository.Store) {
+	store = s
+}
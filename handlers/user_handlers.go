@@ -1,18 +1,16 @@
 package handlers
 
 import (
-	"context"
-	"encoding/json"
 	"errors"
 	"net/http"
-	"time"
 
-	"go-backend/db"
+	"go-backend/codec"
+	"go-backend/middleware"
 	"go-backend/models"
+	"go-backend/problem"
+	"go-backend/repository"
 
 	"github.com/gorilla/mux"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // This is developed for the interview only.
@@ -21,66 +19,34 @@ import (
 
 // GetUsers handles requests to get users, also used for authentication
 func GetUsers(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	collection := db.GetUsersCollection()
-
-	// Build filter based on query parameters
-	filter := bson.M{}
+	ctx := r.Context()
 
 	// For authentication - check email and password
 	email := r.URL.Query().Get("email")
 	password := r.URL.Query().Get("password")
 
-	if email != "" {
-		filter["email"] = email
-	}
-
-	// Execute query
-	cursor, err := collection.Find(ctx, filter)
-	if err != nil {
-		http.Error(w, "Error fetching users", http.StatusInternalServerError)
-		return
-	}
-	defer cursor.Close(ctx)
-
-	// Parse results
-	var users []models.User
-	if err = cursor.All(ctx, &users); err != nil {
-		http.Error(w, "Error parsing users", http.StatusInternalServerError)
+	// Authentication goes through brute-force protection and auditing.
+	// Version 2 only checks credentials at the login endpoint.
+	if password != "" && middleware.APIVersion(ctx) >= middleware.APIv2 {
+		problem.Write(w, r, http.StatusBadRequest, "Checking credentials here is not supported, use POST /api/v2/auth/login")
 		return
 	}
-
-	// For authentication - if password was provided, we need to check it
-	if password != "" && len(users) > 0 {
-		authenticated := false
-		var authenticatedUser models.User
-
-		for _, user := range users {
-			// In production, use password hashing and proper comparison
-			if user.Password == password {
-				authenticated = true
-				authenticatedUser = user
-				break
-			}
-		}
-
-		if !authenticated {
-			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	if password != "" {
+		user, ok := authenticate(ctx, w, r, email, password)
+		if !ok {
 			return
 		}
 
 		// Return only the authenticated user without password
-		userResp := models.UserResponse{
-			ID:    authenticatedUser.ID,
-			Email: authenticatedUser.Email,
-			Name:  authenticatedUser.Name,
-			Role:  authenticatedUser.Role,
-		}
+		userResp := toUserResponse(*user)
+		codec.WriteList(w, r, http.StatusOK, userResp, []models.UserResponse{userResp})
+		return
+	}
 
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(userResp)
+	// Find users, filtered by email if given
+	users, err := store.Users.List(ctx, email)
+	if err != nil {
+		middleware.WriteError(w, r, err, "Error fetching users")
 		return
 	}
 
@@ -95,30 +61,25 @@ func GetUsers(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	// Return users as JSON
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(userResponses)
+	// Return users in the shape of the request's API version
+	serializerFor(r).Users(w, r, userResponses)
 }
 
 // GetUserByID retrieves a single user by ID
 func GetUserByID(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	ctx := r.Context()
 
 	// Get ID from URL
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	collection := db.GetUsersCollection()
-
 	// Find user by ID
-	var user models.User
-	err := collection.FindOne(ctx, bson.M{"id": id}).Decode(&user)
+	user, err := store.Users.Get(ctx, id)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			http.Error(w, "User not found", http.StatusNotFound)
+		if errors.Is(err, repository.ErrNotFound) {
+			serializerFor(r).Error(w, r, http.StatusNotFound, "User not found")
 		} else {
-			http.Error(w, "Error fetching user", http.StatusInternalServerError)
+			middleware.WriteError(w, r, err, "Error fetching user")
 		}
 		return
 	}
@@ -131,10 +92,19 @@ func GetUserByID(w http.ResponseWriter, r *http.Request) {
 		Role:  user.Role,
 	}
 
-	// Return user as JSON
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(userResp); err != nil {
-		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+	// Return user in the negotiated format
+	codec.Write(w, r, http.StatusOK, userResp)
+}
+
+// DeleteUser deletes a user and revokes their sessions (admin only)
+func DeleteUser(w http.ResponseWriter, r *http.Request) {
+	if err := store.DeleteUser(r.Context(), mux.Vars(r)["id"]); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			problem.Write(w, r, http.StatusNotFound, "User not found")
+		} else {
+			middleware.WriteError(w, r, err, "Error deleting user")
+		}
 		return
 	}
+	w.WriteHeader(http.StatusNoContent)
 }
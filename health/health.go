@@ -0,0 +1,173 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Overall and per-check statuses
+const (
+	StatusOK           = "ok"
+	StatusDegraded     = "degraded" // a non-critical check is failing
+	StatusFail         = "fail"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Check is a named dependency check
+type Check struct {
+	Name string
+	// Check returns nil when the dependency is healthy
+	Check func(ctx context.Context) error
+	// Timeout bounds a single run of the check; zero uses the registry default
+	Timeout time.Duration
+	// Critical checks make the service not ready when they fail
+	Critical bool
+}
+
+// CheckResult is the outcome of one check
+type CheckResult struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of all checks
+type Report struct {
+	Status    string                 `json:"status"`
+	CheckedAt time.Time              `json:"checked_at"`
+	Checks    map[string]CheckResult `json:"checks"`
+}
+
+// Registry runs registered checks and caches the report so frequent probes
+// do not hit dependencies on every request
+type Registry struct {
+	mu       sync.Mutex
+	checks   []Check
+	cacheTTL time.Duration
+	timeout  time.Duration
+	cached   *Report
+}
+
+// NewRegistry creates a registry caching reports for cacheTTL and giving
+// each check timeout to complete by default
+func NewRegistry(cacheTTL, timeout time.Duration) *Registry {
+	return &Registry{cacheTTL: cacheTTL, timeout: timeout}
+}
+
+// Default is the registry used by the health endpoints
+var Default = NewRegistry(2*time.Second, 2*time.Second)
+
+// Register adds a check to the default registry
+func Register(check Check) {
+	Default.Register(check)
+}
+
+// Register adds a check
+func (r *Registry) Register(check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, check)
+	r.cached = nil
+}
+
+// Configure changes the cache lifetime and default check timeout
+func (r *Registry) Configure(cacheTTL, timeout time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cacheTTL = cacheTTL
+	r.timeout = timeout
+	r.cached = nil
+}
+
+// Run returns the cached report, running all checks concurrently if it has expired
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cached != nil && time.Since(r.cached.CheckedAt) < r.cacheTTL {
+		return r.withShutdown(*r.cached)
+	}
+
+	report := Report{
+		Status:    StatusOK,
+		CheckedAt: time.Now().UTC(),
+		Checks:    make(map[string]CheckResult, len(r.checks)),
+	}
+
+	results := make([]CheckResult, len(r.checks))
+	var wg sync.WaitGroup
+	for i, check := range r.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.runCheck(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	for i, check := range r.checks {
+		result := results[i]
+		report.Checks[check.Name] = result
+		if result.Status == StatusOK {
+			continue
+		}
+		if check.Critical {
+			report.Status = StatusFail
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+
+	r.cached = &report
+	return r.withShutdown(report)
+}
+
+func (r *Registry) runCheck(ctx context.Context, check Check) CheckResult {
+	timeout := check.Timeout
+	if timeout == 0 {
+		timeout = r.timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	result := CheckResult{
+		Status:    StatusOK,
+		Critical:  check.Critical,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// withShutdown overrides the status while the process is draining
+func (r *Registry) withShutdown(report Report) Report {
+	if ShuttingDown() {
+		report.Status = StatusShuttingDown
+	}
+	return report
+}
+
+// Ready reports whether the service can take traffic
+func (report Report) Ready() bool {
+	return report.Status == StatusOK || report.Status == StatusDegraded
+}
+
+var shuttingDown atomic.Bool
+
+// SetShuttingDown marks the process as draining so readiness starts failing
+func SetShuttingDown() {
+	shuttingDown.Store(true)
+}
+
+// ShuttingDown reports whether the process is draining
+func ShuttingDown() bool {
+	return shuttingDown.Load()
+}
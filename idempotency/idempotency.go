@@ -0,0 +1,46 @@
+// Package idempotency remembers the responses to requests sent with an
+// Idempotency-Key, so that a client retrying a request gets the original
+// response instead of having the request carried out twice.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrInProgress is returned by Begin while another request holds the key
+	ErrInProgress = errors.New("a request with this idempotency key is in progress")
+
+	// ErrMismatch is returned by Begin when the key was used for a request
+	// with a different fingerprint
+	ErrMismatch = errors.New("idempotency key was used for a different request")
+)
+
+// Response is a stored response
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Store keeps the state of idempotency keys. MemoryStore suits a single
+// instance; deployments with several instances can share keys by
+// implementing Store on top of a shared database such as Redis.
+type Store interface {
+	// Begin claims key for a request with the given fingerprint. It returns
+	// the stored response if a request with the key completed, and
+	// ErrInProgress or ErrMismatch if the key cannot be claimed. A nil
+	// response and error mean the caller holds the key until it calls
+	// Complete or Release; an unreleased claim lapses after ttl.
+	Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Response, error)
+
+	// Complete stores the response to the request holding key for ttl
+	Complete(ctx context.Context, key string, response *Response, ttl time.Duration) error
+
+	// Release gives up the claim on key without storing a response, so the
+	// request can be retried
+	Release(ctx context.Context, key string) error
+}
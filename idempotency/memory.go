@@ -0,0 +1,92 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often expired keys are dropped from memory
+const sweepInterval = time.Minute
+
+type entry struct {
+	fingerprint string
+	response    *Response // nil while the request is in progress
+	expires     time.Time
+}
+
+// MemoryStore is an in-process Store
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries:   make(map[string]*entry),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Begin implements Store
+func (s *MemoryStore) Begin(_ context.Context, key, fingerprint string, ttl time.Duration) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	e, ok := s.entries[key]
+	if !ok || !now.Before(e.expires) {
+		s.entries[key] = &entry{fingerprint: fingerprint, expires: now.Add(ttl)}
+		return nil, nil
+	}
+	switch {
+	case e.fingerprint != fingerprint:
+		return nil, ErrMismatch
+	case e.response == nil:
+		return nil, ErrInProgress
+	}
+	return e.response, nil
+}
+
+// Complete implements Store
+func (s *MemoryStore) Complete(_ context.Context, key string, response *Response, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.response = response
+		e.expires = s.now().Add(ttl)
+	}
+	return nil
+}
+
+// Release implements Store
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok && e.response == nil {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// sweep drops expired keys
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
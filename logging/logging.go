@@ -0,0 +1,39 @@
+package logging
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+
+	"go-backend/config"
+)
+
+// level is shared by every handler so it can be changed at runtime
+var level = new(slog.LevelVar)
+
+// Setup installs the default slog logger. The standard log package is
+// routed through it as well.
+func Setup(cfg config.Logging) {
+	SetLevel(cfg.Level)
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler = slog.NewTextHandler(os.Stderr, opts)
+	if strings.EqualFold(cfg.Format, "json") {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+// SetLevel changes the minimum level logged
+func SetLevel(name string) {
+	switch strings.ToLower(name) {
+	case "debug":
+		level.Set(slog.LevelDebug)
+	case "warn":
+		level.Set(slog.LevelWarn)
+	case "error":
+		level.Set(slog.LevelError)
+	default:
+		level.Set(slog.LevelInfo)
+	}
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Panics counts handler panics recovered by the recovery middleware, keyed by route
+var Panics = expvar.NewMap("http_panics_total")
+
+// RequestTimeouts counts requests answered with 504 because their budget ran out, keyed by route
+var RequestTimeouts = expvar.NewMap("http_request_timeouts_total")
+
+// ValidationFailures counts requests rejected and responses replaced because
+// they do not match the OpenAPI document, keyed by "request ROUTE" or
+// "response ROUTE"
+var ValidationFailures = expvar.NewMap("http_validation_failures_total")
+
+// APIRequests counts requests to the versioned API, keyed by version and
+// route, e.g. "v1 GET /api/products"
+var APIRequests = expvar.NewMap("http_api_requests_total")
+
+// CompressedResponses counts responses sent compressed, keyed by encoding
+var CompressedResponses = expvar.NewMap("http_compressed_responses_total")
+
+// IdempotentRequests counts requests with an Idempotency-Key by outcome:
+// stored, replayed, in_progress or mismatch
+var IdempotentRequests = expvar.NewMap("http_idempotent_requests_total")
+
+// GRPCRequests counts gRPC calls, keyed by method and status code, e.g.
+// "/catalog.v1.CatalogService/GetProduct OK"
+var GRPCRequests = expvar.NewMap("grpc_requests_total")
+
+// DatabaseCircuit is the state of the database circuit breaker
+var DatabaseCircuit = expvar.NewString("db_circuit_state")
+
+func init() {
+	DatabaseCircuit.Set("closed")
+}
+
+// runtimeVars are published by the expvar package itself. cmdline holds
+// the command line flags, which can carry credentials such as
+// -database.uri, so neither is served.
+var runtimeVars = map[string]bool{"cmdline": true, "memstats": true}
+
+// Handler serves the application's metrics as JSON
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		io.WriteString(w, "{\n")
+		first := true
+		expvar.Do(func(kv expvar.KeyValue) {
+			if runtimeVars[kv.Key] {
+				return
+			}
+			if !first {
+				io.WriteString(w, ",\n")
+			}
+			first = false
+			fmt.Fprintf(w, "%q: %s", kv.Key, kv.Value)
+		})
+		io.WriteString(w, "\n}\n")
+	})
+}
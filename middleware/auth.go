@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"go-backend/auth"
+	"go-backend/problem"
+)
+
+// AuthMiddleware resolves a bearer token to its session. Requests without a
+// token continue anonymously; requests with an invalid token are rejected.
+func AuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_request"`)
+			problem.Write(w, r, http.StatusUnauthorized, "Authorization header must use the Bearer scheme")
+			return
+		}
+
+		session, err := auth.LookupSession(r.Context(), token)
+		if err != nil {
+			if errors.Is(err, auth.ErrInvalidToken) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				problem.Write(w, r, http.StatusUnauthorized, "Invalid or expired token")
+				return
+			}
+			WriteError(w, r, err, "Error checking credentials")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), session)))
+	})
+}
+
+// RequireAuth rejects anonymous requests with 401
+func RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.FromContext(r.Context()) == nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			problem.Write(w, r, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireRole rejects requests that are not authenticated with the given role
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if auth.FromContext(r.Context()).Role != role {
+				problem.Write(w, r, http.StatusForbidden, "This endpoint requires the "+role+" role")
+				return
+			}
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
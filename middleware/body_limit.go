@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"go-backend/config"
+	"go-backend/problem"
+)
+
+// BodyLimits caps the size of request bodies per route. Reading past the
+// limit fails with an *http.MaxBytesError, which handlers and request
+// validation answer with 413. The limits can be swapped at runtime.
+type BodyLimits struct {
+	cfg atomic.Pointer[config.Body]
+}
+
+// NewBodyLimits creates body size limits for the configured routes
+func NewBodyLimits(cfg config.Body) *BodyLimits {
+	b := &BodyLimits{}
+	b.Update(cfg)
+	return b
+}
+
+// Update atomically replaces the configured limits
+func (b *BodyLimits) Update(cfg config.Body) {
+	b.cfg.Store(&cfg)
+}
+
+// Limit returns the limit for a route, preferring "METHOD /path" over
+// "/path" over the default
+func (b *BodyLimits) Limit(method, pathTemplate string) int64 {
+	cfg := b.cfg.Load()
+	if limit, ok := cfg.Routes[method+" "+pathTemplate]; ok {
+		return limit
+	}
+	if limit, ok := cfg.Routes[pathTemplate]; ok {
+		return limit
+	}
+	return cfg.MaxBytes
+}
+
+// Middleware applies the route's limit. Bodies that announce a larger
+// Content-Length are refused before they are read.
+func (b *BodyLimits) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := b.Limit(r.Method, routeTemplate(r))
+		if r.ContentLength > limit {
+			problem.Write(w, r, http.StatusRequestEntityTooLarge, TooLargeDetail(limit))
+			return
+		}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TooLargeDetail is the problem detail of a 413 for a body over limit bytes
+func TooLargeDetail(limit int64) string {
+	return fmt.Sprintf("Request body is larger than the limit of %d bytes", limit)
+}
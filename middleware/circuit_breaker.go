@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"go-backend/circuit"
+	"go-backend/problem"
+
+	"github.com/gorilla/mux"
+)
+
+// NewCircuitBreakerMiddleware fails requests fast with 503 while breaker is
+// open. Routes whose path template is in exempt always pass, so health
+// checks and metrics keep reporting.
+func NewCircuitBreakerMiddleware(breaker *circuit.Breaker, exempt ...string) mux.MiddlewareFunc {
+	skip := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		skip[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions || skip[routeTemplate(r)] || breaker.Allow() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			retryAfter := int(math.Ceil(breaker.RetryAfter().Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+			problem.Write(w, r, http.StatusServiceUnavailable, "The database is unavailable, try again later")
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+type clientIPKey struct{}
+
+// NewClientIPMiddleware resolves the client's address once per request.
+// X-Forwarded-For is only honoured when the connection comes from one of the
+// trusted proxies; the client is the right-most address not belonging to one.
+func NewClientIPMiddleware(trustedProxies []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := resolveClientIP(r, trustedProxies)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+		})
+	}
+}
+
+// ClientIP returns the client address resolved by the client IP middleware,
+// falling back to the connection's remote address
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+func resolveClientIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	ip := remoteIP(r)
+	if !isTrusted(ip, trustedProxies) {
+		return ip
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, splitList(header)...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := hops[i]
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+		if !isTrusted(hop, trustedProxies) {
+			break
+		}
+	}
+	return ip
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func isTrusted(ip string, trustedProxies []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range trustedProxies {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,324 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go-backend/config"
+	"go-backend/metrics"
+	"go-backend/problem"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// encoder is the writer of a content coding. Encoders are pooled and
+// reset for each response.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoders holds a pool of encoders per content coding
+var encoders = map[string]*sync.Pool{
+	"gzip": {New: func() any {
+		return gzip.NewWriter(nil)
+	}},
+	// Level 4 compresses close to gzip's best at a fraction of the cost of
+	// brotli's default, which is meant for static files
+	"br": {New: func() any {
+		return brotli.NewWriterLevel(nil, 4)
+	}},
+	"zstd": {New: func() any {
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1)) // only fails on invalid options
+		return enc
+	}},
+}
+
+// decoders open a request body sent with a content coding
+var decoders = map[string]func(io.Reader) (io.ReadCloser, error){
+	"gzip": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	"br": func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(brotli.NewReader(r)), nil
+	},
+	"zstd": func(r io.Reader) (io.ReadCloser, error) {
+		dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	},
+}
+
+// NewCompressionMiddleware compresses responses with the encoding the
+// client prefers in Accept-Encoding, and decompresses request bodies sent
+// with a Content-Encoding. Responses are compressed only if their
+// Content-Type is listed and they reach the minimum size; a handler that
+// flushes, like a streaming export, has its response compressed right away
+// and every flush goes out to the client.
+func NewCompressionMiddleware(cfg config.Compression) func(http.Handler) http.Handler {
+	contentTypes := make(map[string]bool, len(cfg.ContentTypes))
+	for _, contentType := range cfg.ContentTypes {
+		contentTypes[strings.ToLower(contentType)] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.DecompressRequests && !decompressRequest(w, r, cfg.Encodings) {
+				return
+			}
+			if !cfg.Enabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), cfg.Encodings)
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				encoding:       encoding,
+				minSize:        cfg.MinSize,
+				contentTypes:   contentTypes,
+				status:         http.StatusOK,
+			}
+			next.ServeHTTP(cw, r)
+			cw.close()
+		})
+	}
+}
+
+// decompressRequest replaces the body of a request sent with a
+// Content-Encoding by its decoded form. It reports whether the request can
+// go on, answering it with 415 if the encoding is not supported.
+func decompressRequest(w http.ResponseWriter, r *http.Request, encodings []string) bool {
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" || r.Body == nil || r.Body == http.NoBody {
+		return true
+	}
+	if encoding == "x-gzip" {
+		encoding = "gzip"
+	}
+
+	if !slices.Contains(encodings, encoding) {
+		w.Header().Set("Accept-Encoding", strings.Join(encodings, ", "))
+		problem.Write(w, r, http.StatusUnsupportedMediaType,
+			"The Content-Encoding of the body is not supported, supported are "+strings.Join(encodings, ", "))
+		return false
+	}
+	body, err := decoders[encoding](r.Body)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, "The body is not valid "+encoding)
+		return false
+	}
+
+	r.Body = body
+	r.ContentLength = -1
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	return true
+}
+
+// negotiateEncoding picks the encoding for an Accept-Encoding header from
+// offered, which is in order of preference. The encoding with the highest
+// quality wins and ties go to the earlier encoding; * gives the quality of
+// encodings not listed. It returns "" if the response should not be encoded.
+func negotiateEncoding(header string, offered []string) string {
+	if strings.TrimSpace(header) == "" {
+		return ""
+	}
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "x-gzip" {
+			coding = "gzip"
+		}
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil && parsed >= 0 && parsed <= 1 {
+				quality = parsed
+			}
+		}
+		qualities[coding] = quality
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range offered {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality = qualities["*"]
+		}
+		if quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+// compressWriter holds a response back until it knows whether to compress
+// it: responses that cannot be compressed are passed through as soon as
+// their headers are written, the others are buffered until they reach the
+// minimum size, are flushed or end.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding     string
+	minSize      int
+	contentTypes map[string]bool
+
+	status      int
+	wroteHeader bool
+	buf         []byte
+
+	decided bool    // the headers went out
+	enc     encoder // set if the response is compressed
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if status < 200 && status != http.StatusSwitchingProtocols {
+		cw.ResponseWriter.WriteHeader(status) // informational, e.g. 103 Early Hints
+		return
+	}
+	if cw.wroteHeader {
+		return
+	}
+	cw.status = status
+	cw.wroteHeader = true
+
+	// Responses known to be small enough go out as they are
+	if length, err := strconv.Atoi(cw.Header().Get("Content-Length")); err == nil && length < cw.minSize {
+		cw.start(false)
+		return
+	}
+	// Without a Content-Type the decision waits for the body to be sniffed
+	if cw.Header().Get("Content-Type") != "" && !cw.compressible() {
+		cw.start(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	switch {
+	case cw.enc != nil:
+		return cw.enc.Write(b)
+	case cw.decided:
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= cw.minSize {
+		if err := cw.start(cw.compressible()); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush sends what was written so far, starting compression if the
+// response has not started yet, so streamed responses are compressed
+// whatever their size
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		cw.start(cw.compressible())
+	}
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// compressible reports whether the response may be compressed, judging by
+// its status and headers
+func (cw *compressWriter) compressible() bool {
+	h := cw.Header()
+	switch {
+	case cw.status == http.StatusNoContent || cw.status == http.StatusNotModified || cw.status == http.StatusSwitchingProtocols:
+		return false
+	case h.Get("Content-Encoding") != "" && h.Get("Content-Encoding") != "identity":
+		return false
+	case strings.Contains(strings.ToLower(h.Get("Cache-Control")), "no-transform"):
+		return false
+	}
+
+	contentType := h.Get("Content-Type")
+	if contentType == "" {
+		if len(cw.buf) == 0 {
+			return false
+		}
+		contentType = http.DetectContentType(cw.buf)
+		h.Set("Content-Type", contentType)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && cw.contentTypes[mediaType]
+}
+
+// start sends the headers and the buffered body, through an encoder if
+// compress is set
+func (cw *compressWriter) start(compress bool) error {
+	cw.decided = true
+	h := cw.Header()
+	if compress {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		// The compressed bytes differ, so a strong validator no longer holds
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		cw.enc = encoders[cw.encoding].Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+		metrics.CompressedResponses.Add(cw.encoding, 1)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(cw.buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf)
+	}
+	cw.buf = nil
+	return err
+}
+
+// close finishes the response once the handler returned: a response still
+// held back is below the minimum size and goes out as is
+func (cw *compressWriter) close() {
+	switch {
+	case cw.enc != nil:
+		cw.enc.Close()
+		cw.enc.Reset(nil)
+		encoders[cw.encoding].Put(cw.enc)
+		cw.enc = nil
+	case cw.wroteHeader && !cw.decided:
+		if cw.Header().Get("Content-Length") == "" && cw.status != http.StatusNoContent && cw.status != http.StatusNotModified {
+			cw.Header().Set("Content-Length", strconv.Itoa(len(cw.buf)))
+		}
+		cw.start(false)
+	}
+}
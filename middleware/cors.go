@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"go-backend/config"
+	"go-backend/problem"
+
+	"github.com/gorilla/mux"
+)
+
+// CORS applies a default CORS policy with optional per-route overrides.
+// Preflight requests are only answered when the router has a route for the
+// requested path and method. The configuration can be swapped at runtime.
+type CORS struct {
+	router    *mux.Router
+	cfg       atomic.Pointer[config.CORS]
+	overrides map[string]config.CORSPolicy
+}
+
+// NewCORS creates CORS handling for the routes registered on router
+func NewCORS(router *mux.Router, cfg config.CORS) *CORS {
+	c := &CORS{
+		router:    router,
+		overrides: make(map[string]config.CORSPolicy),
+	}
+	c.Update(cfg)
+	return c
+}
+
+// Update atomically replaces the configured policies
+func (c *CORS) Update(cfg config.CORS) {
+	c.cfg.Store(&cfg)
+}
+
+// Override sets the built-in policy for the route with the given path
+// template. Routes in the configuration take precedence. Overrides must be
+// registered before serving starts.
+func (c *CORS) Override(pathTemplate string, policy config.CORSPolicy) {
+	c.overrides[pathTemplate] = policy
+}
+
+// Middleware adds CORS headers to responses and answers preflight requests
+func (c *CORS) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		requestedMethod := r.Header.Get("Access-Control-Request-Method")
+
+		// Responses with and without an Origin differ, so caches must not
+		// serve one for the other
+		w.Header().Add("Vary", "Origin")
+
+		// Plain OPTIONS requests have nothing to return
+		if r.Method == http.MethodOptions && (origin == "" || requestedMethod == "") {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		// Same-origin and non-browser requests need no CORS headers
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.Method == http.MethodOptions {
+			c.preflight(w, r, origin, requestedMethod)
+			return
+		}
+
+		policy := c.policyFor(mux.CurrentRoute(r))
+		if allowsOrigin(policy, origin) {
+			setAllowOrigin(w, policy, origin)
+			if len(policy.ExposedHeaders) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// preflight answers an OPTIONS request for the route the browser intends to call
+func (c *CORS) preflight(w http.ResponseWriter, r *http.Request, origin, requestedMethod string) {
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+	// Find the route that would serve the actual request
+	target := r.Clone(r.Context())
+	target.Method = requestedMethod
+	var match mux.RouteMatch
+	if !c.router.Match(target, &match) || match.MatchErr != nil {
+		if errors.Is(match.MatchErr, mux.ErrMethodMismatch) {
+			problem.Write(w, r, http.StatusMethodNotAllowed, "Method "+requestedMethod+" is not allowed for this endpoint")
+			return
+		}
+		problem.Write(w, r, http.StatusNotFound, "Endpoint not found")
+		return
+	}
+
+	policy := c.policyFor(match.Route)
+	if !allowsOrigin(policy, origin) {
+		problem.Write(w, r, http.StatusForbidden, "Origin not allowed")
+		return
+	}
+	if !containsFold(policy.AllowedMethods, requestedMethod) {
+		problem.Write(w, r, http.StatusForbidden, "Method "+requestedMethod+" not allowed by CORS policy")
+		return
+	}
+
+	requestedHeaders := splitList(r.Header.Get("Access-Control-Request-Headers"))
+	for _, header := range requestedHeaders {
+		if !containsFold(policy.AllowedHeaders, header) {
+			problem.Write(w, r, http.StatusForbidden, "Header "+header+" not allowed by CORS policy")
+			return
+		}
+	}
+
+	setAllowOrigin(w, policy, origin)
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(policy.AllowedMethods, ", "))
+	if len(requestedHeaders) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(requestedHeaders, ", "))
+	}
+	if policy.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(policy.MaxAge))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// policyFor returns the configured or built-in policy for the route, or the default
+func (c *CORS) policyFor(route *mux.Route) config.CORSPolicy {
+	cfg := c.cfg.Load()
+	if route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			if policy, ok := cfg.Routes[tmpl]; ok {
+				return policy
+			}
+			if policy, ok := c.overrides[tmpl]; ok {
+				return policy
+			}
+		}
+	}
+	return cfg.CORSPolicy
+}
+
+// allowsOrigin reports whether origin matches one of the policy's allowed origins
+func allowsOrigin(p config.CORSPolicy, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range p.AllowedOrigins {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+
+		// "https://*.example.com" matches any subdomain but not the apex
+		scheme, host, ok := strings.Cut(allowed, "://*.")
+		if !ok {
+			continue
+		}
+		prefix, suffix := scheme+"://", "."+host
+		if strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			sub := origin[len(prefix) : len(origin)-len(suffix)]
+			if sub != "" && !strings.ContainsAny(sub, "/:@") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setAllowOrigin echoes the origin, or "*" when any origin is allowed without credentials
+func setAllowOrigin(w http.ResponseWriter, policy config.CORSPolicy, origin string) {
+	if !policy.AllowCredentials && containsFold(policy.AllowedOrigins, "*") {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if policy.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
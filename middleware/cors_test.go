@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"go-backend/config"
+
+	"github.com/gorilla/mux"
+)
+
+func TestCORSResponsesVaryByOrigin(t *testing.T) {
+	router := mux.NewRouter()
+	cors := NewCORS(router, config.Default().CORS)
+	router.Use(cors.Middleware)
+	router.HandleFunc("/api/products", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET", "OPTIONS")
+
+	for name, origin := range map[string]string{"without an Origin": "", "with an Origin": "https://example.com"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/products", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if vary := rec.Header().Values("Vary"); !slices.Contains(vary, "Origin") {
+			t.Errorf("request %s: Vary = %q, want Origin", name, vary)
+		}
+	}
+}
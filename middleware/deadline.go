@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"go-backend/config"
+	"go-backend/metrics"
+	"go-backend/problem"
+)
+
+// Deadlines gives every request a time budget, set as the deadline of its
+// context. Handlers pass r.Context() down so database calls stop when the
+// budget runs out or the client goes away. The budgets can be swapped at
+// runtime.
+type Deadlines struct {
+	cfg atomic.Pointer[config.Timeouts]
+}
+
+// NewDeadlines creates deadline handling for the configured budgets
+func NewDeadlines(cfg config.Timeouts) *Deadlines {
+	d := &Deadlines{}
+	d.Update(cfg)
+	return d
+}
+
+// Update atomically replaces the configured budgets
+func (d *Deadlines) Update(cfg config.Timeouts) {
+	d.cfg.Store(&cfg)
+}
+
+// Budget returns the budget for a route, preferring "METHOD /path" over
+// "/path" over the default
+func (d *Deadlines) Budget(method, pathTemplate string) time.Duration {
+	cfg := d.cfg.Load()
+	if budget, ok := cfg.Routes[method+" "+pathTemplate]; ok {
+		return budget
+	}
+	if budget, ok := cfg.Routes[pathTemplate]; ok {
+		return budget
+	}
+	return cfg.Default
+}
+
+// Middleware applies the route's budget. Handlers report their own
+// timeouts; if one returns without writing anything after the deadline
+// passed, the client gets a 504.
+func (d *Deadlines) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), d.Budget(r.Method, routeTemplate(r)))
+		defer cancel()
+
+		rec := newResponseRecorder(w)
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		if !rec.wroteHeader && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			metrics.RequestTimeouts.Add(routeTemplate(r), 1)
+			problem.Write(w, r, http.StatusGatewayTimeout, "The request took too long to complete")
+		}
+	})
+}
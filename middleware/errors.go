@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"go-backend/db"
+	"go-backend/metrics"
+	"go-backend/problem"
+)
+
+// StatusClientClosedRequest is logged for requests the client abandoned
+// before a response was ready. Nobody is left to read it.
+const StatusClientClosedRequest = 499
+
+// WriteError answers a request whose storage call failed: 503 when the
+// database cannot be reached, 504 when the request's budget ran out and 500
+// with detail otherwise. Requests the client abandoned get no body.
+func WriteError(w http.ResponseWriter, r *http.Request, err error, detail string) {
+	switch {
+	case errors.Is(err, context.Canceled) && r.Context().Err() != nil:
+		w.WriteHeader(StatusClientClosedRequest)
+	case db.IsUnavailable(err):
+		retryAfter := int(math.Ceil(db.Breaker.RetryAfter().Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+		problem.Write(w, r, http.StatusServiceUnavailable, "The database is unavailable, try again later")
+	case db.IsTimeout(err):
+		metrics.RequestTimeouts.Add(routeTemplate(r), 1)
+		problem.Write(w, r, http.StatusGatewayTimeout, "The request took too long to complete")
+	default:
+		log.Printf("%s: %v", detail, err)
+		problem.Write(w, r, http.StatusInternalServerError, detail)
+	}
+}
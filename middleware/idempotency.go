@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"go-backend/auth"
+	"go-backend/idempotency"
+	"go-backend/metrics"
+	"go-backend/problem"
+)
+
+// maxIdempotencyKeyLength bounds the Idempotency-Key header
+const maxIdempotencyKeyLength = 255
+
+// NewIdempotencyMiddleware makes unsafe requests sent with an
+// Idempotency-Key header safe to retry. The first response to a key is
+// stored for ttl and replayed to later requests with the same key, marked
+// with Idempotent-Replayed. Keys are scoped to the user, or to the client
+// IP for anonymous requests, and bound to the method, URL, Content-Type,
+// Accept and body of the first request: reusing a key for a different
+// request, or for the same one in another format, gets 422, and
+// sending it again while the first request is running gets 409. Server
+// errors are not stored, so requests that failed can be retried.
+func NewIdempotencyMiddleware(store idempotency.Store, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("Idempotency-Key")
+			safe := r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions
+			if key == "" || safe {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !validIdempotencyKey(key) {
+				problem.Write(w, r, http.StatusBadRequest, "Idempotency-Key must be 1 to 255 printable ASCII characters")
+				return
+			}
+
+			// The body is part of the fingerprint; the handler reads it again
+			var body []byte
+			if r.Body != nil {
+				var err error
+				body, err = io.ReadAll(r.Body)
+				r.Body.Close()
+				if err != nil {
+					var tooLarge *http.MaxBytesError
+					if errors.As(err, &tooLarge) {
+						problem.Write(w, r, http.StatusRequestEntityTooLarge, TooLargeDetail(tooLarge.Limit))
+					} else {
+						problem.Write(w, r, http.StatusBadRequest, "Error reading request body")
+					}
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+			}
+
+			scope := "ip:" + ClientIP(r)
+			if userID := auth.UserID(r.Context()); userID != "" {
+				scope = "user:" + userID
+			}
+			key = scope + " " + key
+
+			stored, err := store.Begin(r.Context(), key, fingerprint(r, body), ttl)
+			switch {
+			case errors.Is(err, idempotency.ErrMismatch):
+				metrics.IdempotentRequests.Add("mismatch", 1)
+				problem.Write(w, r, http.StatusUnprocessableEntity,
+					"The Idempotency-Key was already used for a different request")
+				return
+			case errors.Is(err, idempotency.ErrInProgress):
+				metrics.IdempotentRequests.Add("in_progress", 1)
+				w.Header().Set("Retry-After", "1")
+				problem.Write(w, r, http.StatusConflict,
+					"A request with this Idempotency-Key is still being processed")
+				return
+			case err != nil:
+				WriteError(w, r, err, "Error checking the Idempotency-Key")
+				return
+			case stored != nil:
+				metrics.IdempotentRequests.Add("replayed", 1)
+				replay(w, stored)
+				return
+			}
+
+			// Release the key unless a response was stored, also when the
+			// handler panics, so the request can be retried
+			rec := &idempotencyRecorder{ResponseWriter: w, status: http.StatusOK, before: w.Header().Clone()}
+			completed := false
+			defer func() {
+				if !completed {
+					store.Release(context.WithoutCancel(r.Context()), key)
+				}
+			}()
+			next.ServeHTTP(rec, r)
+
+			// Nothing written means the request timed out or was abandoned
+			if !rec.wroteHeader || rec.status >= http.StatusInternalServerError || rec.status == StatusClientClosedRequest {
+				return
+			}
+			response := &idempotency.Response{Status: rec.status, Header: rec.header, Body: rec.body.Bytes()}
+			if err := store.Complete(context.WithoutCancel(r.Context()), key, response, ttl); err != nil {
+				return
+			}
+			completed = true
+			metrics.IdempotentRequests.Add("stored", 1)
+		})
+	}
+}
+
+// validIdempotencyKey reports whether key is short printable ASCII
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := range len(key) {
+		if key[i] < ' ' || key[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// fingerprint identifies a request by its method, URL, body and the media
+// types it is sent and answered in, as a stored response only fits the
+// format it was negotiated in
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	io.WriteString(h, "Content-Type: "+r.Header.Get("Content-Type")+"\n")
+	io.WriteString(h, "Accept: "+r.Header.Get("Accept")+"\n\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replay sends a stored response. Its headers are those the handler set,
+// so the ones set for this request, like X-Request-ID and the rate limit
+// headers, are kept.
+func replay(w http.ResponseWriter, stored *idempotency.Response) {
+	h := w.Header()
+	for name, values := range stored.Header {
+		h[name] = values
+	}
+	h.Set("Idempotent-Replayed", "true")
+	w.WriteHeader(stored.Status)
+	w.Write(stored.Body)
+}
+
+// idempotencyRecorder passes a response through while keeping a copy of
+// its status, body and the headers that changed since before, which were
+// set before the handler ran
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	before      http.Header
+	status      int
+	wroteHeader bool
+	header      http.Header
+	body        bytes.Buffer
+}
+
+func (rec *idempotencyRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader && status >= http.StatusOK {
+		rec.status = status
+		rec.wroteHeader = true
+		rec.header = make(http.Header)
+		for name, values := range rec.Header() {
+			if !slices.Equal(values, rec.before[name]) {
+				rec.header[name] = slices.Clone(values)
+			}
+		}
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *idempotencyRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rec *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-backend/idempotency"
+)
+
+func TestIdempotencyKeyIsBoundToTheFormat(t *testing.T) {
+	var calls atomic.Int32
+	handler := NewIdempotencyMiddleware(idempotency.NewMemoryStore(), time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", r.Header.Get("Accept"))
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	send := func(contentType, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v2/products", strings.NewReader(`{"name":"Chair"}`))
+		req.Header.Set("Idempotency-Key", "key-1")
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := send("application/json", "application/json"); rec.Code != http.StatusCreated {
+		t.Fatalf("first request: status %d, want %d", rec.Code, http.StatusCreated)
+	}
+	rec := send("application/json", "application/json")
+	if rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry: status %d, replayed %q, want the stored 201", rec.Code, rec.Header().Get("Idempotent-Replayed"))
+	}
+	if rec := send("application/json", "application/xml"); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("retry with another Accept: status %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+	if rec := send("application/msgpack", "application/json"); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("retry with another Content-Type: status %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want once", n)
+	}
+}
@@ -12,52 +12,17 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 		start := time.Now()
 
 		// Call the next handler
-		next.ServeHTTP(w, r)
+		rec := newResponseRecorder(w)
+		next.ServeHTTP(rec, r)
 
 		// Log the request
 		log.Printf(
-			"%s %s %s %s",
+			"%s %s %s %d %s",
 			r.Method,
 			r.RequestURI,
 			r.RemoteAddr,
+			rec.status,
 			time.Since(start),
 		)
 	})
 }
-
-// JSONContentTypeMiddleware sets the content type header to application/json
-func JSONContentTypeMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Add("Content-Type", "application/json")
-		next.ServeHTTP(w, r)
-	})
-}
-
-// CORSMiddleware adds CORS headers to requests
-func CORSMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-
-		log.Printf("CORS middleware processing: %s %s", r.Method, r.URL.Path)
-
-		origin := r.Header.Get("Origin")
-		if origin == "" {
-			origin = "*"
-		}
-
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", origin) // Need to set this for production. Just for the interview, I have set it to *
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-		w.Header().Set("Access-Control-Max-Age", "3600")
-
-		// If it's a preflight request, just return
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		// Continue to the next handler
-		next.ServeHTTP(w, r)
-	})
-}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"go-backend/codec"
+	"go-backend/openapi"
+	"go-backend/problem"
+)
+
+// NewNegotiationMiddleware picks the response codec from the Accept header
+// among the media types spec documents for the matched route's successful
+// responses, and refuses requests it cannot answer acceptably with 406.
+// Handlers write with the chosen codec through codec.Write. Routes without
+// an operation, or with a single media type, are left to their handlers.
+func NewNegotiationMiddleware(spec *openapi.Document) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			offered := offeredCodecs(spec.Operation(r.Method, routeTemplate(r)))
+			if len(offered) < 2 || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Accept")
+			c := codec.Negotiate(r.Header.Get("Accept"), offered)
+			if c == nil {
+				types := make([]string, len(offered))
+				for i, c := range offered {
+					types[i] = c.MediaType
+				}
+				problem.Write(w, r, http.StatusNotAcceptable,
+					"None of the media types in Accept can be produced, available are "+strings.Join(types, ", "))
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(codec.NewContext(r.Context(), c)))
+		})
+	}
+}
+
+// offeredCodecs returns the codecs of the media types op documents for its
+// 2xx responses, in the order of preference of the codecs
+func offeredCodecs(op *openapi.Operation) []*codec.Codec {
+	if op == nil {
+		return nil
+	}
+	var offered []*codec.Codec
+	for _, c := range codec.All() {
+		for status, response := range op.Responses {
+			if _, ok := response.Content[c.MediaType]; ok && strings.HasPrefix(status, "2") {
+				offered = append(offered, c)
+				break
+			}
+		}
+	}
+	return offered
+}
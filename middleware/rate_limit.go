@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go-backend/config"
+	"go-backend/problem"
+	"go-backend/ratelimit"
+
+	"github.com/gorilla/mux"
+)
+
+// RateLimiter applies token bucket limits per route and client. The limits
+// can be swapped at runtime.
+type RateLimiter struct {
+	store     ratelimit.Store
+	cfg       atomic.Pointer[config.RateLimits]
+	overrides map[string]string
+
+	// UserID identifies the authenticated user for RateLimitByUser policies
+	UserID func(r *http.Request) string
+	// APIKey identifies the request's authenticated API key for
+	// RateLimitByAPIKey policies, or returns "" when the key is missing or
+	// invalid. Without it those policies key by client address, since an
+	// unchecked X-API-Key header would give every made-up key a new bucket.
+	APIKey func(r *http.Request) string
+}
+
+// NewRateLimiter creates a rate limiter backed by store
+func NewRateLimiter(store ratelimit.Store, cfg config.RateLimits) *RateLimiter {
+	l := &RateLimiter{
+		store:     store,
+		overrides: make(map[string]string),
+	}
+	l.Update(cfg)
+	return l
+}
+
+// Update atomically replaces the limits
+func (l *RateLimiter) Update(cfg config.RateLimits) {
+	l.cfg.Store(&cfg)
+}
+
+// Override applies the named policy ("read", "write" or "auth") to the route
+// with the given path template instead of the method-based default.
+// Overrides must be registered before serving starts.
+func (l *RateLimiter) Override(pathTemplate, policyName string) {
+	l.overrides[pathTemplate] = policyName
+}
+
+// Middleware rejects requests over the limit with 429 and reports the
+// bucket state in RateLimit-* headers
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := l.cfg.Load()
+		if !cfg.Enabled || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		policy := l.policyFor(r, cfg)
+		key := policy.Name + "|" + l.key(r, policy)
+
+		result, err := l.store.Take(r.Context(), key, policy.Limit)
+		if err != nil {
+			// Fail open: a broken limiter store should not take the API down
+			log.Printf("Rate limiter store error: %v", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit.Requests, int(policy.Limit.Per.Seconds())))
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			problem.Write(w, r, http.StatusTooManyRequests, "Rate limit exceeded, retry later")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// policyFor returns the route's override, or the default for the method
+func (l *RateLimiter) policyFor(r *http.Request, cfg *config.RateLimits) config.RateLimitPolicy {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			switch l.overrides[tmpl] {
+			case cfg.Read.Name:
+				return cfg.Read
+			case cfg.Write.Name:
+				return cfg.Write
+			case cfg.Auth.Name:
+				return cfg.Auth
+			}
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		return cfg.Read
+	default:
+		return cfg.Write
+	}
+}
+
+// key identifies the client a bucket belongs to
+func (l *RateLimiter) key(r *http.Request, policy config.RateLimitPolicy) string {
+	switch policy.Key {
+	case config.RateLimitByUser:
+		if l.UserID != nil {
+			if id := l.UserID(r); id != "" {
+				return "user:" + id
+			}
+		}
+	case config.RateLimitByAPIKey:
+		if l.APIKey != nil {
+			if apiKey := l.APIKey(r); apiKey != "" {
+				sum := sha256.Sum256([]byte(apiKey))
+				return "key:" + hex.EncodeToString(sum[:8])
+			}
+		}
+	}
+	return "ip:" + ClientIP(r)
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
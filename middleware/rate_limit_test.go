@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"go-backend/config"
+	"go-backend/ratelimit"
+)
+
+func TestUncheckedAPIKeysShareTheAddressBucket(t *testing.T) {
+	cfg := config.Default().Limits.RateLimit
+	cfg.Enabled = true
+	cfg.Read.Limit = ratelimit.Limit{Requests: 2, Per: time.Minute, Burst: 2}
+	cfg.Read.Key = config.RateLimitByAPIKey
+
+	send := func(l *RateLimiter, apiKey string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/products", nil)
+		req.Header.Set("X-API-Key", apiKey)
+		rec := httptest.NewRecorder()
+		l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// A new made-up key per request does not reset the limit
+	l := NewRateLimiter(ratelimit.NewMemoryStore(), cfg)
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if code := send(l, "made-up-"+strconv.Itoa(i)); code != want {
+			t.Errorf("request %d: status %d, want %d", i, code, want)
+		}
+	}
+
+	// Authenticated keys get their own buckets
+	l = NewRateLimiter(ratelimit.NewMemoryStore(), cfg)
+	l.APIKey = func(r *http.Request) string { return r.Header.Get("X-API-Key") }
+	for i := range 3 {
+		if code := send(l, "key-"+strconv.Itoa(i)); code != http.StatusOK {
+			t.Errorf("request with key %d: status %d, want %d", i, code, http.StatusOK)
+		}
+	}
+}
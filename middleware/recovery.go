@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"go-backend/metrics"
+	"go-backend/problem"
+	"go-backend/requestid"
+
+	"github.com/gorilla/mux"
+)
+
+// NewRecoveryMiddleware turns handler panics into a 500 problem response.
+// The panic is logged with its stack and counted per route. With repanic set
+// the panic is re-raised after logging so it surfaces during development.
+func NewRecoveryMiddleware(repanic bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return recoveryHandler(next, repanic)
+	}
+}
+
+func recoveryHandler(next http.Handler, repanic bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := newResponseRecorder(w)
+
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+
+			// Deliberate aborts are handled by net/http itself
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+
+			route := routeTemplate(r)
+			metrics.Panics.Add(route, 1)
+
+			slog.Error("panic recovered",
+				"request_id", requestid.FromContext(r.Context()),
+				"method", r.Method,
+				"path", r.URL.Path,
+				"route", route,
+				"panic", fmt.Sprint(err),
+				"stack", string(debug.Stack()),
+			)
+
+			if repanic {
+				panic(err)
+			}
+
+			// Too late for a clean error if the handler already started writing
+			if rec.wroteHeader {
+				return
+			}
+
+			problem.Write(w, r, http.StatusInternalServerError, "An unexpected error occurred")
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// routeTemplate returns the mux path template of the matched route
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+	return "unmatched"
+}
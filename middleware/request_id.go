@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go-backend/requestid"
+)
+
+// RequestIDMiddleware assigns every request an ID, reusing the caller's
+// X-Request-ID when present, and echoes it back in the response
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if id == "" || len(id) > 128 {
+			id = requestid.New()
+		}
+
+		w.Header().Set(requestid.Header, id)
+		next.ServeHTTP(w, r.WithContext(requestid.NewContext(r.Context(), id)))
+	})
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"go-backend/config"
+	"go-backend/metrics"
+	"go-backend/openapi"
+	"go-backend/problem"
+	"go-backend/requestid"
+
+	"github.com/gorilla/mux"
+)
+
+// NewValidationMiddleware checks requests against the operation spec
+// documents for the matched route before the handler runs. Mismatches are
+// rejected with a 400 problem listing every invalid parameter and field,
+// and bodies of an undocumented content type with 415. With
+// cfg.Responses set, responses are checked too and replaced with a 500
+// when they drift from the document. Routes without an operation and
+// OPTIONS requests pass through.
+func NewValidationMiddleware(spec *openapi.Document, cfg config.Validation) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !cfg.Requests && !cfg.Responses {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+			route := routeTemplate(r)
+			op := spec.Operation(r.Method, route)
+			if op == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if cfg.Requests && !validateRequest(w, r, spec, op, route) {
+				return
+			}
+			if !cfg.Responses {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			buf := newBufferedResponse(w)
+			next.ServeHTTP(buf, r)
+			validateResponse(w, r, spec, op, route, buf)
+		})
+	}
+}
+
+// validateRequest reports whether r matches op, answering it otherwise.
+// The body is read and replaced so the handler can decode it again.
+func validateRequest(w http.ResponseWriter, r *http.Request, spec *openapi.Document, op *openapi.Operation, route string) bool {
+	var body []byte
+	if op.RequestBody != nil && r.Body != nil {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				problem.Write(w, r, http.StatusRequestEntityTooLarge, TooLargeDetail(tooLarge.Limit))
+			} else {
+				problem.Write(w, r, http.StatusBadRequest, "Error reading request body")
+			}
+			return false
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	err := spec.ValidateRequest(op, r, mux.Vars(r), body)
+	if err == nil {
+		return true
+	}
+	metrics.ValidationFailures.Add("request "+route, 1)
+
+	var invalid *openapi.ValidationError
+	if !errors.As(err, &invalid) {
+		p := problem.New(r, http.StatusUnsupportedMediaType, "Unsupported Content-Type, expected one of "+mediaTypes(op.RequestBody))
+		problem.WriteProblem(w, p)
+		return false
+	}
+	p := problem.New(r, http.StatusBadRequest, "The request does not match the API description: "+err.Error())
+	for _, i := range invalid.Invalid {
+		p.InvalidParams = append(p.InvalidParams, problem.InvalidParam{In: i.In, Name: i.Name, Reason: i.Reason})
+	}
+	problem.WriteProblem(w, p)
+	return false
+}
+
+// validateResponse sends the buffered response if it matches op, and a 500
+// problem otherwise
+func validateResponse(w http.ResponseWriter, r *http.Request, spec *openapi.Document, op *openapi.Operation, route string, buf *bufferedResponse) {
+	err := spec.ValidateResponse(op, buf.status, w.Header().Get("Content-Type"), buf.body.Bytes())
+	if err != nil {
+		metrics.ValidationFailures.Add("response "+route, 1)
+		slog.Error("response does not match the API description",
+			"request_id", requestid.FromContext(r.Context()),
+			"method", r.Method,
+			"route", route,
+			"status", buf.status,
+			"error", err,
+		)
+		problem.Write(w, r, http.StatusInternalServerError,
+			fmt.Sprintf("The %d response does not match the API description: %v", buf.status, err))
+		return
+	}
+
+	w.WriteHeader(buf.status)
+	w.Write(buf.body.Bytes())
+}
+
+func mediaTypes(rb *openapi.RequestBody) string {
+	return strings.Join(slices.Sorted(maps.Keys(rb.Content)), ", ")
+}
+
+// bufferedResponse holds a response back until it has been validated.
+// Headers go straight to the underlying writer's header map.
+type bufferedResponse struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func newBufferedResponse(w http.ResponseWriter) *bufferedResponse {
+	return &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if !b.wroteHeader {
+		b.status = status
+		b.wroteHeader = true
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(p)
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-backend/metrics"
+)
+
+// API versions
+const (
+	APIv1 = 1
+	APIv2 = 2
+)
+
+type apiVersionKey struct{}
+
+// APIVersion returns the API version of the matched route, or 0 for routes
+// outside the versioned API
+func APIVersion(ctx context.Context) int {
+	version, _ := ctx.Value(apiVersionKey{}).(int)
+	return version
+}
+
+// APIVersionInfo describes one version of the API
+type APIVersionInfo struct {
+	Version int
+	Prefix  string // path prefix of the version's routes, e.g. /api/v2
+
+	// A deprecated version announces its replacement with the Deprecation,
+	// Sunset and Link headers. Zero dates are not announced.
+	Deprecated time.Time
+	Sunset     time.Time
+	Successor  string // path prefix of the replacing version
+}
+
+// Middleware records the API version in the request context, counts
+// requests per version and route and announces a deprecated version's
+// sunset
+func (v APIVersionInfo) Middleware(next http.Handler) http.Handler {
+	name := fmt.Sprintf("v%d", v.Version)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metrics.APIRequests.Add(name+" "+r.Method+" "+routeTemplate(r), 1)
+
+		h := w.Header()
+		if !v.Deprecated.IsZero() {
+			// RFC 9745 structured date
+			h.Set("Deprecation", fmt.Sprintf("@%d", v.Deprecated.Unix()))
+		}
+		if !v.Sunset.IsZero() {
+			// RFC 8594
+			h.Set("Sunset", v.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if v.Successor != "" && (!v.Deprecated.IsZero() || !v.Sunset.IsZero()) {
+			successor := v.Successor + strings.TrimPrefix(r.URL.Path, v.Prefix)
+			h.Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		}
+
+		ctx := context.WithValue(r.Context(), apiVersionKey{}, v.Version)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
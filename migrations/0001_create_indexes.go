@@ -0,0 +1,87 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// initialIndexes are the indexes that used to be created on every boot
+var initialIndexes = []struct {
+	collection string
+	models     []mongo.IndexModel
+}{
+	{"categories", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+	}},
+	{"products", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "category_id", Value: 1}}},
+		{Keys: bson.D{{Key: "category_group", Value: 1}}},
+	}},
+	{"users", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+	}},
+	// Expire sessions once they are past their expiry time
+	{"sessions", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	}},
+	// Forget stale login attempt counters after a day
+	{"login_attempts", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "updated_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(24 * 60 * 60)},
+	}},
+	// Auth events are queried by user or email over a time range
+	{"auth_events", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "email", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+	}},
+}
+
+// createIndexesUp creates the initial indexes. Indexes that already exist
+// from earlier boots are left as they are.
+func createIndexesUp(ctx context.Context, db *mongo.Database) error {
+	for _, group := range initialIndexes {
+		if _, err := db.Collection(group.collection).Indexes().CreateMany(ctx, group.models); err != nil {
+			return fmt.Errorf("%s: %w", group.collection, err)
+		}
+	}
+	return nil
+}
+
+// createIndexesDown drops the initial indexes
+func createIndexesDown(ctx context.Context, db *mongo.Database) error {
+	for _, group := range initialIndexes {
+		for _, model := range group.models {
+			name := indexName(model.Keys.(bson.D))
+			if _, err := db.Collection(group.collection).Indexes().DropOne(ctx, name); err != nil && !isIndexNotFound(err) {
+				return fmt.Errorf("%s.%s: %w", group.collection, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// indexName returns the name the server gives an index on keys
+func indexName(keys bson.D) string {
+	parts := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		parts = append(parts, key.Key, fmt.Sprint(key.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+// isIndexNotFound reports whether err is the server's IndexNotFound error
+func isIndexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Code == 27 || cmdErr.Name == "IndexNotFound"
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// addProductVersionUp starts every existing product at version 1
+func addProductVersionUp(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("products").UpdateMany(ctx,
+		bson.M{"version": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"version": 1}},
+	)
+	return err
+}
+
+// addProductVersionDown removes the version field
+func addProductVersionDown(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("products").UpdateMany(ctx,
+		bson.M{},
+		bson.M{"$unset": bson.M{"version": ""}},
+	)
+	return err
+}
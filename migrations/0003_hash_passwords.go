@@ -0,0 +1,56 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go-backend/auth"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// hashPasswordsUp replaces plaintext passwords with salted hashes. Users
+// that already have a hash are skipped, so the migration can be rerun
+// after a failure.
+func hashPasswordsUp(ctx context.Context, db *mongo.Database) error {
+	users := db.Collection("users")
+
+	cursor, err := users.Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var user struct {
+			ID       string `bson:"id"`
+			Password string `bson:"password"`
+		}
+		if err := cursor.Decode(&user); err != nil {
+			return err
+		}
+		if auth.IsPasswordHash(user.Password) {
+			continue
+		}
+
+		hash, err := auth.HashPassword(user.Password)
+		if err != nil {
+			return err
+		}
+		// Match the old value so a concurrent password change is not lost
+		_, err = users.UpdateOne(ctx,
+			bson.M{"id": user.ID, "password": user.Password},
+			bson.M{"$set": bson.M{"password": hash}},
+		)
+		if err != nil {
+			return fmt.Errorf("user %s: %w", user.ID, err)
+		}
+	}
+	return cursor.Err()
+}
+
+// hashPasswordsDown cannot recover the plaintext passwords
+func hashPasswordsDown(ctx context.Context, db *mongo.Database) error {
+	return ErrIrreversible
+}
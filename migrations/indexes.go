@@ -0,0 +1,115 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Index change actions
+const (
+	IndexCreate   = "create"   // declared but missing
+	IndexRecreate = "recreate" // keys or options differ from the declaration
+	IndexExtra    = "extra"    // in the database but not declared; kept
+)
+
+// IndexChange is a difference between the declared indexes and the
+// database's
+type IndexChange struct {
+	Collection string `json:"collection"`
+	Index      string `json:"index"`
+	Action     string `json:"action"`
+}
+
+// liveIndex is an index as listed by the server
+type liveIndex struct {
+	Name               string `bson:"name"`
+	Keys               bson.D `bson:"key"`
+	Unique             bool   `bson:"unique"`
+	ExpireAfterSeconds *int64 `bson:"expireAfterSeconds"`
+}
+
+// SyncIndexes compares the indexes declared by the migrations with the
+// ones in the database. Missing indexes are created, and indexes whose
+// keys or options were changed by hand are dropped and created again.
+// Undeclared indexes are reported but kept. With dryRun nothing changes.
+func SyncIndexes(ctx context.Context, db *mongo.Database, dryRun bool) ([]IndexChange, error) {
+	var changes []IndexChange
+	for _, group := range initialIndexes {
+		indexes := db.Collection(group.collection).Indexes()
+		cursor, err := indexes.List(ctx)
+		if err != nil {
+			return changes, fmt.Errorf("%s: %w", group.collection, err)
+		}
+		var live []liveIndex
+		if err := cursor.All(ctx, &live); err != nil {
+			return changes, fmt.Errorf("%s: %w", group.collection, err)
+		}
+
+		for _, change := range diffIndexes(group.collection, group.models, live) {
+			changes = append(changes, change)
+			if dryRun || change.Action == IndexExtra {
+				continue
+			}
+			if change.Action == IndexRecreate {
+				if _, err := indexes.DropOne(ctx, change.Index); err != nil && !isIndexNotFound(err) {
+					return changes, fmt.Errorf("%s.%s: %w", group.collection, change.Index, err)
+				}
+			}
+			model := group.models[slices.IndexFunc(group.models, func(m mongo.IndexModel) bool {
+				return indexName(m.Keys.(bson.D)) == change.Index
+			})]
+			if _, err := indexes.CreateOne(ctx, model); err != nil {
+				return changes, fmt.Errorf("%s.%s: %w", group.collection, change.Index, err)
+			}
+		}
+	}
+	return changes, nil
+}
+
+// diffIndexes lists what it takes to turn live into declared
+func diffIndexes(collection string, declared []mongo.IndexModel, live []liveIndex) []IndexChange {
+	var changes []IndexChange
+	names := make(map[string]bool, len(declared))
+	for _, model := range declared {
+		name := indexName(model.Keys.(bson.D))
+		names[name] = true
+
+		i := slices.IndexFunc(live, func(index liveIndex) bool { return index.Name == name })
+		switch {
+		case i < 0:
+			changes = append(changes, IndexChange{collection, name, IndexCreate})
+		case !matches(model, live[i]):
+			changes = append(changes, IndexChange{collection, name, IndexRecreate})
+		}
+	}
+	for _, index := range live {
+		if index.Name != "_id_" && !names[index.Name] {
+			changes = append(changes, IndexChange{collection, index.Name, IndexExtra})
+		}
+	}
+	return changes
+}
+
+// matches reports whether a live index has the declared keys and options
+func matches(model mongo.IndexModel, index liveIndex) bool {
+	if indexName(model.Keys.(bson.D)) != indexName(index.Keys) {
+		return false
+	}
+	unique := false
+	var ttl *int64
+	if opts := model.Options; opts != nil {
+		unique = opts.Unique != nil && *opts.Unique
+		if opts.ExpireAfterSeconds != nil {
+			seconds := int64(*opts.ExpireAfterSeconds)
+			ttl = &seconds
+		}
+	}
+	if unique != index.Unique || (ttl == nil) != (index.ExpireAfterSeconds == nil) {
+		return false
+	}
+	return ttl == nil || *ttl == *index.ExpireAfterSeconds
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestDiffIndexes(t *testing.T) {
+	declared := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+	}
+	zero := int64(0)
+	live := []liveIndex{
+		{Name: "_id_", Keys: bson.D{{Key: "_id", Value: int32(1)}}},
+		// Lost its unique option
+		{Name: "email_1", Keys: bson.D{{Key: "email", Value: int32(1)}}},
+		{Name: "expires_at_1", Keys: bson.D{{Key: "expires_at", Value: int32(1)}}, ExpireAfterSeconds: &zero},
+		// user_id_1 was dropped
+		{Name: "created_at_-1", Keys: bson.D{{Key: "created_at", Value: float64(-1)}}},
+		{Name: "name_1", Keys: bson.D{{Key: "name", Value: int32(1)}}},
+	}
+
+	want := []IndexChange{
+		{"users", "email_1", IndexRecreate},
+		{"users", "user_id_1", IndexCreate},
+		{"users", "name_1", IndexExtra},
+	}
+	if got := diffIndexes("users", declared, live); !slices.Equal(got, want) {
+		t.Errorf("diffIndexes = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,330 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collections used to track migrations
+const (
+	migrationsCollection = "schema_migrations"
+	lockCollection       = "schema_migrations_lock"
+)
+
+var (
+	// ErrLocked is returned when another runner holds the migration lock
+	ErrLocked = errors.New("migrations are locked by another runner")
+	// ErrIrreversible is returned by Down functions that cannot undo their migration
+	ErrIrreversible = errors.New("migration cannot be reverted")
+)
+
+// Migration is one versioned change to the database. Versions must be
+// unique and migrations run in ascending version order.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+	Down    func(ctx context.Context, db *mongo.Database) error
+}
+
+// All lists the application's migrations in order. Add new migrations at
+// the end; never change one that has been released.
+var All = []Migration{
+	{1, "create_indexes", createIndexesUp, createIndexesDown},
+	{2, "add_product_version", addProductVersionUp, addProductVersionDown},
+	{3, "hash_passwords", hashPasswordsUp, hashPasswordsDown},
+}
+
+// record is a document in schema_migrations
+type record struct {
+	Version   int64         `bson:"_id"`
+	Name      string        `bson:"name"`
+	AppliedAt time.Time     `bson:"applied_at"`
+	Duration  time.Duration `bson:"duration"`
+}
+
+// Status describes whether a migration has been applied
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// Options control an up or down run
+type Options struct {
+	// Target is the version to migrate to; 0 means all the way for up and
+	// is ignored for down when Steps is set
+	Target int64
+	// Steps limits how many migrations down reverts (default 1)
+	Steps int
+	// DryRun reports the plan without running anything
+	DryRun bool
+}
+
+// Migrator runs migrations against a database
+type Migrator struct {
+	db         *mongo.Database
+	migrations []Migration
+
+	// LockTTL is how long the lock is held without being refreshed before
+	// another runner may take it over
+	LockTTL time.Duration
+	// Log receives progress messages, if set
+	Log func(format string, args ...any)
+}
+
+// New creates a migrator for the given migrations, usually All
+func New(db *mongo.Database, migrations []Migration) (*Migrator, error) {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i, m := range sorted {
+		if m.Version <= 0 || m.Name == "" || m.Up == nil {
+			return nil, fmt.Errorf("migration %d %q: version, name and up are required", m.Version, m.Name)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("duplicate migration version %d", m.Version)
+		}
+	}
+	return &Migrator{db: db, migrations: sorted, LockTTL: 10 * time.Minute}, nil
+}
+
+// Status lists every known migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if rec, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &rec.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Pending lists the migrations that have not been applied
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies pending migrations up to opts.Target and returns the ones run,
+// or the ones that would run for a dry run
+func (m *Migrator) Up(ctx context.Context, opts Options) ([]Migration, error) {
+	return m.run(ctx, opts, func(ctx context.Context) ([]Migration, error) {
+		pending, err := m.Pending(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var plan []Migration
+		for _, migration := range pending {
+			if opts.Target > 0 && migration.Version > opts.Target {
+				break
+			}
+			plan = append(plan, migration)
+		}
+		return plan, nil
+	}, m.apply)
+}
+
+// Down reverts the most recent migrations, either opts.Steps of them
+// (default 1) or all those above opts.Target
+func (m *Migrator) Down(ctx context.Context, opts Options) ([]Migration, error) {
+	return m.run(ctx, opts, func(ctx context.Context) ([]Migration, error) {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		steps := opts.Steps
+		if steps <= 0 && opts.Target == 0 {
+			steps = 1
+		}
+
+		var plan []Migration
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if opts.Target > 0 && migration.Version <= opts.Target {
+				break
+			}
+			if steps > 0 && len(plan) == steps {
+				break
+			}
+			plan = append(plan, migration)
+		}
+		return plan, nil
+	}, m.revert)
+}
+
+// run plans and executes migrations while holding the lock
+func (m *Migrator) run(ctx context.Context, opts Options, planFn func(context.Context) ([]Migration, error), step func(context.Context, Migration) error) ([]Migration, error) {
+	if opts.DryRun {
+		return planFn(ctx)
+	}
+
+	release, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Plan under the lock so a concurrent runner's work is seen
+	plan, err := planFn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, migration := range plan {
+		if err := step(ctx, migration); err != nil {
+			return plan[:i], fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+	}
+	return plan, nil
+}
+
+// apply runs one migration up and records it
+func (m *Migrator) apply(ctx context.Context, migration Migration) error {
+	m.logf("Applying migration %d %s", migration.Version, migration.Name)
+	start := time.Now()
+	if err := migration.Up(ctx, m.db); err != nil {
+		return err
+	}
+
+	_, err := m.db.Collection(migrationsCollection).InsertOne(ctx, record{
+		Version:   migration.Version,
+		Name:      migration.Name,
+		AppliedAt: time.Now().UTC(),
+		Duration:  time.Since(start),
+	})
+	return err
+}
+
+// revert runs one migration down and removes its record
+func (m *Migrator) revert(ctx context.Context, migration Migration) error {
+	if migration.Down == nil {
+		return ErrIrreversible
+	}
+
+	m.logf("Reverting migration %d %s", migration.Version, migration.Name)
+	if err := migration.Down(ctx, m.db); err != nil {
+		return err
+	}
+
+	_, err := m.db.Collection(migrationsCollection).DeleteOne(ctx, bson.M{"_id": migration.Version})
+	return err
+}
+
+// applied returns the applied migrations keyed by version
+func (m *Migrator) applied(ctx context.Context) (map[int64]record, error) {
+	cursor, err := m.db.Collection(migrationsCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]record, len(records))
+	for _, rec := range records {
+		applied[rec.Version] = rec
+	}
+	return applied, nil
+}
+
+// lock takes the migration lock, or takes over one that has expired. The
+// lock is refreshed until release is called.
+func (m *Migrator) lock(ctx context.Context) (release func(), err error) {
+	collection := m.db.Collection(lockCollection)
+	owner := lockOwner()
+
+	now := time.Now()
+	_, err = collection.UpdateOne(ctx,
+		bson.M{"_id": "lock", "expires_at": bson.M{"$lt": now}},
+		bson.M{"$set": bson.M{"owner": owner, "acquired_at": now, "expires_at": now.Add(m.LockTTL)}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		// The lock exists and has not expired
+		var holder struct {
+			Owner     string    `bson:"owner"`
+			ExpiresAt time.Time `bson:"expires_at"`
+		}
+		if collection.FindOne(ctx, bson.M{"_id": "lock"}).Decode(&holder) == nil {
+			return nil, fmt.Errorf("%w (%s, expires %s)", ErrLocked, holder.Owner, holder.ExpiresAt.Format(time.RFC3339))
+		}
+		return nil, ErrLocked
+	}
+	if err != nil {
+		return nil, fmt.Errorf("acquiring migration lock: %w", err)
+	}
+
+	// Keep the lock alive during long migrations
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(m.LockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				collection.UpdateOne(context.Background(),
+					bson.M{"_id": "lock", "owner": owner},
+					bson.M{"$set": bson.M{"expires_at": time.Now().Add(m.LockTTL)}},
+				)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := collection.DeleteOne(ctx, bson.M{"_id": "lock", "owner": owner}); err != nil {
+			m.logf("Failed to release migration lock: %v", err)
+		}
+	}, nil
+}
+
+func (m *Migrator) logf(format string, args ...any) {
+	if m.Log != nil {
+		m.Log(format, args...)
+	}
+}
+
+// lockOwner identifies this process in the lock document
+func lockOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d:%d", host, os.Getpid(), time.Now().UnixNano())
+}
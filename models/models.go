@@ -1,29 +1,39 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go-backend/config"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 // Category represents a product category
 type Category struct {
-	ID       string  `json:"id" bson:"id"`
-	Name     string  `json:"name" bson:"name"`
-	ParentID *string `json:"parent_id" bson:"parent_id,omitempty"`
+	ID       string  `json:"id" bson:"id" openapi:"required"`
+	Name     string  `json:"name" bson:"name" openapi:"required"`
+	ParentID *string `json:"parent_id" bson:"parent_id,omitempty" doc:"Parent category, null for top-level categories"`
 }
 
 // Attribute represents a product attribute with dynamic type
 type Attribute struct {
-	Code  string      `json:"code" bson:"code"`
+	Code  string      `json:"code" bson:"code" openapi:"required,minLength=1"`
 	Value interface{} `json:"value" bson:"value"`
-	Type  string      `json:"type" bson:"type"`
+	Type  string      `json:"type" bson:"type" doc:"Type of value, e.g. string, number or boolean"`
 	Label string      `json:"label" bson:"label"` // Human-readable label for the attribute
 }
 
 // Product represents a product
 type Product struct {
-	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Name          string             `json:"name" bson:"name"`
-	CategoryID    string             `json:"category_id" bson:"category_id"`
-	CategoryGroup string             `json:"category_group" bson:"category_group"`
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty" openapi:"readonly"`
+	Name          string             `json:"name" bson:"name" openapi:"required,minLength=1"`
+	CategoryID    string             `json:"category_id" bson:"category_id" openapi:"required,minLength=1"`
+	CategoryGroup string             `json:"category_group" bson:"category_group" doc:"Top-level category of category_id"`
 	Attributes    []Attribute        `json:"attributes" bson:"attributes"`
+	Version       int64              `json:"version" bson:"version" openapi:"readonly" doc:"Incremented on every update"`
 }
 
 // PaginationParams represents parameters for pagination and filtering
@@ -40,8 +50,43 @@ type PaginationParams struct {
 
 // ProductsResponse represents the response for paginated products
 type ProductsResponse struct {
-	Products []Product `json:"products"`
-	Total    int64     `json:"total"`
+	Products []Product `json:"products" openapi:"required"`
+	Total    int64     `json:"total" openapi:"required" doc:"Number of products matching the filters"`
+}
+
+// Pagination describes where a page lies within all matching items
+type Pagination struct {
+	Page       int   `json:"page" openapi:"required"`
+	PageSize   int   `json:"page_size" openapi:"required"`
+	Offset     int   `json:"offset" openapi:"required" doc:"Number of items before this page"`
+	Total      int64 `json:"total" openapi:"required" doc:"Number of items matching the filters"`
+	TotalPages int   `json:"total_pages" openapi:"required"`
+	HasMore    bool  `json:"has_more" openapi:"required" doc:"Whether items follow this page"`
+}
+
+// NewPagination describes the page of count items starting at params.Start
+// out of total matching items
+func NewPagination(params PaginationParams, count int, total int64) Pagination {
+	pageSize := max(params.Limit, 1)
+	return Pagination{
+		Page:       params.Start/pageSize + 1,
+		PageSize:   pageSize,
+		Offset:     params.Start,
+		Total:      total,
+		TotalPages: int((total + int64(pageSize) - 1) / int64(pageSize)),
+		HasMore:    int64(params.Start+count) < total,
+	}
+}
+
+// ProductPage represents one page of products with its position (API v2)
+type ProductPage struct {
+	Products   []Product  `json:"products" openapi:"required,nonnull"`
+	Pagination Pagination `json:"pagination" openapi:"required"`
+}
+
+// UserList represents a list of users (API v2)
+type UserList struct {
+	Users []UserResponse `json:"users" openapi:"required,nonnull"`
 }
 
 // ErrorResponse represents an error response
@@ -53,15 +98,106 @@ type ErrorResponse struct {
 type User struct {
 	ID       string `json:"id" bson:"id"`
 	Email    string `json:"email" bson:"email"`
-	Password string `json:"password" bson:"password"` // In production, store hashed passwords
+	Password string `json:"password" bson:"password"` // hash from auth.HashPassword
 	Name     string `json:"name" bson:"name"`
 	Role     string `json:"role" bson:"role"`
 }
 
 // UserResponse represents a user response without sensitive data
 type UserResponse struct {
-	ID    string `json:"id" bson:"id"`
-	Email string `json:"email" bson:"email"`
+	ID    string `json:"id" bson:"id" openapi:"required"`
+	Email string `json:"email" bson:"email" openapi:"required"`
 	Name  string `json:"name" bson:"name"`
-	Role  string `json:"role" bson:"role"`
+	Role  string `json:"role" bson:"role" openapi:"enum=admin|user"`
+}
+
+// LoginRequest represents the credentials posted to the login endpoint
+type LoginRequest struct {
+	Email    string `json:"email" openapi:"required,minLength=1"`
+	Password string `json:"password" openapi:"required,minLength=1"`
+}
+
+// LoginResponse represents a successful login
+type LoginResponse struct {
+	Token     string       `json:"token" openapi:"required" doc:"Bearer token for the Authorization header"`
+	ExpiresAt time.Time    `json:"expires_at" openapi:"required"`
+	User      UserResponse `json:"user" openapi:"required"`
+}
+
+// UnlockRequest identifies the account and/or client address to unlock
+type UnlockRequest struct {
+	Email string `json:"email"`
+	IP    string `json:"ip" doc:"Client address whose lockout to clear"`
+}
+
+// MoveCategoryRequest gives a category's new parent; null moves it to the top level
+type MoveCategoryRequest struct {
+	ParentID *string `json:"parent_id" doc:"New parent category, null for the top level"`
+}
+
+// GraphQLRequest represents a GraphQL operation posted to /graphql
+type GraphQLRequest struct {
+	Query         string         `json:"query" openapi:"required,minLength=1"`
+	Variables     map[string]any `json:"variables"`
+	OperationName string         `json:"operationName" doc:"Operation to run when the query holds several"`
+}
+
+// Session represents a logged-in user's access token (stored hashed)
+type Session struct {
+	TokenHash string    `json:"-" bson:"_id"`
+	UserID    string    `json:"user_id" bson:"user_id"`
+	Email     string    `json:"email" bson:"email"`
+	Role      string    `json:"role" bson:"role"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+// AuthEvent represents an audited authentication event
+type AuthEvent struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Type      string             `json:"type" bson:"type" openapi:"enum=login_success|login_failure|lockout|logout|unlock"`
+	UserID    string             `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	Email     string             `json:"email,omitempty" bson:"email,omitempty"`
+	IP        string             `json:"ip,omitempty" bson:"ip,omitempty"`
+	UserAgent string             `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	Reason    string             `json:"reason,omitempty" bson:"reason,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// MaxAttributes is the most attributes a product may be created or updated
+// with, whichever API the change comes through
+var MaxAttributes = config.Default().Limits.Body.MaxAttributes
+
+// CheckLimits reports a product that exceeds MaxAttributes. The error
+// message is meant for the client.
+func (p Product) CheckLimits() error {
+	if MaxAttributes > 0 && len(p.Attributes) > MaxAttributes {
+		return fmt.Errorf("A product can have at most %d attributes, got %d", MaxAttributes, len(p.Attributes))
+	}
+	return nil
+}
+
+// CSVRecord writes a product as a CSV row with one "attr:<code>" column per
+// attribute, the layout the seed command reads
+func (p Product) CSVRecord() (columns, values []string) {
+	columns = []string{"id", "name", "category_id", "category_group", "version"}
+	values = []string{p.ID.Hex(), p.Name, p.CategoryID, p.CategoryGroup, strconv.FormatInt(p.Version, 10)}
+	for _, attribute := range p.Attributes {
+		columns = append(columns, "attr:"+attribute.Code)
+		values = append(values, cell(attribute.Value))
+	}
+	return columns, values
+}
+
+// cell formats an attribute value: strings as they are, nil as an empty cell
+// and anything else as JSON
+func cell(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
 }
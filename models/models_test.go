@@ -0,0 +1,22 @@
+package models
+
+import "testing"
+
+func TestProductCheckLimits(t *testing.T) {
+	defer func(max int) { MaxAttributes = max }(MaxAttributes)
+	MaxAttributes = 2
+
+	product := Product{Attributes: make([]Attribute, 2)}
+	if err := product.CheckLimits(); err != nil {
+		t.Errorf("CheckLimits with 2 attributes: %v", err)
+	}
+	product.Attributes = append(product.Attributes, Attribute{})
+	if err := product.CheckLimits(); err == nil {
+		t.Error("CheckLimits accepted 3 attributes with a limit of 2")
+	}
+
+	MaxAttributes = 0
+	if err := product.CheckLimits(); err != nil {
+		t.Errorf("CheckLimits without a limit: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Check compares the document with the routes registered on router and
+// reports routes without an operation and operations without a route.
+// OPTIONS routes are answered by the CORS middleware and are not documented.
+func (d *Document) Check(router *mux.Router) error {
+	registered := make(map[string]bool)
+	var undocumented []string
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tmpl, err := route.GetPathTemplate()
+		if err != nil || route.GetHandler() == nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			if method == http.MethodOptions {
+				continue
+			}
+			registered[method+" "+routeParam.ReplaceAllString(tmpl, "{$1}")] = true
+			if d.Operation(method, tmpl) == nil {
+				undocumented = append(undocumented, method+" "+tmpl)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var stale []string
+	for path, item := range d.Paths {
+		for method := range item {
+			if key := strings.ToUpper(method) + " " + path; !registered[key] {
+				stale = append(stale, key)
+			}
+		}
+	}
+
+	var problems []string
+	if len(undocumented) > 0 {
+		slices.Sort(undocumented)
+		problems = append(problems, "routes missing from the OpenAPI document: "+strings.Join(undocumented, ", "))
+	}
+	if len(stale) > 0 {
+		slices.Sort(stale)
+		problems = append(problems, "documented operations without a route: "+strings.Join(stale, ", "))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+// Package openapi builds the OpenAPI 3.1 description of the API from the
+// registered routes and the Go types they exchange
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Version is the OpenAPI version of generated documents
+const Version = "3.1.0"
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Tags       []Tag                 `json:"tags,omitempty"`
+	Security   []SecurityRequirement `json:"security,omitempty"`
+
+	generator *generator
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Tag groups operations
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of one path, keyed by lower-case method
+type PathItem map[string]*Operation
+
+// Operation describes one method on one path
+type Operation struct {
+	OperationID string                `json:"operationId,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []SecurityRequirement `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+// Parameter is a path, query or header parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // path, query or header
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes the accepted request bodies by media type
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes a response by media type
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Header describes a response header
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// MediaType holds the schema of one content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the reusable parts of the document
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme describes how clients authenticate
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// SecurityRequirement names the schemes an operation accepts
+type SecurityRequirement map[string][]string
+
+// New creates an empty document
+func New(info Info) *Document {
+	d := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas:         make(map[string]*Schema),
+			SecuritySchemes: make(map[string]SecurityScheme),
+		},
+	}
+	d.generator = newGenerator(d.Components.Schemas)
+	return d
+}
+
+// Schema returns the schema of v's type. Named struct types are added to
+// the components and referenced.
+func (d *Document) Schema(v any) *Schema {
+	return d.generator.schemaOf(v)
+}
+
+// routeParam matches the variables of a mux path template, with an
+// optional pattern as in {id:[0-9]+}
+var routeParam = regexp.MustCompile(`\{([^}:]+)(?::([^}]+))?\}`)
+
+// Add documents the operation for method on a mux path template. Path
+// parameters missing from op are added as required strings.
+func (d *Document) Add(method, pathTemplate string, op Operation) {
+	path := routeParam.ReplaceAllString(pathTemplate, "{$1}")
+	for _, match := range routeParam.FindAllStringSubmatch(pathTemplate, -1) {
+		name, pattern := match[1], match[2]
+		documented := slices.ContainsFunc(op.Parameters, func(p Parameter) bool {
+			return p.In == "path" && p.Name == name
+		})
+		if !documented {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string", Pattern: pattern},
+			})
+		}
+	}
+	if op.Responses == nil {
+		op.Responses = make(map[string]Response)
+	}
+
+	item, ok := d.Paths[path]
+	if !ok {
+		item = make(PathItem)
+		d.Paths[path] = item
+	}
+	item[strings.ToLower(method)] = &op
+}
+
+// Operation returns the operation for method on a mux path template, or nil
+func (d *Document) Operation(method, pathTemplate string) *Operation {
+	path := routeParam.ReplaceAllString(pathTemplate, "{$1}")
+	return d.Paths[path][strings.ToLower(method)]
+}
+
+// Handler serves the document as JSON. The document must not change once
+// it has been served.
+func (d *Document) Handler() http.Handler {
+	encode := sync.OnceValues(func() ([]byte, error) {
+		return json.MarshalIndent(d, "", "  ")
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := encode()
+		if err != nil {
+			http.Error(w, "Error encoding OpenAPI document", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	})
+}
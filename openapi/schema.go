@@ -0,0 +1,259 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Schema is the subset of JSON Schema 2020-12 used by the API
+type Schema struct {
+	Ref         string `json:"$ref,omitempty"`
+	Type        string `json:"-"`
+	Nullable    bool   `json:"-"` // written as a "null" type, as OpenAPI 3.1 has no nullable
+	Format      string `json:"format,omitempty"`
+	Description string `json:"description,omitempty"`
+	Enum        []any  `json:"enum,omitempty"`
+	Default     any    `json:"default,omitempty"`
+	ReadOnly    bool   `json:"readOnly,omitempty"`
+
+	// Strings
+	Pattern   string `json:"pattern,omitempty"`
+	MinLength *int   `json:"minLength,omitempty"`
+	MaxLength *int   `json:"maxLength,omitempty"`
+
+	// Numbers
+	Minimum *float64 `json:"minimum,omitempty"`
+	Maximum *float64 `json:"maximum,omitempty"`
+
+	// Arrays
+	Items    *Schema `json:"items,omitempty"`
+	MinItems *int    `json:"minItems,omitempty"`
+	MaxItems *int    `json:"maxItems,omitempty"`
+
+	// Objects
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+
+	OneOf []*Schema `json:"oneOf,omitempty"`
+}
+
+// MarshalJSON writes Type and Nullable as the "type" keyword
+func (s Schema) MarshalJSON() ([]byte, error) {
+	type plain Schema
+	var typ any
+	switch {
+	case s.Type != "" && s.Nullable:
+		typ = []string{s.Type, "null"}
+	case s.Type != "":
+		typ = s.Type
+	}
+	return json.Marshal(struct {
+		Type any `json:"type,omitempty"`
+		plain
+	}{typ, plain(s)})
+}
+
+// Ref returns a schema referring to the named component schema
+func Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// String returns a string schema
+func String() *Schema { return &Schema{Type: "string"} }
+
+// Integer returns an integer schema
+func Integer() *Schema { return &Schema{Type: "integer"} }
+
+// Boolean returns a boolean schema
+func Boolean() *Schema { return &Schema{Type: "boolean"} }
+
+// ArrayOf returns an array schema of items
+func ArrayOf(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+// OneOf returns a schema matching exactly one of the alternatives
+func OneOf(alternatives ...*Schema) *Schema {
+	return &Schema{OneOf: alternatives}
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+)
+
+// generator derives schemas from Go types the way encoding/json encodes them
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newGenerator(schemas map[string]*Schema) *generator {
+	return &generator{schemas: schemas, names: make(map[reflect.Type]string)}
+}
+
+func (g *generator) schemaOf(v any) *Schema {
+	t, ok := v.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(v)
+	}
+	return g.schema(t)
+}
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case objectIDType:
+		return &Schema{Type: "string", Pattern: "^[0-9a-f]{24}$"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(g.schema(t.Elem()))
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		// encoding/json writes nil slices as null
+		return &Schema{Type: "array", Items: g.schema(t.Elem()), Nullable: t.Kind() == reflect.Slice}
+	case reflect.Map:
+		// and nil maps too
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return g.named(t)
+	}
+	// interface{} and anything else accepts any value
+	return &Schema{}
+}
+
+// named adds a struct type to the components once and refers to it
+func (g *generator) named(t reflect.Type) *Schema {
+	if name, ok := g.names[t]; ok {
+		return Ref(name)
+	}
+
+	name := t.Name()
+	for i := 2; g.schemas[name] != nil; i++ {
+		name = t.Name() + strconv.Itoa(i)
+	}
+	g.names[t] = name
+	g.schemas[name] = &Schema{} // placeholder for recursive types
+	*g.schemas[name] = *g.object(t)
+	return Ref(name)
+}
+
+// object describes a struct from its json, doc and openapi tags
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(s, t)
+	return s
+}
+
+func (g *generator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			g.addFields(s, f.Type)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		field := g.schema(f.Type)
+		if doc := f.Tag.Get("doc"); doc != "" {
+			field = describe(field, doc)
+		}
+		if applyOptions(field, f.Tag.Get("openapi")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = field
+	}
+}
+
+// applyOptions applies an openapi tag such as
+// `openapi:"required,minLength=1,enum=asc|desc"` and reports whether the
+// field is required
+func applyOptions(s *Schema, tag string) (required bool) {
+	if tag == "" {
+		return false
+	}
+	for _, option := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "required":
+			required = true
+		case "readonly":
+			s.ReadOnly = true
+		case "format":
+			s.Format = value
+		case "pattern":
+			s.Pattern = value
+		case "enum":
+			for _, item := range strings.Split(value, "|") {
+				s.Enum = append(s.Enum, item)
+			}
+		case "minLength":
+			s.MinLength = intOption(value)
+		case "maxLength":
+			s.MaxLength = intOption(value)
+		case "minItems":
+			s.MinItems = intOption(value)
+		case "maxItems":
+			s.MaxItems = intOption(value)
+		case "minimum":
+			n, _ := strconv.ParseFloat(value, 64)
+			s.Minimum = &n
+		case "maximum":
+			n, _ := strconv.ParseFloat(value, 64)
+			s.Maximum = &n
+		case "nonnull":
+			s.Nullable = false
+		}
+	}
+	return required
+}
+
+func intOption(value string) *int {
+	n, _ := strconv.Atoi(value)
+	return &n
+}
+
+// nullable also accepts null
+func nullable(s *Schema) *Schema {
+	if s.Ref != "" {
+		return OneOf(s, &Schema{Type: "null"})
+	}
+	if s.Type == "" {
+		return s
+	}
+	s.Nullable = true
+	return s
+}
+
+// describe adds a description, which OpenAPI 3.1 allows next to $ref
+func describe(s *Schema, description string) *Schema {
+	s.Description = description
+	return s
+}
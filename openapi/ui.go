@@ -0,0 +1,19 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+	"strings"
+)
+
+//go:embed ui.html
+var uiPage string
+
+// UIHandler serves a Swagger UI page for the document at specURL
+func UIHandler(specURL string) http.Handler {
+	page := strings.ReplaceAll(uiPage, "{{SPEC_URL}}", specURL)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	})
+}
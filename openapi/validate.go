@@ -0,0 +1,518 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"go-backend/codec"
+)
+
+// ErrUnsupportedMediaType is returned for request bodies of a content type
+// the operation does not accept
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// Invalid is one value that does not match its schema
+type Invalid struct {
+	In     string `json:"in"`   // path, query, header, body or response
+	Name   string `json:"name"` // parameter name or location in the body, e.g. attributes[0].code
+	Reason string `json:"reason"`
+}
+
+func (i Invalid) String() string {
+	if i.Name == "" {
+		return i.In + ": " + i.Reason
+	}
+	return i.In + " " + i.Name + ": " + i.Reason
+}
+
+// ValidationError lists every value of a request or response that does not
+// match the document
+type ValidationError struct {
+	Invalid []Invalid
+}
+
+func (e *ValidationError) Error() string {
+	reasons := make([]string, len(e.Invalid))
+	for i, invalid := range e.Invalid {
+		reasons[i] = invalid.String()
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// ValidateRequest checks the parameters and body of r against op. pathParams
+// holds the path variables of the matched route and body the request body,
+// which r.Body no longer needs to hold. The error is ErrUnsupportedMediaType
+// or a *ValidationError.
+func (d *Document) ValidateRequest(op *Operation, r *http.Request, pathParams map[string]string, body []byte) error {
+	v := d.validator(true)
+
+	query := r.URL.Query()
+	for _, p := range op.Parameters {
+		var values []string
+		switch p.In {
+		case "path":
+			if value, ok := pathParams[p.Name]; ok {
+				values = []string{value}
+			}
+		case "query":
+			values = query[p.Name]
+		case "header":
+			values = r.Header.Values(p.Name)
+		}
+		if len(values) == 0 {
+			if p.Required {
+				v.fail(p.In, p.Name, "is required")
+			}
+			continue
+		}
+		v.parameter(p, values)
+	}
+
+	if op.RequestBody != nil {
+		if err := v.body(op.RequestBody, r.Header.Get("Content-Type"), body); err != nil {
+			return err
+		}
+	}
+	return v.err()
+}
+
+// ValidateBody checks v, encoded as JSON, against op's JSON request body
+// schema. It lets requests that do not arrive over HTTP share the checks.
+// The error is a *ValidationError.
+func (d *Document) ValidateBody(op *Operation, v any) error {
+	if op.RequestBody == nil {
+		return nil
+	}
+	content, ok := op.RequestBody.Content["application/json"]
+	if !ok {
+		return nil
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding body: %w", err)
+	}
+	value, err := decodeJSON(body)
+	if err != nil {
+		return fmt.Errorf("decoding body: %w", err)
+	}
+	val := d.validator(true)
+	val.value(content.Schema, value, "body", "")
+	return val.err()
+}
+
+// ValidateResponse checks a response to op. Statuses without their own
+// entry must match the default response, and JSON bodies must match the
+// schema of their content type.
+func (d *Document) ValidateResponse(op *Operation, status int, contentType string, body []byte) error {
+	v := d.validator(false)
+
+	response, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		response, ok = op.Responses["default"]
+	}
+	if !ok {
+		v.fail("response", "", fmt.Sprintf("status %d is not documented", status))
+		return v.err()
+	}
+	if len(body) == 0 || len(response.Content) == 0 {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	content, ok := response.Content[mediaType]
+	if !ok {
+		v.fail("response", "", fmt.Sprintf("content type %q is not documented for status %d", contentType, status))
+		return v.err()
+	}
+	if isJSON(mediaType) {
+		value, err := decodeJSON(body)
+		if err != nil {
+			v.fail("response", "", "is not valid JSON: "+err.Error())
+		} else {
+			v.value(content.Schema, value, "response", "")
+		}
+	}
+	return v.err()
+}
+
+// validator collects the mismatches of one request or response
+type validator struct {
+	doc     *Document
+	request bool // readOnly properties are ignored in requests
+	text    bool // scalars are text, converted to the type of their schema
+	invalid []Invalid
+}
+
+func (d *Document) validator(request bool) *validator {
+	return &validator{doc: d, request: request}
+}
+
+func (v *validator) fail(in, name, reason string) {
+	v.invalid = append(v.invalid, Invalid{In: in, Name: name, Reason: reason})
+}
+
+func (v *validator) err() error {
+	if len(v.invalid) == 0 {
+		return nil
+	}
+	return &ValidationError{Invalid: v.invalid}
+}
+
+// parameter converts the text of a parameter to its schema type and checks it
+func (v *validator) parameter(p Parameter, values []string) {
+	schema := v.doc.resolve(p.Schema)
+	if schema == nil {
+		return
+	}
+	if schema.Type == "array" {
+		items := make([]any, len(values))
+		for i, value := range values {
+			items[i] = coerce(v.doc.resolve(schema.Items), value)
+		}
+		v.value(schema, items, p.In, p.Name)
+		return
+	}
+	v.value(schema, coerce(schema, values[0]), p.In, p.Name)
+}
+
+// coerce converts parameter text to the JSON value its schema describes.
+// Text that does not convert stays a string and fails the type check.
+func coerce(s *Schema, text string) any {
+	if s == nil {
+		return text
+	}
+	switch s.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(text, 64); err == nil {
+			return json.Number(text)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(text); err == nil {
+			return b
+		}
+	}
+	return text
+}
+
+// fromText converts a scalar of a format that carries scalars as text, like
+// XML, to the type of its schema. Empty elements stand for empty lists and
+// objects.
+func fromText(s *Schema, value any) any {
+	text, ok := value.(string)
+	if !ok {
+		return value
+	}
+	switch s.Type {
+	case "array":
+		if strings.TrimSpace(text) == "" {
+			return []any{}
+		}
+	case "object":
+		if strings.TrimSpace(text) == "" {
+			return map[string]any{}
+		}
+	case "integer", "number", "boolean":
+		return coerce(s, strings.TrimSpace(text))
+	}
+	return text
+}
+
+// body checks a request body against the schema of its content type. A
+// missing Content-Type is taken to be JSON; other formats are decoded with
+// their codec.
+func (v *validator) body(rb *RequestBody, contentType string, body []byte) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		if rb.Required {
+			v.fail("body", "", "is required")
+		}
+		return nil
+	}
+
+	mediaType := "application/json"
+	if contentType != "" {
+		parsed, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return fmt.Errorf("%w: %q", ErrUnsupportedMediaType, contentType)
+		}
+		mediaType = parsed
+	}
+	content, ok := rb.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedMediaType, mediaType)
+	}
+
+	if isJSON(mediaType) {
+		value, err := decodeJSON(body)
+		if err != nil {
+			v.fail("body", "", "is not valid JSON: "+err.Error())
+			return nil
+		}
+		v.value(content.Schema, value, "body", "")
+		return nil
+	}
+
+	// Other formats are checked in the JSON data model they decode to
+	c := codec.Lookup(mediaType)
+	if c == nil || c.Decode == nil {
+		return fmt.Errorf("%w: %s", ErrUnsupportedMediaType, mediaType)
+	}
+	value, err := c.DecodeTree(bytes.NewReader(body))
+	if err != nil {
+		v.fail("body", "", "is not valid "+mediaType+": "+err.Error())
+		return nil
+	}
+	v.text = c.TextScalars
+	v.value(content.Schema, value, "body", "")
+	return nil
+}
+
+// value checks a decoded JSON value against a schema. name is the location
+// of the value within its parameter or body.
+func (v *validator) value(s *Schema, value any, in, name string) {
+	s = v.doc.resolve(s)
+	if s == nil {
+		return
+	}
+
+	if len(s.OneOf) > 0 {
+		v.oneOf(s.OneOf, value, in, name)
+		return
+	}
+	if v.text {
+		value = fromText(s, value)
+	}
+
+	if value == nil {
+		if s.Type != "" && s.Type != "null" && !s.Nullable {
+			v.fail(in, name, "must be "+article(s.Type)+", not null")
+		}
+		return
+	}
+	if s.Type != "" && !hasType(value, s.Type) {
+		v.fail(in, name, fmt.Sprintf("must be %s, not %s", article(s.Type), article(jsonType(value))))
+		return
+	}
+
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(allowed any) bool {
+		return fmt.Sprint(allowed) == fmt.Sprint(value)
+	}) {
+		v.fail(in, name, "must be one of "+joinValues(s.Enum))
+	}
+
+	switch value := value.(type) {
+	case string:
+		v.string(s, value, in, name)
+	case json.Number:
+		n, _ := value.Float64()
+		if s.Minimum != nil && n < *s.Minimum {
+			v.fail(in, name, "must be at least "+formatNumber(*s.Minimum))
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			v.fail(in, name, "must be at most "+formatNumber(*s.Maximum))
+		}
+	case []any:
+		if s.MinItems != nil && len(value) < *s.MinItems {
+			v.fail(in, name, fmt.Sprintf("must have at least %d items", *s.MinItems))
+		}
+		if s.MaxItems != nil && len(value) > *s.MaxItems {
+			v.fail(in, name, fmt.Sprintf("must have at most %d items", *s.MaxItems))
+		}
+		for i, item := range value {
+			v.value(s.Items, item, in, fmt.Sprintf("%s[%d]", name, i))
+		}
+	case map[string]any:
+		v.object(s, value, in, name)
+	}
+}
+
+func (v *validator) string(s *Schema, value, in, name string) {
+	length := utf8.RuneCountInString(value)
+	if s.MinLength != nil && length < *s.MinLength {
+		if *s.MinLength == 1 {
+			v.fail(in, name, "must not be empty")
+		} else {
+			v.fail(in, name, fmt.Sprintf("must be at least %d characters", *s.MinLength))
+		}
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		v.fail(in, name, fmt.Sprintf("must be at most %d characters", *s.MaxLength))
+	}
+	if s.Pattern != "" && !compile(s.Pattern).MatchString(value) {
+		v.fail(in, name, "must match "+s.Pattern)
+	}
+	if s.Format == "date-time" {
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			v.fail(in, name, "must be an RFC 3339 timestamp")
+		}
+	}
+}
+
+func (v *validator) object(s *Schema, value map[string]any, in, name string) {
+	for _, required := range s.Required {
+		if property := v.doc.resolve(s.Properties[required]); v.request && property != nil && property.ReadOnly {
+			continue
+		}
+		if _, ok := value[required]; !ok {
+			v.fail(in, join(name, required), "is required")
+		}
+	}
+	for key, item := range value {
+		property, ok := s.Properties[key]
+		if !ok {
+			// Unknown properties are allowed unless additionalProperties says otherwise
+			if s.AdditionalProperties != nil {
+				v.value(s.AdditionalProperties, item, in, join(name, key))
+			}
+			continue
+		}
+		if resolved := v.doc.resolve(property); v.request && resolved != nil && resolved.ReadOnly {
+			continue
+		}
+		v.value(property, item, in, join(name, key))
+	}
+}
+
+// oneOf requires exactly one alternative to match. When none does, the
+// mismatches of the closest alternative are reported: one of the value's
+// type with the fewest mismatches.
+func (v *validator) oneOf(alternatives []*Schema, value any, in, name string) {
+	var closest []Invalid
+	closestRank, matches := math.MaxInt, 0
+	for _, alternative := range alternatives {
+		sub := &validator{doc: v.doc, request: v.request, text: v.text}
+		sub.value(alternative, value, in, name)
+		if len(sub.invalid) == 0 {
+			matches++
+			continue
+		}
+		rank := len(sub.invalid)
+		if s := v.doc.resolve(alternative); s != nil && s.Type != "" {
+			converted := value
+			if v.text {
+				converted = fromText(s, value)
+			}
+			if !hasType(converted, s.Type) {
+				rank += len(alternatives) * 1000
+			}
+		}
+		if rank < closestRank {
+			closest, closestRank = sub.invalid, rank
+		}
+	}
+	switch {
+	case matches == 0:
+		v.invalid = append(v.invalid, closest...)
+	case matches > 1:
+		v.fail(in, name, "matches more than one alternative")
+	}
+}
+
+// resolve follows a reference to a component schema
+func (d *Document) resolve(s *Schema) *Schema {
+	for seen := 0; s != nil && s.Ref != "" && seen < 8; seen++ {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+// decodeJSON decodes a single JSON value, keeping numbers exact
+func decodeJSON(body []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func hasType(value any, typ string) bool {
+	actual := jsonType(value)
+	if typ == "number" && actual == "integer" {
+		return true
+	}
+	return actual == typ
+}
+
+// jsonType names the JSON Schema type of a decoded value
+func jsonType(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if n, err := value.Float64(); err == nil && n == math.Trunc(n) && !strings.ContainsAny(string(value), ".eE") {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func article(typ string) string {
+	switch typ {
+	case "null":
+		return "null"
+	case "array", "integer", "object":
+		return "an " + typ
+	}
+	return "a " + typ
+}
+
+func join(name, key string) string {
+	if name == "" {
+		return key
+	}
+	return name + "." + key
+}
+
+func joinValues(values []any) string {
+	texts := make([]string, len(values))
+	for i, value := range values {
+		texts[i] = fmt.Sprint(value)
+	}
+	return strings.Join(texts, ", ")
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// patterns caches compiled schema patterns
+var patterns sync.Map
+
+func compile(pattern string) *regexp.Regexp {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		// An invalid pattern in the document matches anything
+		re = regexp.MustCompile("")
+	}
+	patterns.Store(pattern, re)
+	return re
+}
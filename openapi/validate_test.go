@@ -0,0 +1,27 @@
+package openapi
+
+import "testing"
+
+func TestTextScalarsInOneOfBranches(t *testing.T) {
+	d := &Document{Components: Components{Schemas: map[string]*Schema{
+		"Stock": {Type: "object", Properties: map[string]*Schema{"count": {Type: "integer"}}},
+	}}}
+	// A nullable $ref, as generated for pointer fields
+	schema := OneOf(&Schema{Ref: "#/components/schemas/Stock"}, &Schema{Type: "null"})
+
+	tests := []struct {
+		value   any
+		invalid int
+	}{
+		{map[string]any{"count": "42"}, 0},
+		{map[string]any{"count": " 7 "}, 0},
+		{map[string]any{"count": "many"}, 1},
+	}
+	for _, tt := range tests {
+		v := &validator{doc: d, request: true, text: true}
+		v.value(schema, tt.value, "body", "stock")
+		if len(v.invalid) != tt.invalid {
+			t.Errorf("%v: invalid = %+v, want %d", tt.value, v.invalid, tt.invalid)
+		}
+	}
+}
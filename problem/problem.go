@@ -0,0 +1,56 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go-backend/requestid"
+)
+
+// ContentType is the media type of problem responses (RFC 9457)
+const ContentType = "application/problem+json"
+
+// Problem is an RFC 9457 problem details response
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+
+	// InvalidParams lists the parameters and body fields that failed validation
+	InvalidParams []InvalidParam `json:"invalid_params,omitempty"`
+}
+
+// InvalidParam is one invalid parameter or body field
+type InvalidParam struct {
+	In     string `json:"in"` // path, query, header or body
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// New builds a problem for the given status and request
+func New(r *http.Request, status int, detail string) Problem {
+	return Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		RequestID: requestid.FromContext(r.Context()),
+	}
+}
+
+// Write sends a problem response with the given status and detail
+func Write(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	WriteProblem(w, New(r, status, detail))
+}
+
+// WriteProblem sends a fully built problem response
+func WriteProblem(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
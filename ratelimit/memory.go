@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle buckets are dropped from memory
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // when the bucket will be full again
+}
+
+// MemoryStore is an in-process Store
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Take implements Store
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	interval := limit.interval()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+
+	// Refill for the time elapsed since the last request
+	b.tokens += float64(now.Sub(b.updated)) / float64(interval)
+	if b.tokens > float64(limit.Burst) {
+		b.tokens = float64(limit.Burst)
+	}
+	b.updated = now
+
+	result := Result{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(interval))
+	}
+
+	missing := float64(limit.Burst) - b.tokens
+	b.full = now.Add(time.Duration(missing * float64(interval)))
+	result.Remaining = int(b.tokens)
+	result.Reset = b.full.Sub(now)
+
+	return result, nil
+}
+
+// sweep drops buckets that have refilled completely, since a missing bucket
+// behaves exactly like a full one
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
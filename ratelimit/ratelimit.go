@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket allowing Requests per Per on average, with bursts
+// of up to Burst requests
+type Limit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// ParseLimit parses limits written as "requests/duration", optionally
+// followed by a burst size, e.g. "100/1m" or "5/1m:10"
+func ParseLimit(s string) (Limit, error) {
+	rate, burstStr, hasBurst := strings.Cut(strings.TrimSpace(s), ":")
+	requestsStr, perStr, ok := strings.Cut(rate, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q (want requests/duration, e.g. 100/1m)", s)
+	}
+
+	requests, err := strconv.Atoi(requestsStr)
+	if err != nil || requests <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: request count must be a positive number", s)
+	}
+	per, err := time.ParseDuration(perStr)
+	if err != nil || per <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: duration must be positive, e.g. 1m", s)
+	}
+
+	limit := Limit{Requests: requests, Per: per, Burst: requests}
+	if hasBurst {
+		limit.Burst, err = strconv.Atoi(burstStr)
+		if err != nil || limit.Burst <= 0 {
+			return Limit{}, fmt.Errorf("invalid rate limit %q: burst must be a positive number", s)
+		}
+	}
+	return limit, nil
+}
+
+// String formats the limit the way ParseLimit reads it
+func (l Limit) String() string {
+	s := strconv.Itoa(l.Requests) + "/" + l.Per.String()
+	if l.Burst != l.Requests {
+		s += ":" + strconv.Itoa(l.Burst)
+	}
+	return s
+}
+
+// MarshalText formats the limit for configuration files
+func (l Limit) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText parses limits from configuration files and environment variables
+func (l *Limit) UnmarshalText(text []byte) error {
+	limit, err := ParseLimit(string(text))
+	if err != nil {
+		return err
+	}
+	*l = limit
+	return nil
+}
+
+// interval is the time it takes to refill one token
+func (l Limit) interval() time.Duration {
+	return l.Per / time.Duration(l.Requests)
+}
+
+// Result describes the state of a bucket after a Take
+type Result struct {
+	Allowed    bool
+	Limit      int           // bucket capacity
+	Remaining  int           // tokens left after this request
+	Reset      time.Duration // time until the bucket is full again
+	RetryAfter time.Duration // time until a token is available, when not allowed
+}
+
+// Store keeps token buckets. MemoryStore suits a single instance; deployments
+// with several instances can share limits by implementing Store on top of a
+// shared database such as Redis.
+type Store interface {
+	// Take removes a token from the bucket identified by key, creating the
+	// bucket full if it does not exist
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+
+	"go-backend/db"
+	"go-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoCategories struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoCategories) List(ctx context.Context) ([]models.Category, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetMaxTime(db.MaxTime(ctx)))
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer cursor.Close(ctx)
+
+	var categories []models.Category
+	if err = cursor.All(ctx, &categories); err != nil {
+		return nil, translate(err)
+	}
+	return categories, nil
+}
+
+func (r *mongoCategories) Get(ctx context.Context, id string) (*models.Category, error) {
+	var category models.Category
+	if err := r.collection.FindOne(ctx, bson.M{"id": id}, options.FindOne().SetMaxTime(db.MaxTime(ctx))).Decode(&category); err != nil {
+		return nil, translate(err)
+	}
+	return &category, nil
+}
+
+func (r *mongoCategories) SetParent(ctx context.Context, id string, parentID *string) error {
+	update := bson.M{"$unset": bson.M{"parent_id": ""}}
+	if parentID != nil {
+		update = bson.M{"$set": bson.M{"parent_id": *parentID}}
+	}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"id": id}, update)
+	if err != nil {
+		return translate(err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoCategories) Delete(ctx context.Context, id string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return translate(err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoCategories) Upsert(ctx context.Context, category *models.Category) error {
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"id": category.ID}, category, options.Replace().SetUpsert(true))
+	return translate(err)
+}
@@ -0,0 +1,563 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"go-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NewMemoryStore creates repositories that keep everything in memory. They
+// follow the MongoDB repositories, unique keys and transactions included,
+// for tests and tools that must not touch a database.
+func NewMemoryStore() *Store {
+	m := &memory{
+		products:   make(map[primitive.ObjectID]models.Product),
+		categories: make(map[string]models.Category),
+		users:      make(map[string]models.User),
+		sessions:   make(map[string]models.Session),
+	}
+	return &Store{
+		Products:   &memoryProducts{m},
+		Categories: &memoryCategories{m},
+		Users:      &memoryUsers{m},
+		Sessions:   &memorySessions{m},
+		UnitOfWork: &memoryUnitOfWork{m},
+	}
+}
+
+// memory holds the documents of every repository behind one lock, so a
+// transaction can hold it across repositories
+type memory struct {
+	mu         sync.Mutex
+	products   map[primitive.ObjectID]models.Product
+	categories map[string]models.Category
+	users      map[string]models.User
+	sessions   map[string]models.Session
+}
+
+type memoryTxKey struct{}
+
+// lock checks ctx and takes the lock, unless ctx belongs to a transaction
+// that holds it already
+func (m *memory) lock(ctx context.Context) (func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if ctx.Value(memoryTxKey{}) == m {
+		return func() {}, nil
+	}
+	m.mu.Lock()
+	return m.mu.Unlock, nil
+}
+
+// memoryUnitOfWork serializes transactions and restores a snapshot when one fails
+type memoryUnitOfWork struct {
+	*memory
+}
+
+func (u *memoryUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	m := u.memory
+	if ctx.Value(memoryTxKey{}) == m {
+		return fn(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	products, categories := maps.Clone(m.products), maps.Clone(m.categories)
+	users, sessions := maps.Clone(m.users), maps.Clone(m.sessions)
+	if err := fn(context.WithValue(ctx, memoryTxKey{}, m)); err != nil {
+		m.products, m.categories, m.users, m.sessions = products, categories, users, sessions
+		return err
+	}
+	return nil
+}
+
+type memoryProducts struct {
+	*memory
+}
+
+func (r *memoryProducts) List(ctx context.Context, params models.PaginationParams) ([]models.Product, int64, error) {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer unlock()
+
+	var products []models.Product
+	for _, product := range r.products {
+		if (params.CategoryID == "" || product.CategoryID == params.CategoryID) &&
+			(params.CategoryGroup == "" || product.CategoryGroup == params.CategoryGroup) {
+			products = append(products, cloneProduct(product))
+		}
+	}
+
+	// Without a sort field the order is insertion order, as ObjectIDs increase
+	slices.SortFunc(products, func(a, b models.Product) int {
+		return strings.Compare(a.ID.Hex(), b.ID.Hex())
+	})
+	if key := productSortKey(params.SortField); key != nil {
+		slices.SortStableFunc(products, func(a, b models.Product) int {
+			c := cmp.Compare(key(a), key(b))
+			if params.SortOrder == "desc" {
+				return -c
+			}
+			return c
+		})
+	}
+
+	total := int64(len(products))
+	start := min(max(params.Start, 0), len(products))
+	products = products[start:]
+	if params.Limit > 0 && params.Limit < len(products) {
+		products = products[:params.Limit]
+	}
+	return products, total, nil
+}
+
+// productSortKey returns the sort key for a field, compared case-insensitively
+// like the collation the MongoDB repository uses
+func productSortKey(field string) func(models.Product) string {
+	switch field {
+	case "id", "_id":
+		return func(p models.Product) string { return p.ID.Hex() }
+	case "name":
+		return func(p models.Product) string { return strings.ToLower(p.Name) }
+	case "category_id":
+		return func(p models.Product) string { return strings.ToLower(p.CategoryID) }
+	case "category_group":
+		return func(p models.Product) string { return strings.ToLower(p.CategoryGroup) }
+	}
+	return nil
+}
+
+func (r *memoryProducts) Get(ctx context.Context, id string) (*models.Product, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	product, ok := r.products[objectID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	product = cloneProduct(product)
+	return &product, nil
+}
+
+func (r *memoryProducts) ListByCategories(ctx context.Context, categoryIDs []string, limit int) (map[string][]models.Product, error) {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	products := make(map[string][]models.Product)
+	for _, product := range r.products {
+		if slices.Contains(categoryIDs, product.CategoryID) {
+			products[product.CategoryID] = append(products[product.CategoryID], cloneProduct(product))
+		}
+	}
+	for id, list := range products {
+		slices.SortFunc(list, func(a, b models.Product) int {
+			return strings.Compare(a.ID.Hex(), b.ID.Hex())
+		})
+		products[id] = list[:min(limit, len(list))]
+	}
+	return products, nil
+}
+
+func (r *memoryProducts) Create(ctx context.Context, product *models.Product) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if product.ID.IsZero() {
+		product.ID = primitive.NewObjectID()
+	}
+	if _, ok := r.products[product.ID]; ok {
+		return ErrDuplicate
+	}
+	product.Version = 1
+	r.products[product.ID] = cloneProduct(*product)
+	return nil
+}
+
+func (r *memoryProducts) Update(ctx context.Context, product *models.Product) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	stored, ok := r.products[product.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	r.save(stored, product)
+	return nil
+}
+
+func (r *memoryProducts) Upsert(ctx context.Context, product *models.Product) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	stored, ok := r.products[product.ID]
+	if product.ID.IsZero() {
+		for _, candidate := range r.products {
+			if candidate.CategoryID == product.CategoryID && candidate.Name == product.Name {
+				stored, ok = candidate, true
+				break
+			}
+		}
+	}
+	if !ok {
+		stored = models.Product{ID: product.ID}
+		if stored.ID.IsZero() {
+			stored.ID = primitive.NewObjectID()
+		}
+	}
+	r.save(stored, product)
+	return nil
+}
+
+// save applies the editable fields of product to stored, bumps the version
+// and copies the result back, like productUpdate
+func (r *memoryProducts) save(stored models.Product, product *models.Product) {
+	stored.Name = product.Name
+	stored.CategoryID = product.CategoryID
+	stored.CategoryGroup = product.CategoryGroup
+	stored.Attributes = product.Attributes
+	stored.Version++
+	r.products[stored.ID] = cloneProduct(stored)
+	*product = cloneProduct(stored)
+}
+
+func (r *memoryProducts) SetCategoryGroup(ctx context.Context, categoryIDs []string, group string) (int64, error) {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	var modified int64
+	for id, product := range r.products {
+		if slices.Contains(categoryIDs, product.CategoryID) && product.CategoryGroup != group {
+			product.CategoryGroup = group
+			product.Version++
+			r.products[id] = product
+			modified++
+		}
+	}
+	return modified, nil
+}
+
+func (r *memoryProducts) MoveCategory(ctx context.Context, from, to, group string) (int64, error) {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	var modified int64
+	for id, product := range r.products {
+		if product.CategoryID == from {
+			product.CategoryID = to
+			product.CategoryGroup = group
+			product.Version++
+			r.products[id] = product
+			modified++
+		}
+	}
+	return modified, nil
+}
+
+func (r *memoryProducts) ForEach(ctx context.Context, fn func(models.Product) error) error {
+	// Iterate over a copy so fn may call back into the repository
+	products, _, err := r.List(ctx, models.PaginationParams{})
+	if err != nil {
+		return err
+	}
+	for _, product := range products {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cloneProduct(product models.Product) models.Product {
+	product.Attributes = slices.Clone(product.Attributes)
+	return product
+}
+
+type memoryCategories struct {
+	*memory
+}
+
+// List returns the categories in ID order
+func (r *memoryCategories) List(ctx context.Context) ([]models.Category, error) {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	categories := slices.Collect(maps.Values(r.categories))
+	slices.SortFunc(categories, func(a, b models.Category) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return categories, nil
+}
+
+func (r *memoryCategories) Get(ctx context.Context, id string) (*models.Category, error) {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	category, ok := r.categories[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &category, nil
+}
+
+func (r *memoryCategories) Upsert(ctx context.Context, category *models.Category) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	r.categories[category.ID] = *category
+	return nil
+}
+
+func (r *memoryCategories) SetParent(ctx context.Context, id string, parentID *string) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	category, ok := r.categories[id]
+	if !ok {
+		return ErrNotFound
+	}
+	category.ParentID = parentID
+	r.categories[id] = category
+	return nil
+}
+
+func (r *memoryCategories) Delete(ctx context.Context, id string) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, ok := r.categories[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.categories, id)
+	return nil
+}
+
+type memoryUsers struct {
+	*memory
+}
+
+// List returns the matching users in ID order
+func (r *memoryUsers) List(ctx context.Context, email string) ([]models.User, error) {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	var users []models.User
+	for _, user := range r.users {
+		if email == "" || user.Email == email {
+			users = append(users, user)
+		}
+	}
+	slices.SortFunc(users, func(a, b models.User) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return users, nil
+}
+
+func (r *memoryUsers) Get(ctx context.Context, id string) (*models.User, error) {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *memoryUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if user, ok := r.byEmail(email); ok {
+		return &user, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryUsers) Create(ctx context.Context, user *models.User) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, ok := r.users[user.ID]; ok {
+		return ErrDuplicate
+	}
+	if _, ok := r.byEmail(user.Email); ok {
+		return ErrDuplicate
+	}
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUsers) Upsert(ctx context.Context, user *models.User) (bool, error) {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	if stored, ok := r.byEmail(user.Email); ok {
+		stored.Name, stored.Role = user.Name, user.Role
+		r.users[stored.ID] = stored
+		return false, nil
+	}
+
+	if user.ID == "" {
+		user.ID = primitive.NewObjectID().Hex()
+	}
+	if _, ok := r.users[user.ID]; ok {
+		return false, ErrDuplicate
+	}
+	r.users[user.ID] = *user
+	return true, nil
+}
+
+func (r *memoryUsers) SetRole(ctx context.Context, id, role string) error {
+	return r.set(ctx, id, func(user *models.User) { user.Role = role })
+}
+
+func (r *memoryUsers) SetPassword(ctx context.Context, id, hash string) error {
+	return r.set(ctx, id, func(user *models.User) { user.Password = hash })
+}
+
+func (r *memoryUsers) Delete(ctx context.Context, id string) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.users, id)
+	return nil
+}
+
+func (r *memoryUsers) set(ctx context.Context, id string, change func(*models.User)) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	change(&user)
+	r.users[id] = user
+	return nil
+}
+
+func (r *memoryUsers) byEmail(email string) (models.User, bool) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, true
+		}
+	}
+	return models.User{}, false
+}
+
+type memorySessions struct {
+	*memory
+}
+
+func (r *memorySessions) Create(ctx context.Context, session *models.Session) error {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, ok := r.sessions[session.TokenHash]; ok {
+		return ErrDuplicate
+	}
+	r.sessions[session.TokenHash] = *session
+	return nil
+}
+
+func (r *memorySessions) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	var deleted int64
+	for hash, session := range r.sessions {
+		if session.UserID == userID {
+			delete(r.sessions, hash)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-backend/models"
+)
+
+func TestMemoryUnitOfWorkCommits(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	product := &models.Product{Name: "Chair", CategoryID: "furniture"}
+	err := store.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := store.Categories.Upsert(ctx, &models.Category{ID: "furniture", Name: "Furniture"}); err != nil {
+			return err
+		}
+		return store.Products.Create(ctx, product)
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+
+	if _, err := store.Categories.Get(ctx, "furniture"); err != nil {
+		t.Errorf("category after commit: %v", err)
+	}
+	stored, err := store.Products.Get(ctx, product.ID.Hex())
+	if err != nil {
+		t.Fatalf("product after commit: %v", err)
+	}
+	if stored.Name != "Chair" || stored.Version != 1 {
+		t.Errorf("product after commit = %+v, want Chair at version 1", stored)
+	}
+}
+
+func TestMemoryUnitOfWorkRollsBack(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	existing := &models.Product{Name: "Table", CategoryID: "furniture"}
+	if err := store.Products.Create(ctx, existing); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	failure := errors.New("failure")
+	err := store.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := store.Users.Create(ctx, &models.User{ID: "u1", Email: "a@example.com"}); err != nil {
+			return err
+		}
+		if _, err := store.Products.MoveCategory(ctx, "furniture", "garden", "garden"); err != nil {
+			return err
+		}
+		// A nested unit of work joins the outer one and is rolled back with it
+		return store.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+			if err := store.Categories.Upsert(ctx, &models.Category{ID: "garden"}); err != nil {
+				return err
+			}
+			return failure
+		})
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Do = %v, want %v", err, failure)
+	}
+
+	if _, err := store.Users.Get(ctx, "u1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("user after rollback: err = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := store.Categories.Get(ctx, "garden"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("category after rollback: err = %v, want %v", err, ErrNotFound)
+	}
+	stored, err := store.Products.Get(ctx, existing.ID.Hex())
+	if err != nil {
+		t.Fatalf("product after rollback: %v", err)
+	}
+	if stored.CategoryID != "furniture" || stored.Version != 1 {
+		t.Errorf("product after rollback = %+v, want it unchanged", stored)
+	}
+}
+
+func TestMemoryUsersRejectDuplicateEmail(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	if err := store.Users.Create(ctx, &models.User{ID: "u1", Email: "a@example.com"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	err := store.Users.Create(ctx, &models.User{ID: "u2", Email: "a@example.com"})
+	if !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Create with a taken email = %v, want %v", err, ErrDuplicate)
+	}
+}
+
+func TestMemoryProductsAreCopied(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	product := &models.Product{Name: "Lamp", CategoryID: "lighting", Attributes: []models.Attribute{{Code: "color", Value: "red"}}}
+	if err := store.Products.Create(ctx, product); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	product.Attributes[0].Value = "blue"
+
+	stored, err := store.Products.Get(ctx, product.ID.Hex())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if stored.Attributes[0].Value != "red" {
+		t.Errorf("stored attribute = %q, want the value at creation", stored.Attributes[0].Value)
+	}
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go-backend/models"
+)
+
+// MoveCategory moves a category under parentID, or to the top level when it
+// is nil. Products in the moved subtree get the category group of their new
+// top-level category.
+func (s *Store) MoveCategory(ctx context.Context, id string, parentID *string) error {
+	return s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		tree, err := s.categoryTree(ctx)
+		if err != nil {
+			return err
+		}
+		if _, ok := tree[id]; !ok {
+			return ErrNotFound
+		}
+		if parentID != nil {
+			if _, ok := tree[*parentID]; !ok {
+				return fmt.Errorf("%w: parent category %s does not exist", ErrConflict, *parentID)
+			}
+			if tree.isWithin(*parentID, id) {
+				return fmt.Errorf("%w: category %s cannot move under itself or its subcategories", ErrConflict, id)
+			}
+		}
+
+		if err := s.Categories.SetParent(ctx, id, parentID); err != nil {
+			return err
+		}
+		tree[id] = parentID
+
+		_, err = s.Products.SetCategoryGroup(ctx, tree.subtree(id), tree.root(id))
+		return err
+	})
+}
+
+// DeleteCategory deletes a category. Its subcategories and products move to
+// its parent. A top-level category's subcategories become top-level
+// categories, and it can only be deleted once it holds no products itself.
+func (s *Store) DeleteCategory(ctx context.Context, id string) error {
+	return s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		tree, err := s.categoryTree(ctx)
+		if err != nil {
+			return err
+		}
+		parentID, ok := tree[id]
+		if !ok {
+			return ErrNotFound
+		}
+
+		if parentID != nil {
+			if _, err := s.Products.MoveCategory(ctx, id, *parentID, tree.root(*parentID)); err != nil {
+				return err
+			}
+		} else {
+			_, total, err := s.Products.List(ctx, models.PaginationParams{CategoryID: id, Limit: 1})
+			if err != nil {
+				return err
+			}
+			if total > 0 {
+				return fmt.Errorf("%w: top-level category %s still has %d products", ErrConflict, id, total)
+			}
+		}
+
+		for _, child := range tree.children(id) {
+			if err := s.Categories.SetParent(ctx, child, parentID); err != nil {
+				return err
+			}
+			tree[child] = parentID
+			if parentID == nil {
+				if _, err := s.Products.SetCategoryGroup(ctx, tree.subtree(child), child); err != nil {
+					return err
+				}
+			}
+		}
+		return s.Categories.Delete(ctx, id)
+	})
+}
+
+// DeleteUser deletes a user and revokes all of their sessions
+func (s *Store) DeleteUser(ctx context.Context, id string) error {
+	return s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := s.Users.Delete(ctx, id); err != nil {
+			return err
+		}
+		_, err := s.Sessions.DeleteByUser(ctx, id)
+		return err
+	})
+}
+
+// categoryTree maps category IDs to their parent IDs
+type categoryTree map[string]*string
+
+func (s *Store) categoryTree(ctx context.Context) (categoryTree, error) {
+	categories, err := s.Categories.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tree := make(categoryTree, len(categories))
+	for _, category := range categories {
+		tree[category.ID] = category.ParentID
+	}
+	return tree, nil
+}
+
+// root returns the top-level category above id
+func (t categoryTree) root(id string) string {
+	for seen := 0; t[id] != nil && seen < len(t); seen++ {
+		id = *t[id]
+	}
+	return id
+}
+
+// isWithin reports whether id is ancestor or one of its descendants
+func (t categoryTree) isWithin(id, ancestor string) bool {
+	for seen := 0; seen <= len(t); seen++ {
+		if id == ancestor {
+			return true
+		}
+		parent := t[id]
+		if parent == nil {
+			return false
+		}
+		id = *parent
+	}
+	return false
+}
+
+// children returns the direct subcategories of id
+func (t categoryTree) children(id string) []string {
+	var children []string
+	for child, parent := range t {
+		if parent != nil && *parent == id {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// subtree returns id and all of its descendants
+func (t categoryTree) subtree(id string) []string {
+	var ids []string
+	for candidate := range t {
+		if t.isWithin(candidate, id) {
+			ids = append(ids, candidate)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+
+	"go-backend/db"
+	"go-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoProducts struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoProducts) List(ctx context.Context, params models.PaginationParams) ([]models.Product, int64, error) {
+	// Build filter
+	filter := bson.M{}
+	if params.CategoryID != "" {
+		filter["category_id"] = params.CategoryID
+	}
+	if params.CategoryGroup != "" {
+		filter["category_group"] = params.CategoryGroup
+	}
+
+	// Build options for sorting and pagination
+	findOptions := options.Find().SetMaxTime(db.MaxTime(ctx))
+	if params.SortField != "" {
+		sortValue := 1 // asc
+		if params.SortOrder == "desc" {
+			sortValue = -1 // desc
+		}
+
+		// Map API field names to MongoDB field names
+		sortFieldName := params.SortField
+		if params.SortField == "id" {
+			sortFieldName = "_id"
+		}
+
+		findOptions.SetSort(bson.D{{Key: sortFieldName, Value: sortValue}})
+
+		// Add case-insensitive collation for string fields
+		if params.SortField != "id" && params.SortField != "_id" {
+			// Use simple collation with case-insensitive comparison
+			findOptions.SetCollation(&options.Collation{
+				Locale:   "en",
+				Strength: 2, // 2 = case-insensitive
+			})
+		}
+	}
+
+	// First get total count
+	total, err := r.collection.CountDocuments(ctx, filter, options.Count().SetMaxTime(db.MaxTime(ctx)))
+	if err != nil {
+		return nil, 0, translate(err)
+	}
+
+	// Apply pagination
+	findOptions.SetSkip(int64(params.Start))
+	findOptions.SetLimit(int64(params.Limit))
+
+	// Execute query
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, translate(err)
+	}
+	defer cursor.Close(ctx)
+
+	var products []models.Product
+	if err = cursor.All(ctx, &products); err != nil {
+		return nil, 0, translate(err)
+	}
+	return products, total, nil
+}
+
+func (r *mongoProducts) Get(ctx context.Context, id string) (*models.Product, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var product models.Product
+	if err := r.collection.FindOne(ctx, bson.M{"_id": objectID}, options.FindOne().SetMaxTime(db.MaxTime(ctx))).Decode(&product); err != nil {
+		return nil, translate(err)
+	}
+	return &product, nil
+}
+
+func (r *mongoProducts) ListByCategories(ctx context.Context, categoryIDs []string, limit int) (map[string][]models.Product, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"category_id": bson.M{"$in": categoryIDs}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$category_id", "products": bson.M{"$push": "$$ROOT"}}}},
+		{{Key: "$project", Value: bson.M{"products": bson.M{"$slice": bson.A{"$products", limit}}}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline, options.Aggregate().SetMaxTime(db.MaxTime(ctx)))
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		CategoryID string           `bson:"_id"`
+		Products   []models.Product `bson:"products"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, translate(err)
+	}
+	products := make(map[string][]models.Product, len(groups))
+	for _, group := range groups {
+		products[group.CategoryID] = group.Products
+	}
+	return products, nil
+}
+
+func (r *mongoProducts) Create(ctx context.Context, product *models.Product) error {
+	if product.ID.IsZero() {
+		product.ID = primitive.NewObjectID()
+	}
+	product.Version = 1
+	_, err := r.collection.InsertOne(ctx, product)
+	return translate(err)
+}
+
+func (r *mongoProducts) Update(ctx context.Context, product *models.Product) error {
+	// Return the stored document so the caller sees the new version
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetMaxTime(db.MaxTime(ctx))
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": product.ID}, productUpdate(product), opts).Decode(product)
+	return translate(err)
+}
+
+func (r *mongoProducts) Upsert(ctx context.Context, product *models.Product) error {
+	filter := bson.M{"_id": product.ID}
+	if product.ID.IsZero() {
+		filter = bson.M{"category_id": product.CategoryID, "name": product.Name}
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After).SetMaxTime(db.MaxTime(ctx))
+	err := r.collection.FindOneAndUpdate(ctx, filter, productUpdate(product), opts).Decode(product)
+	return translate(err)
+}
+
+// productUpdate sets the editable fields and bumps the version, which
+// starts at 1 for upserted documents
+func productUpdate(product *models.Product) bson.M {
+	return bson.M{
+		"$set": bson.M{
+			"name":           product.Name,
+			"category_id":    product.CategoryID,
+			"category_group": product.CategoryGroup,
+			"attributes":     product.Attributes,
+		},
+		"$inc": bson.M{"version": 1},
+	}
+}
+
+func (r *mongoProducts) SetCategoryGroup(ctx context.Context, categoryIDs []string, group string) (int64, error) {
+	result, err := r.collection.UpdateMany(ctx,
+		bson.M{"category_id": bson.M{"$in": categoryIDs}, "category_group": bson.M{"$ne": group}},
+		bson.M{"$set": bson.M{"category_group": group}, "$inc": bson.M{"version": 1}},
+	)
+	if err != nil {
+		return 0, translate(err)
+	}
+	return result.ModifiedCount, nil
+}
+
+func (r *mongoProducts) MoveCategory(ctx context.Context, from, to, group string) (int64, error) {
+	result, err := r.collection.UpdateMany(ctx,
+		bson.M{"category_id": from},
+		bson.M{"$set": bson.M{"category_id": to, "category_group": group}, "$inc": bson.M{"version": 1}},
+	)
+	if err != nil {
+		return 0, translate(err)
+	}
+	return result.ModifiedCount, nil
+}
+
+func (r *mongoProducts) ForEach(ctx context.Context, fn func(models.Product) error) error {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return translate(err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var product models.Product
+		if err := cursor.Decode(&product); err != nil {
+			return err
+		}
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+	return translate(cursor.Err())
+}
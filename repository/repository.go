@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"go-backend/models"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrNotFound is returned when no document matches
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidID is returned for malformed document IDs
+	ErrInvalidID = errors.New("invalid id")
+	// ErrDuplicate is returned when a unique field is already taken
+	ErrDuplicate = errors.New("duplicate")
+	// ErrConflict is returned when a change would leave the data inconsistent
+	ErrConflict = errors.New("conflict")
+)
+
+// ProductRepository stores products
+type ProductRepository interface {
+	// List returns one page of products matching params and the total match count
+	List(ctx context.Context, params models.PaginationParams) ([]models.Product, int64, error)
+	Get(ctx context.Context, id string) (*models.Product, error)
+	// ListByCategories returns up to limit products of each category in ID
+	// order, keyed by category ID, with a single query
+	ListByCategories(ctx context.Context, categoryIDs []string, limit int) (map[string][]models.Product, error)
+	// Create inserts the product, generating an ID if it has none
+	Create(ctx context.Context, product *models.Product) error
+	Update(ctx context.Context, product *models.Product) error
+	// Upsert updates the product with the same ID, or with the same name
+	// and category when it has no ID, or inserts it
+	Upsert(ctx context.Context, product *models.Product) error
+	// ForEach calls fn for every product in ID order
+	ForEach(ctx context.Context, fn func(models.Product) error) error
+	// SetCategoryGroup sets the category group of every product in the
+	// given categories and returns how many changed
+	SetCategoryGroup(ctx context.Context, categoryIDs []string, group string) (int64, error)
+	// MoveCategory moves every product in one category to another
+	MoveCategory(ctx context.Context, from, to, group string) (int64, error)
+}
+
+// CategoryRepository stores categories
+type CategoryRepository interface {
+	List(ctx context.Context) ([]models.Category, error)
+	Get(ctx context.Context, id string) (*models.Category, error)
+	// Upsert replaces the category with the same ID or inserts it
+	Upsert(ctx context.Context, category *models.Category) error
+	// SetParent moves the category under parentID, or to the top level when it is nil
+	SetParent(ctx context.Context, id string, parentID *string) error
+	Delete(ctx context.Context, id string) error
+}
+
+// UserRepository stores users
+type UserRepository interface {
+	// List returns all users, or those with the given email when it is set
+	List(ctx context.Context, email string) ([]models.User, error)
+	Get(ctx context.Context, id string) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	Create(ctx context.Context, user *models.User) error
+	// Upsert updates the name and role of the user with the same email, or
+	// inserts the user. It reports whether the user was created.
+	Upsert(ctx context.Context, user *models.User) (bool, error)
+	SetRole(ctx context.Context, id, role string) error
+	// SetPassword stores a password hash from auth.HashPassword
+	SetPassword(ctx context.Context, id, hash string) error
+	Delete(ctx context.Context, id string) error
+}
+
+// SessionRepository stores access token sessions
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	// DeleteByUser revokes every session of the user and returns how many there were
+	DeleteByUser(ctx context.Context, userID string) (int64, error)
+}
+
+// Store groups the repositories used by the HTTP handlers and the CLI.
+// Calls that must succeed or fail together go through UnitOfWork.
+type Store struct {
+	Products   ProductRepository
+	Categories CategoryRepository
+	Users      UserRepository
+	Sessions   SessionRepository
+	UnitOfWork UnitOfWork
+}
+
+// NewMongoStore creates repositories backed by a MongoDB database
+func NewMongoStore(database *mongo.Database) *Store {
+	return &Store{
+		Products:   &mongoProducts{collection: database.Collection("products")},
+		Categories: &mongoCategories{collection: database.Collection("categories")},
+		Users:      &mongoUsers{collection: database.Collection("users")},
+		Sessions:   &mongoSessions{collection: database.Collection("sessions")},
+		UnitOfWork: &mongoUnitOfWork{database: database},
+	}
+}
+
+// translate maps driver errors onto repository errors. Other errors are
+// returned as they are, so db.IsUnavailable and db.IsTimeout still apply.
+func translate(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return ErrNotFound
+	case mongo.IsDuplicateKeyError(err):
+		return ErrDuplicate
+	}
+	return err
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+
+	"go-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoSessions struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoSessions) Create(ctx context.Context, session *models.Session) error {
+	_, err := r.collection.InsertOne(ctx, session)
+	return translate(err)
+}
+
+func (r *mongoSessions) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, translate(err)
+	}
+	return result.DeletedCount, nil
+}
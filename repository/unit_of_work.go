@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// UnitOfWork makes a group of repository calls atomic
+type UnitOfWork interface {
+	// Do runs fn in a transaction. Repository calls made with the context
+	// passed to fn are committed together when fn returns nil and rolled
+	// back when it returns an error, which Do returns. fn may run more than
+	// once when the transaction hits a transient error, so it must not have
+	// side effects outside the repositories. Calling Do inside fn joins the
+	// outer transaction.
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Transactions must read from the primary whatever the client's read preference
+var transactionOptions = options.Transaction().SetReadPreference(readpref.Primary())
+
+// mongoUnitOfWork runs transactions in a MongoDB session. Standalone servers
+// have no transactions, so there fn runs without one.
+type mongoUnitOfWork struct {
+	database *mongo.Database
+
+	mu           sync.Mutex
+	checked      bool
+	transactions bool
+}
+
+func (u *mongoUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	supported, err := u.supportsTransactions(ctx)
+	if err != nil {
+		return translate(err)
+	}
+	if !supported {
+		return fn(ctx)
+	}
+
+	session, err := u.database.Client().StartSession()
+	if err != nil {
+		return translate(err)
+	}
+	defer session.EndSession(context.WithoutCancel(ctx))
+
+	// WithTransaction retries on TransientTransactionError and
+	// UnknownTransactionCommitResult until ctx is done
+	_, err = session.WithTransaction(ctx, func(ctx mongo.SessionContext) (any, error) {
+		return nil, fn(ctx)
+	}, transactionOptions)
+	return translate(err)
+}
+
+// supportsTransactions reports whether the deployment is a replica set or a
+// sharded cluster. The answer is cached once the server has replied.
+func (u *mongoUnitOfWork) supportsTransactions(ctx context.Context) (bool, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.checked {
+		return u.transactions, nil
+	}
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err := u.database.Client().Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		return false, err
+	}
+
+	u.checked = true
+	u.transactions = hello.SetName != "" || hello.Msg == "isdbgrid"
+	if !u.transactions {
+		log.Println("MongoDB is a standalone server: multi-document changes run without transactions")
+	}
+	return u.transactions, nil
+}
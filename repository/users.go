@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+
+	"go-backend/db"
+	"go-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoUsers struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoUsers) List(ctx context.Context, email string) ([]models.User, error) {
+	filter := bson.M{}
+	if email != "" {
+		filter["email"] = email
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetMaxTime(db.MaxTime(ctx)))
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, translate(err)
+	}
+	return users, nil
+}
+
+func (r *mongoUsers) Get(ctx context.Context, id string) (*models.User, error) {
+	var user models.User
+	if err := r.collection.FindOne(ctx, bson.M{"id": id}, options.FindOne().SetMaxTime(db.MaxTime(ctx))).Decode(&user); err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *mongoUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := r.collection.FindOne(ctx, bson.M{"email": email}, options.FindOne().SetMaxTime(db.MaxTime(ctx))).Decode(&user); err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *mongoUsers) Create(ctx context.Context, user *models.User) error {
+	_, err := r.collection.InsertOne(ctx, user)
+	return translate(err)
+}
+
+func (r *mongoUsers) Upsert(ctx context.Context, user *models.User) (bool, error) {
+	if user.ID == "" {
+		user.ID = primitive.NewObjectID().Hex()
+	}
+	update := bson.M{
+		"$set":         bson.M{"name": user.Name, "role": user.Role},
+		"$setOnInsert": bson.M{"id": user.ID, "password": user.Password},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"email": user.Email}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return false, translate(err)
+	}
+	return result.UpsertedCount > 0, nil
+}
+
+func (r *mongoUsers) SetRole(ctx context.Context, id, role string) error {
+	return r.set(ctx, id, bson.M{"role": role})
+}
+
+func (r *mongoUsers) SetPassword(ctx context.Context, id, hash string) error {
+	return r.set(ctx, id, bson.M{"password": hash})
+}
+
+func (r *mongoUsers) Delete(ctx context.Context, id string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return translate(err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoUsers) set(ctx context.Context, id string, fields bson.M) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"id": id}, bson.M{"$set": fields})
+	if err != nil {
+		return translate(err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,506 @@
+package routes
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"go-backend/codec"
+	"go-backend/health"
+	"go-backend/models"
+	"go-backend/openapi"
+	"go-backend/problem"
+)
+
+// Spec describes every route registered by RegisterRoutes. A route added
+// there needs an entry here; the openapi -check command and startup report
+// routes that are missing.
+func Spec() *openapi.Document {
+	d := openapi.New(openapi.Info{
+		Title:       "Go MongoDB API Backend",
+		Version:     "2.0.0",
+		Description: "Products, categories and users stored in MongoDB.",
+	})
+	d.Components.SecuritySchemes["bearerAuth"] = openapi.SecurityScheme{
+		Type:        "http",
+		Scheme:      "bearer",
+		Description: "Token from POST /api/auth/login",
+	}
+	d.Tags = []openapi.Tag{
+		{Name: "categories"}, {Name: "products"}, {Name: "users"},
+		{Name: "auth"}, {Name: "graphql"}, {Name: "health"}, {Name: "meta"},
+	}
+
+	// Errors written by the middleware on any route
+	problemSchema := d.Schema(problem.Problem{})
+	problemResponse := func(description string) openapi.Response {
+		return openapi.Response{
+			Description: description,
+			Content:     map[string]openapi.MediaType{problem.ContentType: {Schema: problemSchema}},
+		}
+	}
+	textResponse := func(description string) openapi.Response {
+		return openapi.Response{
+			Description: description,
+			Content:     map[string]openapi.MediaType{"text/plain": {Schema: openapi.String()}},
+		}
+	}
+	jsonResponse := func(description string, schema *openapi.Schema) openapi.Response {
+		return openapi.Response{
+			Description: description,
+			Content:     map[string]openapi.MediaType{"application/json": {Schema: schema}},
+		}
+	}
+	jsonBody := func(v any) *openapi.RequestBody {
+		return &openapi.RequestBody{
+			Required: true,
+			Content:  map[string]openapi.MediaType{"application/json": {Schema: d.Schema(v)}},
+		}
+	}
+
+	// Resources are exchanged in the media type of every codec, with the
+	// same schema. Lists can also be had as CSV, one line per item.
+	response := func(description string, schema *openapi.Schema) openapi.Response {
+		content := make(map[string]openapi.MediaType)
+		for _, c := range codec.All() {
+			if !c.ListsOnly {
+				content[c.MediaType] = openapi.MediaType{Schema: schema}
+			}
+		}
+		return openapi.Response{Description: description, Content: content}
+	}
+	listResponse := func(description string, schema *openapi.Schema) openapi.Response {
+		r := response(description, schema)
+		for _, c := range codec.All() {
+			if c.ListsOnly {
+				r.Content[c.MediaType] = openapi.MediaType{Schema: openapi.String()}
+			}
+		}
+		return r
+	}
+	withHeaders := func(r openapi.Response, headers map[string]openapi.Header) openapi.Response {
+		r.Headers = headers
+		return r
+	}
+	body := func(v any) *openapi.RequestBody {
+		content := make(map[string]openapi.MediaType)
+		for _, c := range codec.All() {
+			if c.Decode != nil {
+				content[c.MediaType] = openapi.MediaType{Schema: d.Schema(v)}
+			}
+		}
+		return &openapi.RequestBody{Required: true, Content: content}
+	}
+	query := func(name, description string, schema *openapi.Schema) openapi.Parameter {
+		return openapi.Parameter{Name: name, In: "query", Description: description, Schema: schema}
+	}
+	intRange := func(minimum, maximum float64) *openapi.Schema {
+		s := openapi.Integer()
+		s.Minimum = &minimum
+		if maximum > 0 {
+			s.Maximum = &maximum
+		}
+		return s
+	}
+	enum := func(values ...any) *openapi.Schema {
+		s := openapi.String()
+		s.Enum = values
+		return s
+	}
+	dateTime := &openapi.Schema{Type: "string", Format: "date-time"}
+	objectID := openapi.Parameter{
+		Name:     "id",
+		In:       "path",
+		Required: true,
+		Schema:   &openapi.Schema{Type: "string", Pattern: "^[0-9a-fA-F]{24}$"},
+	}
+	status := &openapi.Schema{
+		Type:       "object",
+		Properties: map[string]*openapi.Schema{"status": openapi.String()},
+		Required:   []string{"status"},
+	}
+
+	// admin marks an operation as needing a token with the admin role
+	bearer := []openapi.SecurityRequirement{{"bearerAuth": {}}}
+	admin := func(op openapi.Operation) openapi.Operation {
+		op.Security = bearer
+		op.Responses["401"] = problemResponse("Missing or invalid token")
+		op.Responses["403"] = problemResponse("The token's user is not an admin")
+		return op
+	}
+
+	totalCount := map[string]openapi.Header{
+		"X-Total-Count": {Description: "Number of products matching the filters", Schema: openapi.Integer()},
+	}
+
+	// Categories
+	d.Add("GET", "/api/categories", openapi.Operation{
+		OperationID: "listCategories",
+		Summary:     "List all categories",
+		Tags:        []string{"categories"},
+		Responses: map[string]openapi.Response{
+			"200": listResponse("All categories", d.Schema([]models.Category{})),
+		},
+	})
+	d.Add("PUT", "/api/categories/{id}/parent", admin(openapi.Operation{
+		OperationID: "moveCategory",
+		Summary:     "Move a category",
+		Description: "Products in the moved subtree get the category group of their new top-level category.",
+		Tags:        []string{"categories"},
+		RequestBody: body(models.MoveCategoryRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": response("The moved category", d.Schema(models.Category{})),
+			"400": problemResponse("Invalid request body"),
+			"404": problemResponse("Category not found"),
+			"409": problemResponse("The parent does not exist or is inside the category"),
+		},
+	}))
+	d.Add("DELETE", "/api/categories/{id}", admin(openapi.Operation{
+		OperationID: "deleteCategory",
+		Summary:     "Delete a category",
+		Description: "Subcategories and products move to the parent. Top-level categories must not hold products.",
+		Tags:        []string{"categories"},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Deleted"},
+			"404": problemResponse("Category not found"),
+			"409": problemResponse("The top-level category still holds products"),
+		},
+	}))
+
+	// Products
+	d.Add("GET", "/api/products", openapi.Operation{
+		OperationID: "listProducts",
+		Summary:     "List products",
+		Tags:        []string{"products"},
+		Parameters: []openapi.Parameter{
+			query("page", "Page number", intRange(1, 0)),
+			query("page_size", "Products per page", intRange(1, 0)),
+			query("category_id", "Only products in this category", openapi.String()),
+			query("category_group", "Only products in this top-level category", openapi.String()),
+			query("_sort", "Field to sort by", openapi.String()),
+			query("sortField", "Same as _sort", openapi.String()),
+			query("_order", "Sort order", enum("asc", "desc")),
+			query("sortOrder", "Same as _order", enum("asc", "desc")),
+			query("_start", "Offset of the first product, instead of page", intRange(0, 0)),
+			query("_limit", "Number of products, instead of page_size", intRange(1, 0)),
+		},
+		Responses: map[string]openapi.Response{
+			"200": withHeaders(listResponse("One page of products", d.Schema(models.ProductsResponse{})), totalCount),
+		},
+	})
+	d.Add("POST", "/api/products", openapi.Operation{
+		OperationID: "createProduct",
+		Summary:     "Create a product",
+		Tags:        []string{"products"},
+		RequestBody: body(models.Product{}),
+		Responses: map[string]openapi.Response{
+			"201": response("The created product", d.Schema(models.Product{})),
+			"400": textResponse("Invalid body or missing required fields"),
+		},
+	})
+	d.Add("GET", "/api/products/{id}", openapi.Operation{
+		OperationID: "getProduct",
+		Parameters:  []openapi.Parameter{objectID},
+		Summary:     "Get a product",
+		Tags:        []string{"products"},
+		Responses: map[string]openapi.Response{
+			"200": response("The product", d.Schema(models.Product{})),
+			"400": textResponse("Invalid ObjectID format"),
+			"404": textResponse("Product not found"),
+		},
+	})
+	d.Add("PUT", "/api/products/{id}", openapi.Operation{
+		OperationID: "updateProduct",
+		Parameters:  []openapi.Parameter{objectID},
+		Summary:     "Update a product",
+		Tags:        []string{"products"},
+		RequestBody: body(models.Product{}),
+		Responses: map[string]openapi.Response{
+			"200": response("The updated product", d.Schema(models.Product{})),
+			"400": textResponse("Invalid body or ObjectID format"),
+			"404": textResponse("Product not found"),
+		},
+	})
+
+	// Users
+	userSchema := d.Schema(models.UserResponse{})
+	d.Add("GET", "/api/users", openapi.Operation{
+		OperationID: "listUsers",
+		Summary:     "List users, or check a user's credentials",
+		Description: "With password set, checks the credentials of the user with email and returns that user. Prefer POST /api/auth/login.",
+		Tags:        []string{"users"},
+		Parameters: []openapi.Parameter{
+			query("email", "Only the user with this email", openapi.String()),
+			query("password", "Password to check for email", openapi.String()),
+		},
+		Responses: map[string]openapi.Response{
+			"200": listResponse("The users, or the authenticated user", openapi.OneOf(
+				&openapi.Schema{Type: "array", Items: userSchema, Nullable: true},
+				userSchema,
+			)),
+			"401": problemResponse("Invalid credentials"),
+			"429": problemResponse("Too many failed login attempts"),
+		},
+	})
+	d.Add("GET", "/api/users/{id}", openapi.Operation{
+		OperationID: "getUser",
+		Summary:     "Get a user",
+		Tags:        []string{"users"},
+		Responses: map[string]openapi.Response{
+			"200": response("The user", userSchema),
+			"404": textResponse("User not found"),
+		},
+	})
+	d.Add("DELETE", "/api/users/{id}", admin(openapi.Operation{
+		OperationID: "deleteUser",
+		Summary:     "Delete a user and revoke their tokens",
+		Tags:        []string{"users"},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Deleted"},
+			"404": problemResponse("User not found"),
+		},
+	}))
+
+	// Auth
+	d.Add("POST", "/api/auth/login", openapi.Operation{
+		OperationID: "login",
+		Summary:     "Exchange credentials for a bearer token",
+		Tags:        []string{"auth"},
+		RequestBody: body(models.LoginRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": response("The token and user", d.Schema(models.LoginResponse{})),
+			"400": problemResponse("Email and password are required"),
+			"401": problemResponse("Invalid credentials"),
+			"429": problemResponse("Too many failed login attempts"),
+		},
+	})
+	d.Add("POST", "/api/auth/logout", openapi.Operation{
+		OperationID: "logout",
+		Summary:     "Revoke the current token",
+		Tags:        []string{"auth"},
+		Security:    bearer,
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Revoked"},
+			"401": problemResponse("Missing or invalid token"),
+		},
+	})
+	d.Add("POST", "/api/auth/unlock", admin(openapi.Operation{
+		OperationID: "unlockAccount",
+		Summary:     "Clear a login lockout",
+		Tags:        []string{"auth"},
+		RequestBody: body(models.UnlockRequest{}),
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Unlocked"},
+			"400": problemResponse("Either email or ip is required"),
+			"404": problemResponse("No failed attempts recorded"),
+		},
+	}))
+	d.Add("GET", "/api/auth/events", admin(openapi.Operation{
+		OperationID: "listAuthEvents",
+		Summary:     "Query the auth audit log",
+		Tags:        []string{"auth"},
+		Parameters: []openapi.Parameter{
+			query("user_id", "Only events of this user", openapi.String()),
+			query("email", "Only events for this email", openapi.String()),
+			query("type", "Only events of this type", enum("login_success", "login_failure", "lockout", "logout", "unlock")),
+			query("from", "Only events at or after this time", dateTime),
+			query("to", "Only events before this time", dateTime),
+			query("limit", "Maximum number of events", intRange(1, 1000)),
+		},
+		Responses: map[string]openapi.Response{
+			"200": listResponse("Matching events, newest first", d.Schema([]models.AuthEvent{})),
+			"400": problemResponse("Invalid filter"),
+		},
+	}))
+
+	// Health
+	readiness := openapi.Operation{
+		Summary: "Readiness: dependencies are reachable",
+		Tags:    []string{"health"},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("Ready", status),
+			"503": jsonResponse("Not ready or shutting down", status),
+		},
+	}
+	d.Add("GET", "/healthz", openapi.Operation{
+		OperationID: "liveness",
+		Summary:     "Liveness: the process is up",
+		Tags:        []string{"health"},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("Alive", status),
+		},
+	})
+	readiness.OperationID = "readiness"
+	d.Add("GET", "/readyz", readiness)
+	readiness.OperationID = "health"
+	d.Add("GET", "/api/health", readiness)
+	d.Add("GET", "/api/health/details", admin(openapi.Operation{
+		OperationID: "healthDetails",
+		Summary:     "Status and latency of every dependency check",
+		Tags:        []string{"health"},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("Ready", d.Schema(health.Report{})),
+			"503": jsonResponse("Not ready", d.Schema(health.Report{})),
+		},
+	}))
+
+	// GraphQL
+	graphqlResult := &openapi.Schema{
+		Type:        "object",
+		Description: "GraphQL result with data and/or errors; each error's extensions.code names the failure",
+		Properties: map[string]*openapi.Schema{
+			"data":   {Type: "object", Nullable: true},
+			"errors": openapi.ArrayOf(&openapi.Schema{Type: "object"}),
+		},
+	}
+	d.Add("GET", "/graphql", openapi.Operation{
+		OperationID: "graphqlQuery",
+		Summary:     "Run a GraphQL query",
+		Description: "Mutations must be sent with POST.",
+		Tags:        []string{"graphql"},
+		Parameters: []openapi.Parameter{
+			{Name: "query", In: "query", Required: true, Description: "GraphQL document", Schema: openapi.String()},
+			query("variables", "Variables as a JSON object", openapi.String()),
+			query("operationName", "Operation to run when the query holds several", openapi.String()),
+		},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("The result, possibly with field errors", graphqlResult),
+			"400": jsonResponse("The query does not parse, is invalid or exceeds the depth or complexity limit", graphqlResult),
+			"405": problemResponse("The operation is a mutation"),
+		},
+	})
+	d.Add("POST", "/graphql", openapi.Operation{
+		OperationID: "graphql",
+		Summary:     "Run a GraphQL query or mutation",
+		Tags:        []string{"graphql"},
+		RequestBody: jsonBody(models.GraphQLRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("The result, possibly with field errors", graphqlResult),
+			"400": jsonResponse("The query does not parse, is invalid or exceeds the depth or complexity limit", graphqlResult),
+		},
+	})
+
+	// Metrics and documentation
+	d.Add("GET", "/debug/vars", admin(openapi.Operation{
+		OperationID: "metrics",
+		Summary:     "expvar counters",
+		Tags:        []string{"meta"},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("The application's counters", &openapi.Schema{Type: "object"}),
+		},
+	}))
+	d.Add("GET", "/api/openapi.json", openapi.Operation{
+		OperationID: "openapi",
+		Summary:     "This document",
+		Tags:        []string{"meta"},
+		Responses: map[string]openapi.Response{
+			"200": jsonResponse("OpenAPI 3.1 document", &openapi.Schema{Type: "object"}),
+		},
+	})
+	d.Add("GET", "/api/docs", openapi.Operation{
+		OperationID: "docs",
+		Summary:     "Interactive API reference",
+		Tags:        []string{"meta"},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Swagger UI page", Content: map[string]openapi.MediaType{"text/html": {Schema: openapi.String()}}},
+		},
+	})
+
+	// Version 2 serves the same resources under /api/v2 with the response
+	// shapes fixed, and version 1 is deprecated
+	versioned := map[string]bool{"categories": true, "products": true, "users": true, "auth": true}
+	var v1Paths []string
+	for path, item := range d.Paths {
+		for _, op := range item {
+			if versioned[op.Tags[0]] {
+				v1Paths = append(v1Paths, path)
+				break
+			}
+		}
+	}
+	for _, path := range v1Paths {
+		for method, op := range d.Paths[path] {
+			v2 := *op
+			v2.OperationID += "V2"
+			v2.Parameters = slices.Clone(op.Parameters)
+			v2.Responses = maps.Clone(op.Responses)
+			for status, response := range v2.Responses {
+				if _, ok := response.Content["text/plain"]; ok {
+					v2.Responses[status] = problemResponse(response.Description)
+				}
+			}
+			d.Add(method, "/api/v2"+strings.TrimPrefix(path, "/api"), v2)
+
+			op.Deprecated = true
+			op.Description = strings.TrimSpace(op.Description + " Deprecated: use " +
+				strings.ToUpper(method) + " /api/v2" + strings.TrimPrefix(path, "/api") + ". " +
+				"Responses carry the Deprecation and Sunset headers.")
+		}
+	}
+	d.Operation("GET", "/api/v2/products").Responses["200"] = withHeaders(listResponse("One page of products", d.Schema(models.ProductPage{})), totalCount)
+	d.Operation("GET", "/api/v2/categories").Responses["200"] = listResponse("All categories", openapi.ArrayOf(d.Schema(models.Category{})))
+	d.Operation("GET", "/api/v2/auth/events").Responses["200"] = listResponse("Matching events, newest first", openapi.ArrayOf(d.Schema(models.AuthEvent{})))
+	*d.Operation("GET", "/api/v2/users") = openapi.Operation{
+		OperationID: "listUsersV2",
+		Summary:     "List users",
+		Tags:        []string{"users"},
+		Parameters: []openapi.Parameter{
+			query("email", "Only the user with this email", openapi.String()),
+		},
+		Responses: map[string]openapi.Response{
+			"200": listResponse("The users", d.Schema(models.UserList{})),
+			"400": problemResponse("A password was given; use POST /api/v2/auth/login"),
+		},
+	}
+
+	// Unsafe requests may carry an Idempotency-Key
+	minKeyLength, maxKeyLength := 1, 255
+	idempotencyKey := openapi.Parameter{
+		Name:        "Idempotency-Key",
+		In:          "header",
+		Description: "Makes the request safe to retry: a request repeated with the same key and body gets the first response again, marked with Idempotent-Replayed",
+		Schema:      &openapi.Schema{Type: "string", MinLength: &minKeyLength, MaxLength: &maxKeyLength},
+	}
+
+	// Every route may be rate limited, and database-backed routes fail fast
+	// or time out. Requests that do not match their operation are rejected
+	// by the validation middleware, bodies over their size limit by the body
+	// limit middleware, and requests whose Accept header matches none of
+	// several media types by the negotiation middleware.
+	for _, item := range d.Paths {
+		for method, op := range item {
+			if method == "post" || method == "put" || method == "patch" || method == "delete" {
+				op.Parameters = append(op.Parameters, idempotencyKey)
+				conflict := "A request with the same Idempotency-Key is still being processed"
+				if existing, ok := op.Responses["409"]; ok {
+					conflict = existing.Description + ", or a request with the same Idempotency-Key is still being processed"
+				}
+				op.Responses["409"] = problemResponse(conflict)
+				op.Responses["422"] = problemResponse("The Idempotency-Key was already used for a different request")
+			}
+
+			op.Responses["default"] = problemResponse("Unexpected error, including 429 when rate limited, " +
+				"503 when the database is unavailable and 504 when the request runs out of time")
+			if len(op.Responses["200"].Content) > 1 || len(op.Responses["201"].Content) > 1 {
+				op.Responses["406"] = problemResponse("None of the media types in Accept can be produced")
+			}
+
+			if len(op.Parameters) == 0 && op.RequestBody == nil {
+				continue
+			}
+			invalid, ok := op.Responses["400"]
+			if !ok {
+				invalid = problemResponse("Parameters or body do not match this description")
+			} else if invalid.Content == nil {
+				invalid.Content = make(map[string]openapi.MediaType)
+			}
+			invalid.Content[problem.ContentType] = openapi.MediaType{Schema: problemSchema}
+			op.Responses["400"] = invalid
+			if op.RequestBody != nil {
+				op.Responses["413"] = problemResponse("The body is larger than the route's limit")
+				op.Responses["415"] = problemResponse("The Content-Type of the body is not supported")
+			}
+		}
+	}
+	return d
+}
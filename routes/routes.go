@@ -2,26 +2,149 @@
 package routes
 
 import (
+	"net"
 	"net/http"
 
+	"go-backend/auth"
+	"go-backend/circuit"
+	"go-backend/config"
+	"go-backend/graph"
 	"go-backend/handlers"
+	"go-backend/idempotency"
+	"go-backend/metrics"
 	"go-backend/middleware"
+	"go-backend/openapi"
+	"go-backend/repository"
+	"go-backend/tracing"
 
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 )
 
-// RegisterRoutes sets up the API routes
-func RegisterRoutes(router *mux.Router) {
+// Options configures the middleware applied by RegisterRoutes. CORS,
+// RateLimiter, Deadlines and BodyLimits are created by the caller so their
+// configuration can be reloaded while serving.
+type Options struct {
+	CORS           *middleware.CORS
+	RateLimiter    *middleware.RateLimiter
+	Deadlines      *middleware.Deadlines
+	BodyLimits     *middleware.BodyLimits
+	TrustedProxies []*net.IPNet
+	Validation     config.Validation
+	APIv1          config.Deprecation
+	GraphQL        config.GraphQL
+	Compression    config.Compression
+	Idempotency    config.Idempotency
+	Debug          bool
+
+	// Store backs the GraphQL endpoint; the REST handlers get theirs from
+	// handlers.SetStore
+	Store *repository.Store
+
+	// IdempotencyStore keeps the responses to requests with an
+	// Idempotency-Key
+	IdempotencyStore idempotency.Store
+
+	// DatabaseBreaker, if set, turns requests away with 503 while the
+	// database is down
+	DatabaseBreaker *circuit.Breaker
+}
+
+// RegisterRoutes sets up the API routes. The routes are served either way;
+// the error reports routes that are missing from the OpenAPI document.
+func RegisterRoutes(router *mux.Router, opts Options) error {
+	spec := Spec()
+
+	// The health check is public and never needs credentials
+	cors := opts.CORS
+	cors.Override("/api/health", config.CORSPolicy{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET"},
+	})
+
+	// Credential checks get the strict policy
+	limiter := opts.RateLimiter
+	limiter.UserID = func(r *http.Request) string { return auth.UserID(r.Context()) }
+	limiter.Override("/api/users", "auth")
+	limiter.Override("/api/auth/login", "auth")
+	limiter.Override("/api/v2/auth/login", "auth")
+
 	// Apply global middleware
-	router.Use(middleware.CORSMiddleware)
+	router.Use(otelmux.Middleware(tracing.ServiceName))
+	router.Use(middleware.RequestIDMiddleware)
 	router.Use(middleware.LoggingMiddleware)
-	router.Use(middleware.JSONContentTypeMiddleware)
+	router.Use(middleware.NewRecoveryMiddleware(opts.Debug))
+	router.Use(middleware.NewClientIPMiddleware(opts.TrustedProxies))
+	if opts.Compression.Enabled || opts.Compression.DecompressRequests {
+		router.Use(middleware.NewCompressionMiddleware(opts.Compression))
+	}
+	router.Use(cors.Middleware)
+	if opts.DatabaseBreaker != nil {
+		// Health checks, metrics and the docs work without the database
+		router.Use(middleware.NewCircuitBreakerMiddleware(opts.DatabaseBreaker,
+			"/healthz", "/readyz", "/api/health", "/api/health/details", "/debug/vars",
+			"/api/openapi.json", "/api/docs"))
+	}
+	router.Use(opts.Deadlines.Middleware)
+	router.Use(opts.BodyLimits.Middleware)
+	router.Use(middleware.AuthMiddleware)
+	router.Use(limiter.Middleware)
+	if opts.Idempotency.Enabled {
+		router.Use(middleware.NewIdempotencyMiddleware(opts.IdempotencyStore, opts.Idempotency.TTL))
+	}
+	router.Use(middleware.NewNegotiationMiddleware(spec))
+	router.Use(middleware.NewValidationMiddleware(spec, opts.Validation))
+
+	// The resources are served by both API versions; v1 is deprecated
+	v1Deprecated, v1Sunset, _ := opts.APIv1.Dates() // validated by config.Load
+	versions := []middleware.APIVersionInfo{
+		{Version: middleware.APIv2, Prefix: "/api/v2"},
+		{Version: middleware.APIv1, Prefix: "/api", Deprecated: v1Deprecated, Sunset: v1Sunset, Successor: "/api/v2"},
+	}
+	for _, version := range versions {
+		api := router.PathPrefix(version.Prefix).Subrouter()
+		api.Use(version.Middleware)
+		registerResources(api)
+	}
 
-	// Create API subrouter
+	// Unversioned API routes
 	api := router.PathPrefix("/api").Subrouter()
+	requireAdmin := middleware.RequireRole(auth.RoleAdmin)
+
+	// Health checks
+	router.HandleFunc("/healthz", handlers.Liveness).Methods("GET")
+	router.HandleFunc("/readyz", handlers.Readiness).Methods("GET")
+	api.HandleFunc("/health", handlers.Readiness).Methods("GET", "OPTIONS")
+	api.Handle("/health/details", requireAdmin(http.HandlerFunc(handlers.HealthDetails))).Methods("GET", "OPTIONS")
+
+	// GraphQL over the same resources
+	router.Handle("/graphql", graph.New(opts.Store, spec, opts.GraphQL)).Methods("GET", "POST", "OPTIONS")
+
+	// Metrics, for admins only
+	router.Handle("/debug/vars", requireAdmin(metrics.Handler())).Methods("GET")
+
+	// API documentation
+	api.Handle("/openapi.json", spec.Handler()).Methods("GET", "OPTIONS")
+	api.Handle("/docs", openapi.UIHandler("/api/openapi.json")).Methods("GET", "OPTIONS")
+
+	// Handle 404
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"endpoint not found"}`))
+	})
+
+	return spec.Check(router)
+}
+
+// registerResources registers the resource routes of one API version
+func registerResources(api *mux.Router) {
+	requireAdmin := middleware.RequireRole(auth.RoleAdmin)
 
 	// Categories endpoints
 	api.HandleFunc("/categories", handlers.GetCategories).Methods("GET", "OPTIONS")
+	api.Handle("/categories/{id}/parent", requireAdmin(http.HandlerFunc(handlers.MoveCategory))).Methods("PUT", "OPTIONS")
+	api.Handle("/categories/{id}", requireAdmin(http.HandlerFunc(handlers.DeleteCategory))).Methods("DELETE", "OPTIONS")
 
 	// Products endpoints
 	api.HandleFunc("/products", handlers.GetProducts).Methods("GET", "OPTIONS")
@@ -32,16 +155,11 @@ func RegisterRoutes(router *mux.Router) {
 	// Users endpoints
 	api.HandleFunc("/users", handlers.GetUsers).Methods("GET", "OPTIONS")
 	api.HandleFunc("/users/{id}", handlers.GetUserByID).Methods("GET", "OPTIONS")
+	api.Handle("/users/{id}", requireAdmin(http.HandlerFunc(handlers.DeleteUser))).Methods("DELETE", "OPTIONS")
 
-	// Health check
-	api.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok"}`))
-	}).Methods("GET")
-
-	// Handle 404
-	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte(`{"error":"endpoint not found"}`))
-	})
+	// Auth endpoints
+	api.HandleFunc("/auth/login", handlers.Login).Methods("POST", "OPTIONS")
+	api.Handle("/auth/logout", middleware.RequireAuth(http.HandlerFunc(handlers.Logout))).Methods("POST", "OPTIONS")
+	api.Handle("/auth/unlock", requireAdmin(http.HandlerFunc(handlers.UnlockAccount))).Methods("POST", "OPTIONS")
+	api.Handle("/auth/events", requireAdmin(http.HandlerFunc(handlers.GetAuthEvents))).Methods("GET", "OPTIONS")
 }
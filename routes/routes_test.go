@@ -0,0 +1,140 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"go-backend/circuit"
+	"go-backend/config"
+	"go-backend/handlers"
+	"go-backend/idempotency"
+	"go-backend/middleware"
+	"go-backend/problem"
+	"go-backend/ratelimit"
+	"go-backend/repository"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/gorilla/mux"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// testOptions builds the route options from the default configuration,
+// backed by in-memory stores
+func testOptions(router *mux.Router) Options {
+	cfg := config.Default()
+	return Options{
+		CORS:             middleware.NewCORS(router, cfg.CORS),
+		RateLimiter:      middleware.NewRateLimiter(ratelimit.NewMemoryStore(), cfg.Limits.RateLimit),
+		Deadlines:        middleware.NewDeadlines(cfg.Limits.Timeouts),
+		BodyLimits:       middleware.NewBodyLimits(cfg.Limits.Body),
+		Validation:       cfg.Validation,
+		APIv1:            cfg.API.V1,
+		GraphQL:          cfg.Limits.GraphQL,
+		Compression:      cfg.Compression,
+		Idempotency:      cfg.Idempotency,
+		Store:            repository.NewMemoryStore(),
+		IdempotencyStore: idempotency.NewMemoryStore(),
+	}
+}
+
+func TestSpecCoversEveryRoute(t *testing.T) {
+	router := mux.NewRouter()
+	if err := RegisterRoutes(router, testOptions(router)); err != nil {
+		t.Fatalf("RegisterRoutes: %v", err)
+	}
+	if err := Spec().Check(router); err != nil {
+		t.Errorf("Spec().Check: %v", err)
+	}
+}
+
+func TestNonJSONBodiesAreValidated(t *testing.T) {
+	handlers.SetStore(repository.NewMemoryStore())
+	router := mux.NewRouter()
+	if err := RegisterRoutes(router, testOptions(router)); err != nil {
+		t.Fatalf("RegisterRoutes: %v", err)
+	}
+
+	invalid := map[string]any{
+		"name":        "",
+		"category_id": "furniture",
+		"attributes":  []any{map[string]any{"code": 7}},
+	}
+	msgpackBody, _ := msgpack.Marshal(invalid)
+	cborBody, _ := cbor.Marshal(invalid)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		status      int
+		invalid     []string
+	}{
+		{"invalid msgpack", "application/msgpack", msgpackBody, http.StatusBadRequest, []string{"attributes[0].code", "name"}},
+		{"invalid cbor", "application/cbor", cborBody, http.StatusBadRequest, []string{"attributes[0].code", "name"}},
+		{
+			"invalid xml", "application/xml",
+			[]byte(`<product><name></name><category_id>furniture</category_id><attributes><attribute><value>1</value></attribute></attributes></product>`),
+			http.StatusBadRequest, []string{"attributes[0].code", "name"},
+		},
+		{
+			// XML text is read as the type of its schema, so a name that
+			// looks like a number is still a string
+			"valid xml", "application/xml",
+			[]byte(`<product><name>2024</name><category_id>furniture</category_id><attributes></attributes></product>`),
+			http.StatusCreated, nil,
+		},
+		{"undecodable msgpack", "application/msgpack", []byte{0xc1}, http.StatusBadRequest, []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v2/products", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set("Accept", "application/json")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			var p problem.Problem
+			json.Unmarshal(rec.Body.Bytes(), &p)
+			var names []string
+			for _, param := range p.InvalidParams {
+				names = append(names, param.Name)
+			}
+			slices.Sort(names)
+			if !slices.Equal(names, tt.invalid) {
+				t.Errorf("invalid params = %q, want %q", names, tt.invalid)
+			}
+		})
+	}
+}
+
+func TestOpenBreakerSparesHealthMetricsAndDocs(t *testing.T) {
+	router := mux.NewRouter()
+	opts := testOptions(router)
+	opts.DatabaseBreaker = circuit.New(time.Hour)
+	opts.DatabaseBreaker.Trip()
+	if err := RegisterRoutes(router, opts); err != nil {
+		t.Fatalf("RegisterRoutes: %v", err)
+	}
+
+	for path, unavailable := range map[string]bool{
+		"/api/products":       true,
+		"/api/health/details": false,
+		"/debug/vars":         false,
+		"/api/openapi.json":   false,
+		"/api/docs":           false,
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if got := rec.Code == http.StatusServiceUnavailable; got != unavailable {
+			t.Errorf("GET %s: status %d, want 503 only for routes that need the database", path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package seed
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture kinds, which are also the file names in a fixtures directory
+const (
+	KindCategories = "categories"
+	KindProducts   = "products"
+	KindUsers      = "users"
+)
+
+// extensions lists the supported fixture formats in lookup order
+var extensions = []string{".json", ".yaml", ".yml", ".csv"}
+
+// LoadDir reads categories, products and users fixtures from dir. Each kind
+// is optional and may be JSON, YAML or CSV, e.g. categories.yaml and
+// products.csv.
+func LoadDir(dir string) (*Data, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	var data Data
+	for _, kind := range []string{KindCategories, KindProducts, KindUsers} {
+		var found []string
+		for _, ext := range extensions {
+			path := filepath.Join(dir, kind+ext)
+			if _, err := os.Stat(path); err == nil {
+				found = append(found, path)
+			}
+		}
+		if len(found) > 1 {
+			return nil, fmt.Errorf("%s: more than one %s fixture: %s", dir, kind, strings.Join(found, ", "))
+		}
+		if len(found) == 1 {
+			if err := readList(found[0], kind, &data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &data, nil
+}
+
+// ReadFile reads a single JSON or YAML file with categories, products and
+// users sections
+func ReadFile(path string) (*Data, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var data Data
+	if err := decode(path, content, &data); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &data, nil
+}
+
+// readList reads one kind of fixture into data
+func readList(path, kind string, data *Data) error {
+	var target any
+	switch kind {
+	case KindCategories:
+		target = &data.Categories
+	case KindProducts:
+		target = &data.Products
+	case KindUsers:
+		target = &data.Users
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		err = readCSV(f, kind, data)
+	} else {
+		var content []byte
+		if content, err = io.ReadAll(f); err == nil {
+			err = decode(path, content, target)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return nil
+}
+
+// decode reads JSON or YAML into v. YAML goes through JSON so the models'
+// json tags apply to both.
+func decode(path string, content []byte, v any) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return json.Unmarshal(content, v)
+	case ".yaml", ".yml":
+		var doc any
+		if err := yaml.Unmarshal(content, &doc); err != nil {
+			return err
+		}
+		converted, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(converted, v)
+	}
+	return fmt.Errorf("unsupported fixture format %q", filepath.Ext(path))
+}
+
+// readCSV reads rows keyed by the header line. Products take attributes
+// from "attr:<code>" columns; empty cells are left out.
+func readCSV(r io.Reader, kind string, data *Data) error {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := reader.FieldPos(0)
+
+		row := make(map[string]string, len(header))
+		for i, name := range header {
+			row[strings.TrimSpace(name)] = strings.TrimSpace(record[i])
+		}
+
+		switch kind {
+		case KindCategories:
+			category := models.Category{ID: row["id"], Name: row["name"]}
+			if parent := row["parent_id"]; parent != "" {
+				category.ParentID = &parent
+			}
+			data.Categories = append(data.Categories, category)
+
+		case KindUsers:
+			data.Users = append(data.Users, models.User{
+				ID:       row["id"],
+				Email:    row["email"],
+				Password: row["password"],
+				Name:     row["name"],
+				Role:     row["role"],
+			})
+
+		case KindProducts:
+			product := models.Product{
+				Name:          row["name"],
+				CategoryID:    row["category_id"],
+				CategoryGroup: row["category_group"],
+			}
+			if id := row["id"]; id != "" {
+				if product.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+					return fmt.Errorf("line %d: invalid id %q", line, id)
+				}
+			}
+			for _, name := range header {
+				name = strings.TrimSpace(name)
+				code, ok := strings.CutPrefix(name, "attr:")
+				if !ok || row[name] == "" {
+					continue
+				}
+				product.Attributes = append(product.Attributes, csvAttribute(code, row[name]))
+			}
+			data.Products = append(data.Products, product)
+		}
+	}
+}
+
+// csvAttribute infers an attribute's type from its cell
+func csvAttribute(code, value string) models.Attribute {
+	attribute := models.Attribute{Code: code, Label: label(code), Type: "string", Value: value}
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		attribute.Type, attribute.Value = "number", n
+	} else if b, err := strconv.ParseBool(value); err == nil {
+		attribute.Type, attribute.Value = "boolean", b
+	}
+	return attribute
+}
+
+// label turns an attribute code like screen_size into "Screen size"
+func label(code string) string {
+	s := strings.ReplaceAll(code, "_", " ")
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,97 @@
+package seed
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+
+	"go-backend/models"
+)
+
+// productWords gives generated products plausible names per top-level
+// category; other categories use genericWords
+var productWords = map[string][]string{
+	"electronics":    {"Phone", "Laptop", "Tablet", "Headphones", "Monitor", "Camera", "Speaker", "Smartwatch"},
+	"clothing":       {"Shirt", "Jacket", "Jeans", "Sneakers", "Dress", "Sweater", "Coat", "Scarf"},
+	"home & kitchen": {"Blender", "Toaster", "Kettle", "Chair", "Table", "Lamp", "Sofa", "Cookware Set"},
+	"books":          {"Novel", "Cookbook", "Biography", "Atlas", "Guide", "Anthology", "Textbook", "Memoir"},
+}
+
+var genericWords = []string{"Item", "Kit", "Set", "Pack", "Bundle"}
+
+var (
+	brands     = []string{"Acme", "Northwind", "Contoso", "Globex", "Initech", "Umbrella", "Stark", "Wayne"}
+	adjectives = []string{"Classic", "Pro", "Ultra", "Lite", "Eco", "Deluxe", "Smart", "Compact", "Premium"}
+	colors     = []string{"Black", "White", "Silver", "Blue", "Red", "Green", "Grey"}
+)
+
+// GenerateProducts makes n fake products spread across the leaf categories
+// of the tree, each with its top-level category as category_group. The same
+// seed produces the same products, so seeding them again updates rather
+// than duplicates.
+func GenerateProducts(categories []models.Category, n int, seed uint64) ([]models.Product, error) {
+	byID := make(map[string]models.Category, len(categories))
+	hasChildren := make(map[string]bool)
+	for _, category := range categories {
+		byID[category.ID] = category
+		if category.ParentID != nil {
+			hasChildren[*category.ParentID] = true
+		}
+	}
+
+	// Sort so the same seed gives the same products whatever the input order
+	var leaves []models.Category
+	for _, category := range byID {
+		if !hasChildren[category.ID] {
+			leaves = append(leaves, category)
+		}
+	}
+	sort.Slice(leaves, func(i, j int) bool { return leaves[i].ID < leaves[j].ID })
+	if len(leaves) == 0 {
+		return nil, fmt.Errorf("no categories to generate products in")
+	}
+
+	rng := rand.New(rand.NewPCG(seed, seed))
+	products := make([]models.Product, 0, n)
+	for i := range n {
+		category := leaves[rng.IntN(len(leaves))]
+		root := rootOf(category, byID)
+
+		words, ok := productWords[strings.ToLower(root.Name)]
+		if !ok {
+			words = genericWords
+		}
+		brand := brands[rng.IntN(len(brands))]
+		name := fmt.Sprintf("%s %s %s %d", brand, adjectives[rng.IntN(len(adjectives))], words[rng.IntN(len(words))], i+1)
+
+		products = append(products, models.Product{
+			Name:          name,
+			CategoryID:    category.ID,
+			CategoryGroup: root.ID,
+			Attributes: []models.Attribute{
+				{Code: "brand", Label: "Brand", Type: "string", Value: brand},
+				{Code: "price", Label: "Price", Type: "number", Value: float64(rng.IntN(100000)+99) / 100},
+				{Code: "color", Label: "Color", Type: "string", Value: colors[rng.IntN(len(colors))]},
+				{Code: "rating", Label: "Rating", Type: "number", Value: float64(rng.IntN(41)+10) / 10},
+				{Code: "in_stock", Label: "In stock", Type: "boolean", Value: rng.IntN(5) > 0},
+			},
+		})
+	}
+	return products, nil
+}
+
+// rootOf follows parents up to the top-level category
+func rootOf(category models.Category, byID map[string]models.Category) models.Category {
+	for range len(byID) {
+		if category.ParentID == nil {
+			break
+		}
+		parent, ok := byID[*category.ParentID]
+		if !ok {
+			break
+		}
+		category = parent
+	}
+	return category
+}
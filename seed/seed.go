@@ -0,0 +1,209 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"go-backend/auth"
+	"go-backend/models"
+	"go-backend/repository"
+)
+
+// Data is a set of fixtures to load into the database
+type Data struct {
+	Categories []models.Category `json:"categories"`
+	Products   []models.Product  `json:"products"`
+	Users      []models.User     `json:"users"`
+}
+
+// Options control how data is applied
+type Options struct {
+	// SkipUsers leaves users out, as required in production
+	SkipUsers bool
+	// DryRun validates without writing
+	DryRun bool
+}
+
+// Result counts what a seed run changed
+type Result struct {
+	Categories   int  `json:"categories"`
+	Products     int  `json:"products"`
+	UsersCreated int  `json:"users_created"`
+	UsersUpdated int  `json:"users_updated"`
+	UsersSkipped int  `json:"users_skipped"`
+	DryRun       bool `json:"dry_run"`
+}
+
+// Apply validates data against itself and the stored categories, then
+// upserts it keyed on natural IDs: category id, user email, and product id
+// or name within its category. Running it twice changes nothing. Existing
+// users keep their password; plaintext passwords of new users are hashed.
+func Apply(ctx context.Context, store *repository.Store, data *Data, opts Options) (*Result, error) {
+	result := Result{DryRun: opts.DryRun}
+	if opts.SkipUsers {
+		result.UsersSkipped = len(data.Users)
+	}
+
+	existing, err := store.Categories.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := Validate(data, existing); err != nil {
+		return nil, err
+	}
+
+	if opts.DryRun {
+		result.Categories = len(data.Categories)
+		result.Products = len(data.Products)
+		if !opts.SkipUsers {
+			result.UsersCreated = len(data.Users)
+		}
+		return &result, nil
+	}
+
+	for i := range data.Categories {
+		if err := store.Categories.Upsert(ctx, &data.Categories[i]); err != nil {
+			return &result, fmt.Errorf("category %s: %w", data.Categories[i].ID, err)
+		}
+		result.Categories++
+	}
+
+	for i := range data.Products {
+		if err := store.Products.Upsert(ctx, &data.Products[i]); err != nil {
+			return &result, fmt.Errorf("product %s: %w", data.Products[i].Name, err)
+		}
+		result.Products++
+		if result.Products%1000 == 0 {
+			slog.Info("Seeding products", "done", result.Products, "total", len(data.Products))
+		}
+	}
+
+	if opts.SkipUsers {
+		return &result, nil
+	}
+
+	for _, user := range data.Users {
+		if !auth.IsPasswordHash(user.Password) {
+			hash, err := auth.HashPassword(user.Password)
+			if err != nil {
+				return &result, err
+			}
+			user.Password = hash
+		}
+
+		created, err := store.Users.Upsert(ctx, &user)
+		if err != nil {
+			return &result, fmt.Errorf("user %s: %w", user.Email, err)
+		}
+		if created {
+			result.UsersCreated++
+		} else {
+			result.UsersUpdated++
+		}
+	}
+
+	return &result, nil
+}
+
+// Validate checks required fields, duplicates and references between
+// fixtures. Categories and products may refer to categories in data or in
+// existing.
+func Validate(data *Data, existing []models.Category) error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	parents := make(map[string]*string)
+	for _, category := range existing {
+		parents[category.ID] = category.ParentID
+	}
+
+	seen := make(map[string]bool)
+	for i, category := range data.Categories {
+		switch {
+		case category.ID == "":
+			fail("categories[%d]: id is required", i)
+			continue
+		case seen[category.ID]:
+			fail("categories[%d]: duplicate id %q", i, category.ID)
+		}
+		if category.Name == "" {
+			fail("category %s: name is required", category.ID)
+		}
+		seen[category.ID] = true
+		parents[category.ID] = category.ParentID
+	}
+
+	for _, category := range data.Categories {
+		if category.ParentID == nil {
+			continue
+		}
+		if _, ok := parents[*category.ParentID]; !ok {
+			fail("category %s: parent_id %q does not exist", category.ID, *category.ParentID)
+		} else if hasCycle(category.ID, parents) {
+			fail("category %s: parent_id %q creates a cycle", category.ID, *category.ParentID)
+		}
+	}
+
+	type productKey struct{ categoryID, name string }
+	products := make(map[productKey]bool)
+	for i, product := range data.Products {
+		if product.Name == "" || product.CategoryID == "" {
+			fail("products[%d]: name and category_id are required", i)
+			continue
+		}
+		if _, ok := parents[product.CategoryID]; !ok {
+			fail("product %q: category_id %q does not exist", product.Name, product.CategoryID)
+		}
+		if _, ok := parents[product.CategoryGroup]; product.CategoryGroup != "" && !ok {
+			fail("product %q: category_group %q does not exist", product.Name, product.CategoryGroup)
+		}
+		key := productKey{product.CategoryID, product.Name}
+		if product.ID.IsZero() && products[key] {
+			fail("product %q: duplicate name in category %s", product.Name, product.CategoryID)
+		}
+		products[key] = true
+	}
+
+	emails := make(map[string]bool)
+	for i, user := range data.Users {
+		switch {
+		case user.Email == "":
+			fail("users[%d]: email is required", i)
+			continue
+		case emails[user.Email]:
+			fail("user %s: duplicate email", user.Email)
+		}
+		emails[user.Email] = true
+		if user.Password == "" {
+			fail("user %s: password is required", user.Email)
+		}
+		if !auth.ValidRole(user.Role) {
+			fail("user %s: role must be %s or %s", user.Email, auth.RoleAdmin, auth.RoleUser)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid fixtures:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// hasCycle reports whether following parents from id leads back to id
+func hasCycle(id string, parents map[string]*string) bool {
+	current := id
+	for range len(parents) {
+		parent := parents[current]
+		if parent == nil {
+			return false
+		}
+		if *parent == id {
+			return true
+		}
+		current = *parent
+	}
+	return true
+}
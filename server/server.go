@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"go-backend/config"
+	"go-backend/health"
+)
+
+// New creates an http.Server for handler with the configured limits
+func New(handler http.Handler, cfg config.Server) *http.Server {
+	return &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
+
+// Run serves until ctx is cancelled, then fails readiness, waits for the
+// shutdown delay and drains in-flight requests within the shutdown timeout
+func Run(ctx context.Context, srv *http.Server, cfg config.Server) error {
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("Server starting on port %s", cfg.Port)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutdown requested, failing readiness for %s", cfg.ShutdownDelay)
+	health.SetShuttingDown()
+	time.Sleep(cfg.ShutdownDelay)
+
+	log.Printf("Draining in-flight requests (timeout %s)", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		return fmt.Errorf("graceful shutdown incomplete: %w", err)
+	}
+
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	log.Println("Server stopped")
+	return nil
+}